@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/muidea/quickModbus/internal/config"
 	"net/http"
+	"strings"
 
 	"github.com/muidea/magicCommon/application"
 	"github.com/muidea/magicCommon/foundation/log"
@@ -15,6 +16,7 @@ import (
 var listenPort = "8880"
 var endpointName = "quickModbus"
 var configFile = ""
+var templateFiles = ""
 
 func initPprofMonitor(listenPort string) {
 	addr := ":1" + listenPort
@@ -31,6 +33,7 @@ func main() {
 	flag.StringVar(&listenPort, "ListenPort", listenPort, "listen address")
 	flag.StringVar(&endpointName, "EndpointName", endpointName, "endpoint name.")
 	flag.StringVar(&configFile, "Config", configFile, "config file path")
+	flag.StringVar(&templateFiles, "Template", templateFiles, "device template files to import, separated by comma")
 	flag.Parse()
 
 	initPprofMonitor(listenPort)
@@ -43,6 +46,9 @@ func main() {
 			return
 		}
 	}
+	if templateFiles != "" {
+		config.AddTemplateFiles(strings.Split(templateFiles, ",")...)
+	}
 
 	corePtr, coreErr := core.New(endpointName, listenPort)
 	if coreErr != nil {
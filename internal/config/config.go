@@ -1,7 +1,34 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/datastore"
+	"github.com/muidea/quickModbus/pkg/history"
+)
+
 const cfgPath = "/var/app/config/cfg.json"
 
+const defaultModbusBindPort = "502"
+
+const defaultSlaveUnitID = 0x01
+
+const (
+	defaultMQTTClientID     = "quickModbus"
+	defaultMQTTKeepAlive    = 30
+	defaultMQTTValueTopic   = "quickModbus/{slaveID}/{tag}"
+	defaultMQTTCommandTopic = "quickModbus/command"
+)
+
+var currentConfig = &config{
+	ModbusBindPort: defaultModbusBindPort,
+}
+
 func init() {
 	err := LoadConfig(cfgPath)
 	if err == nil {
@@ -13,17 +40,173 @@ func LoadConfig(cfgFile string) (err error) {
 	if cfgFile == "" {
 		return
 	}
+
+	byteVal, byteErr := os.ReadFile(cfgFile)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	cfgPtr := &config{
+		ModbusBindPort: defaultModbusBindPort,
+	}
+	err = json.Unmarshal(byteVal, cfgPtr)
+	if err != nil {
+		return
+	}
+
+	currentConfig = cfgPtr
 	return
 }
 
 func BindAddr() string {
-	return "0.0.0.0:502"
+	return fmt.Sprintf("0.0.0.0:%s", currentConfig.ModbusBindPort)
 }
 
 func SlaveAddr() string {
 	return ""
 }
 
+// SlaveListeners 从站监听列表,未配置时只在ModbusBindPort上监听Modbus TCP
+func SlaveListeners() []ListenerConfig {
+	if len(currentConfig.SlaveListeners) == 0 {
+		return []ListenerConfig{{BindPort: currentConfig.ModbusBindPort, Mode: common.ModbusTcp}}
+	}
+
+	return currentConfig.SlaveListeners
+}
+
+// DataFile 从站数据持久化文件,为空时数据只保存在内存中
+func DataFile() string {
+	return currentConfig.DataFile
+}
+
+// SlaveUnits 从站各UnitID的数据表地址区间,未配置时默认只响应UnitID 1
+func SlaveUnits() []datastore.UnitConfig {
+	if len(currentConfig.SlaveUnits) == 0 {
+		return []datastore.UnitConfig{datastore.DefaultUnitConfig(defaultSlaveUnitID)}
+	}
+
+	return currentConfig.SlaveUnits
+}
+
+// TemplateFiles 启动时导入的设备模板文件
+func TemplateFiles() []string {
+	return currentConfig.TemplateFiles
+}
+
+// AddTemplateFiles 追加命令行指定的设备模板文件
+func AddTemplateFiles(files ...string) {
+	currentConfig.TemplateFiles = append(currentConfig.TemplateFiles, files...)
+}
+
+// ScanGroups 启动时创建的扫描组
+func ScanGroups() []*common.ScanGroup {
+	return currentConfig.ScanGroups
+}
+
+// MQTT MQTT北向参数,未配置BrokerAddr与EmbeddedBroker时返回nil
+func MQTT() *MQTTConfig {
+	if currentConfig.MQTT == nil || (currentConfig.MQTT.BrokerAddr == "" && currentConfig.MQTT.EmbeddedBroker == "") {
+		return nil
+	}
+
+	cfgVal := *currentConfig.MQTT
+	if cfgVal.BrokerAddr == "" {
+		_, port, _ := net.SplitHostPort(cfgVal.EmbeddedBroker)
+		cfgVal.BrokerAddr = net.JoinHostPort("127.0.0.1", port)
+	}
+	if cfgVal.ClientID == "" {
+		cfgVal.ClientID = defaultMQTTClientID
+	}
+	if cfgVal.KeepAlive <= 0 {
+		cfgVal.KeepAlive = defaultMQTTKeepAlive
+	}
+	if cfgVal.ValueTopic == "" {
+		cfgVal.ValueTopic = defaultMQTTValueTopic
+	}
+	if cfgVal.CommandTopic == "" {
+		cfgVal.CommandTopic = defaultMQTTCommandTopic
+	}
+	if cfgVal.ResponseTopic == "" {
+		cfgVal.ResponseTopic = cfgVal.CommandTopic + "/response"
+	}
+	// 与mqtt.Client.Subscribe一致,QoS大于1时按1处理,否则所有发布都会失败
+	if cfgVal.QoS > 1 {
+		cfgVal.QoS = 1
+	}
+	return &cfgVal
+}
+
+/*
+MQTTConfig MQTT北向参数,EmbeddedBroker非空时在该地址上启动内嵌的Broker,
+BrokerAddr为空时连接内嵌的Broker,KeepAlive单位为秒,QoS仅支持0与1,大于1时按1处理
+ValueTopic中的{slaveID}、{tag}、{scanGroup}替换为Tag的对应取值
+*/
+type MQTTConfig struct {
+	BrokerAddr     string `json:"brokerAddr"`
+	EmbeddedBroker string `json:"embeddedBroker,omitempty"`
+	ClientID       string `json:"clientID,omitempty"`
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	KeepAlive      int    `json:"keepAlive,omitempty"`
+	QoS            byte   `json:"qos,omitempty"`
+	Retain         bool   `json:"retain,omitempty"`
+	ValueTopic     string `json:"valueTopic,omitempty"`
+	CommandTopic   string `json:"commandTopic,omitempty"`
+	ResponseTopic  string `json:"responseTopic,omitempty"`
+}
+
+// History 历史数据参数,未配置Dir时返回nil
+func History() *HistoryConfig {
+	if currentConfig.History == nil || currentConfig.History.Dir == "" {
+		return nil
+	}
+
+	return currentConfig.History
+}
+
+/*
+HistoryConfig 历史数据参数,SegmentDuration与Retention为Go的时长格式(如1h、720h)
+SegmentDuration为空时为1小时,Retention为空时不清理历史数据
+*/
+type HistoryConfig struct {
+	Dir             string `json:"dir"`
+	SegmentDuration string `json:"segmentDuration,omitempty"`
+	Retention       string `json:"retention,omitempty"`
+}
+
+func (s *HistoryConfig) Options() (ret history.Options, err error) {
+	ret.Dir = s.Dir
+	if s.SegmentDuration != "" {
+		ret.SegmentDuration, err = time.ParseDuration(s.SegmentDuration)
+		if err != nil {
+			return
+		}
+	}
+	if s.Retention != "" {
+		ret.Retention, err = time.ParseDuration(s.Retention)
+	}
+	return
+}
+
+// ListenerConfig Mode取值与ConnectSlaveRequest.DeviceType一致
+type ListenerConfig struct {
+	BindPort string `json:"bindPort"`
+	Mode     byte   `json:"mode"`
+}
+
+func (s ListenerConfig) BindAddr() string {
+	return fmt.Sprintf("0.0.0.0:%s", s.BindPort)
+}
+
 type config struct {
-	ModbusBindPort string `json:"modbusBindPort"`
+	ModbusBindPort string                 `json:"modbusBindPort"`
+	SlaveListeners []ListenerConfig       `json:"slaveListeners"`
+	DataFile       string                 `json:"dataFile"`
+	SlaveUnits     []datastore.UnitConfig `json:"slaveUnits"`
+	TemplateFiles  []string               `json:"templateFiles"`
+	ScanGroups     []*common.ScanGroup    `json:"scanGroups"`
+	MQTT           *MQTTConfig            `json:"mqtt"`
+	History        *HistoryConfig         `json:"history"`
 }
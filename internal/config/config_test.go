@@ -0,0 +1,20 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMQTT(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "cfg.json")
+	_ = os.WriteFile(cfgFile, []byte(`{"mqtt":{"embeddedBroker":"0.0.0.0:1883","qos":2}}`), 0644)
+	if err := LoadConfig(cfgFile); err != nil {
+		t.Fatalf("LoadConfig failed, error:%s", err.Error())
+	}
+
+	cfgVal := MQTT()
+	if cfgVal == nil || cfgVal.QoS != 1 || cfgVal.BrokerAddr != "127.0.0.1:1883" || cfgVal.ResponseTopic != defaultMQTTCommandTopic+"/response" {
+		t.Errorf("MQTT failed, config:%+v", cfgVal)
+	}
+}
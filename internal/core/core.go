@@ -1,17 +1,21 @@
 package core
 
 import (
+	"fmt"
+	"net/http"
 	"sync"
 
 	cd "github.com/muidea/magicCommon/def"
 	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
 	"github.com/muidea/magicCommon/module"
 	"github.com/muidea/magicCommon/task"
 
 	engine "github.com/muidea/magicEngine/http"
 
 	_ "github.com/muidea/quickModbus/internal/core/kernel/master"
-	//_ "github.com/muidea/quickModbus/internal/core/kernel/slave"
+	_ "github.com/muidea/quickModbus/internal/core/kernel/mqtt"
+	_ "github.com/muidea/quickModbus/internal/core/kernel/slave"
 )
 
 // New 新建Core
@@ -25,6 +29,11 @@ func New(endpointName, listenPort string) (ret *Core, err *cd.Result) {
 	return
 }
 
+// rawHandlerModule 需要直接使用http.ResponseWriter的模块实现该接口,例如依赖Flush的推送接口
+type rawHandlerModule interface {
+	RawHandlers() map[string]http.HandlerFunc
+}
+
 // Core Core对象
 type Core struct {
 	endpointName string
@@ -66,7 +75,34 @@ func (s *Core) Run() {
 	}()
 
 	wg.Wait()
-	s.httpServer.Run()
+	s.serve()
+}
+
+// serve 模块提供的RawHandlers优先处理,其余请求交由httpServer
+func (s *Core) serve() {
+	mux := http.NewServeMux()
+	rawCount := 0
+	for _, val := range module.GetModules() {
+		rawModule, ok := val.(rawHandlerModule)
+		if !ok {
+			continue
+		}
+
+		for pattern, handler := range rawModule.RawHandlers() {
+			mux.HandleFunc(pattern, handler)
+			rawCount++
+		}
+	}
+
+	engineHandler, ok := s.httpServer.(http.Handler)
+	if rawCount == 0 || !ok {
+		s.httpServer.Run()
+		return
+	}
+
+	mux.Handle("/", engineHandler)
+	err := http.ListenAndServe(fmt.Sprintf(":%s", s.listenPort), mux)
+	log.Criticalf("run httpserver fatal, err:%s", err.Error())
 }
 
 // Shutdown 销毁
@@ -2,8 +2,13 @@ package biz
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"sort"
+	"sync"
 
 	cd "github.com/muidea/magicCommon/def"
 	"github.com/muidea/magicCommon/event"
@@ -13,62 +18,237 @@ import (
 
 	"github.com/muidea/quickModbus/internal/core/base/biz"
 	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/history"
 	"github.com/muidea/quickModbus/pkg/model"
+	"github.com/muidea/quickModbus/pkg/serial"
 )
 
 type Master struct {
 	biz.Base
 
 	slaveInfoCache cache.KVCache
+
+	linkLock sync.Mutex
+	linkMap  map[string]*linkEntry
+
+	tagLock sync.RWMutex
+	tagMap  map[string]*common.Tag
+
+	scanLock     sync.Mutex
+	scanGroupMap map[string]*scanGroup
+
+	valueLock  sync.RWMutex
+	valueCache map[string]*common.TagValue
+	reportMap  map[string]*common.TagValue
+
+	subscribeLock sync.RWMutex
+	subscribeID   int
+	subscriberMap map[int]*valueSubscriber
+
+	historyPtr    *history.Store
+	historyLock   sync.Mutex
+	historyFailed bool
+}
+
+type linkEntry struct {
+	link         *mbLink
+	serialConfig *serial.Config
 }
 
 func New(
 	eventHub event.Hub,
 	backgroundRoutine task.BackgroundRoutine,
 ) *Master {
-	return &Master{
+	ptr := &Master{
 		Base:           biz.New(common.MasterModule, eventHub, backgroundRoutine),
 		slaveInfoCache: cache.NewKVCache(nil),
+		linkMap:        map[string]*linkEntry{},
+		tagMap:         map[string]*common.Tag{},
+		scanGroupMap:   map[string]*scanGroup{},
+		valueCache:     map[string]*common.TagValue{},
+		reportMap:      map[string]*common.TagValue{},
+		subscriberMap:  map[int]*valueSubscriber{},
+	}
+
+	ptr.Timer(superviseInterval, 0, ptr.superviseSlaves)
+	ptr.Timer(scanTick, 0, ptr.dispatchScan)
+	ptr.SubscribeFunc(common.WriteCommandEvent, ptr.handleWriteCommand)
+	return ptr
+}
+
+func (s *Master) ConnectSlave(slaveAddr string, devID, devType, endianType byte, serialConfig *serial.Config, policy common.RequestPolicy, maxTransactions int, coalesce common.ReadCoalesce) (ret string, err *cd.Result) {
+	coalesceErr := coalesce.Validate()
+	if coalesceErr != nil {
+		log.Errorf("connectSlave failed, error:%s", coalesceErr.Error())
+		err = cd.NewError(cd.IllegalParam, coalesceErr.Error())
+		return
+	}
+
+	// 链路在持有linkLock时登记并增加引用,同一链路上并发的连接共享该链路,连接动作由链路自身串行化
+	s.linkLock.Lock()
+	err = s.checkDuplicate(slaveAddr, devID)
+	if err != nil {
+		s.linkLock.Unlock()
+		return
 	}
+	linkPtr, linkErr := s.fetchLink(slaveAddr, devType, serialConfig, maxTransactions)
+	if linkErr != nil {
+		s.linkLock.Unlock()
+		log.Errorf("connectSlave failed, error:%s", linkErr.Error())
+		err = cd.NewError(cd.IllegalParam, linkErr.Error())
+		return
+	}
+	linkPtr.acquire()
+	s.linkLock.Unlock()
+
+	// 连接可能阻塞数秒,不能持有linkLock,以免影响其它从站
+	startErr := linkPtr.Start(slaveAddr)
+
+	s.linkLock.Lock()
+	defer s.linkLock.Unlock()
+	if startErr != nil {
+		s.abortLink(linkPtr, slaveAddr, devType)
+		log.Errorf("connectSlave failed, error:%s", startErr.Error())
+		err = cd.NewError(cd.UnExpected, startErr.Error())
+		return
+	}
+
+	// 连接期间可能已有其它调用方连接了同一从站
+	err = s.checkDuplicate(slaveAddr, devID)
+	if err != nil {
+		s.abortLink(linkPtr, slaveAddr, devType)
+		return
+	}
+	slaveID, idErr := s.newSlaveID(slaveAddr, devID)
+	if idErr != nil {
+		s.abortLink(linkPtr, slaveAddr, devType)
+		log.Errorf("connectSlave failed, error:%s", idErr.Error())
+		err = cd.NewError(cd.Duplicated, idErr.Error())
+		return
+	}
+
+	masterPtr := newMaster(linkPtr, slaveID, devID, endianType)
+	masterPtr.SetPolicy(policy)
+	s.slaveInfoCache.Put(slaveID, newSlaveInfo(slaveID, slaveAddr, devID, devType, endianType, coalesce, masterPtr), cache.ForeverAgeValue)
+	ret = slaveID
+	return
 }
 
-func (s *Master) ConnectSlave(slaveAddr string, devID, devType, endianType byte) (ret string, err *cd.Result) {
-	slaveID := fmt.Sprintf("mb%03d", devID)
-	val := s.slaveInfoCache.Fetch(slaveID)
-	if val != nil {
-		errMsg := fmt.Sprintf("duplicate slave device %d", devID)
-		log.Errorf("connectSlave failed, error:%s", errMsg)
-		err = cd.NewError(cd.Duplicated, errMsg)
+// checkDuplicate 需要持有linkLock
+func (s *Master) checkDuplicate(slaveAddr string, devID byte) (err *cd.Result) {
+	if s.findSlaveID(slaveAddr, devID) == "" {
 		return
 	}
 
-	var masterPtr MBMaster
-	if devType == common.ModbusTcp {
-		masterPtr = NewTCPMaster(devID, endianType)
-	} else if devType == common.ModbusRTUOverTcp {
-		masterPtr = NewRTUMaster(devID, endianType)
-	} else if devType == common.ModbusASCIIOverTcp {
-		masterPtr = NewASCIIMaster(devID, endianType)
-	} else {
-		errMsg := fmt.Sprintf("illegal slave device type, id:%v, type:%v", devID, devType)
-		log.Errorf("connectSlave failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+	errMsg := fmt.Sprintf("duplicate slave device %d at %s", devID, slaveAddr)
+	log.Errorf("connectSlave failed, error:%s", errMsg)
+	err = cd.NewError(cd.Duplicated, errMsg)
+	return
+}
+
+// abortLink 释放连接失败时增加的链路引用,需要持有linkLock
+func (s *Master) abortLink(linkPtr *mbLink, slaveAddr string, devType byte) {
+	linkPtr.release()
+	s.releaseLink(slaveAddr, devType)
+}
+
+// findSlaveID 查找已连接的从站,不存在时返回空
+func (s *Master) findSlaveID(slaveAddr string, devID byte) string {
+	for _, val := range s.slaveInfoCache.GetAll() {
+		infoPtr := val.(*slaveInfo)
+		if infoPtr.slaveAddr == slaveAddr && infoPtr.devID == devID {
+			return infoPtr.slaveID
+		}
+	}
+
+	return ""
+}
+
+func defaultSlaveID(devID byte) string {
+	return fmt.Sprintf("mb%03d", devID)
+}
+
+// newSlaveID 默认为mb加设备地址,该设备地址已被其它网关上的从站占用时再附加网关地址摘要
+func (s *Master) newSlaveID(slaveAddr string, devID byte) (ret string, err error) {
+	slaveID := defaultSlaveID(devID)
+	if s.slaveInfoCache.Fetch(slaveID) == nil {
+		ret = slaveID
 		return
 	}
 
-	errInfo := masterPtr.Start(slaveAddr)
-	if errInfo != nil {
-		log.Errorf("connectSlave failed, error:%s", errInfo.Error())
-		err = cd.NewError(cd.UnExpected, errInfo.Error())
+	slaveID = fmt.Sprintf("mb%03d-%08x", devID, crc32.ChecksumIEEE([]byte(slaveAddr)))
+	if s.slaveInfoCache.Fetch(slaveID) != nil {
+		err = fmt.Errorf("duplicate slave id %s", slaveID)
 		return
 	}
 
-	s.slaveInfoCache.Put(slaveID, masterPtr, cache.ForeverAgeValue)
 	ret = slaveID
 	return
 }
 
+func linkKey(slaveAddr string, devType byte) string {
+	return fmt.Sprintf("%d|%s", devType, slaveAddr)
+}
+
+// fetchLink 同一地址上相同类型的从站共享一条链路,链路参数以首次创建时为准
+func (s *Master) fetchLink(slaveAddr string, devType byte, serialConfig *serial.Config, maxTransactions int) (ret *mbLink, err error) {
+	var configVal *serial.Config
+	if devType == common.ModbusRTU || devType == common.ModbusASCII {
+		config := getSerialConfig(serialConfig)
+		configVal = &config
+	}
+
+	keyVal := linkKey(slaveAddr, devType)
+	entryPtr, entryOK := s.linkMap[keyVal]
+	if entryOK {
+		if configVal != nil && *configVal != *entryPtr.serialConfig {
+			err = fmt.Errorf("mismatch serial config for %s", slaveAddr)
+			return
+		}
+
+		ret = entryPtr.link
+		return
+	}
+
+	switch devType {
+	case common.ModbusTcp:
+		ret = newTCPLink(maxTransactions)
+	case common.ModbusRTUOverTcp, common.ModbusRTU:
+		ret = newRTULink(configVal)
+	case common.ModbusASCIIOverTcp, common.ModbusASCII:
+		ret = newASCIILink(configVal)
+	default:
+		err = fmt.Errorf("illegal slave device type:%v", devType)
+		return
+	}
+
+	s.linkMap[keyVal] = &linkEntry{link: ret, serialConfig: configVal}
+	return
+}
+
+// releaseLink 链路上已经没有从站时从链路表中移除
+func (s *Master) releaseLink(slaveAddr string, devType byte) {
+	keyVal := linkKey(slaveAddr, devType)
+	entryPtr, entryOK := s.linkMap[keyVal]
+	if !entryOK || entryPtr.link.refs() > 0 {
+		return
+	}
+
+	delete(s.linkMap, keyVal)
+}
+
+func getSerialConfig(serialConfig *serial.Config) serial.Config {
+	if serialConfig == nil {
+		return serial.DefaultConfig()
+	}
+
+	return *serialConfig
+}
+
 func (s *Master) DisConnectSlave(slaveID string) (err *cd.Result) {
+	s.linkLock.Lock()
+	defer s.linkLock.Unlock()
+
 	vVal := s.slaveInfoCache.Fetch(slaveID)
 	if vVal == nil {
 		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
@@ -77,34 +257,126 @@ func (s *Master) DisConnectSlave(slaveID string) (err *cd.Result) {
 		return
 	}
 
-	vVal.(MBMaster).Stop()
+	infoPtr := vVal.(*slaveInfo)
 	s.slaveInfoCache.Remove(slaveID)
+	infoPtr.close()
+	s.releaseLink(infoPtr.slaveAddr, infoPtr.devType)
 	return
 }
 
-func (s *Master) ReadCoils(slaveID string, address, count uint16) (ret []bool, exCode byte, err *cd.Result) {
+// Teardown 断开所有从站,等待中的请求随链路关闭立即返回
+func (s *Master) Teardown() {
+	s.linkLock.Lock()
+	defer s.linkLock.Unlock()
+
+	for _, val := range s.slaveInfoCache.GetAll() {
+		infoPtr := val.(*slaveInfo)
+		s.slaveInfoCache.Remove(infoPtr.slaveID)
+		infoPtr.close()
+		s.releaseLink(infoPtr.slaveAddr, infoPtr.devType)
+	}
+
+	if s.historyPtr != nil {
+		err := s.historyPtr.Close()
+		if err != nil {
+			log.Errorf("close history failed, error:%s", err.Error())
+		}
+	}
+}
+
+func (s *Master) ListSlave() (ret []*common.SlaveView) {
+	ret = []*common.SlaveView{}
+	for _, val := range s.slaveInfoCache.GetAll() {
+		ret = append(ret, val.(*slaveInfo).View())
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].SlaveID < ret[j].SlaveID
+	})
+	return
+}
+
+func (s *Master) QuerySlave(slaveID string) (ret *common.SlaveView, err *cd.Result) {
 	vVal := s.slaveInfoCache.Fetch(slaveID)
 	if vVal == nil {
 		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("readCoils failed, error:%s", errMsg)
+		log.Errorf("querySlave failed, error:%s", errMsg)
 		err = cd.NewError(cd.UnExpected, errMsg)
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("readCoils failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
+	ret = vVal.(*slaveInfo).View()
+	return
+}
+
+func (s *Master) QuerySlaveStatus(slaveID string) (ret *common.SlaveStatus, err *cd.Result) {
+	vVal := s.slaveInfoCache.Fetch(slaveID)
+	if vVal == nil {
+		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
+		log.Errorf("querySlaveStatus failed, error:%s", errMsg)
+		err = cd.NewError(cd.UnExpected, errMsg)
+		return
+	}
+
+	infoPtr := vVal.(*slaveInfo)
+	statsVal := infoPtr.master.Stats()
+	reconnectCount, failedReconnects := infoPtr.ReconnectCount()
+	ret = &common.SlaveStatus{
+		Status:              infoPtr.Status(),
+		ReconnectCount:      reconnectCount,
+		FailedReconnects:    failedReconnects,
+		ConsecutiveFailures: statsVal.ConsecutiveFailures,
+		LastError:           infoPtr.LastError(),
+	}
+	if ret.LastError == "" && statsVal.ConsecutiveFailures > 0 {
+		ret.LastError = statsVal.LastError
+	}
+	return
+}
+
+// fetchMaster 获取从站,链路断开时立即尝试重连
+func (s *Master) fetchMaster(slaveID string) (ret MBMaster, err *cd.Result) {
+	vVal := s.slaveInfoCache.Fetch(slaveID)
+	if vVal == nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("no exist slave device %s", slaveID))
+		return
+	}
+
+	infoPtr := vVal.(*slaveInfo)
+	connErr := infoPtr.ensureConnect()
+	if connErr != nil {
+		err = cd.NewError(cd.UnExpected, connErr.Error())
+		return
 	}
 
-	readVal, readExCode, readErr := mbMasterPtr.ReadCoils(address, count)
+	ret = infoPtr.master
+	return
+}
+
+// requestError 调用方取消与等待应答超时使用独立的错误码,便于调用方区分链路故障
+func requestError(err error) *cd.Result {
+	switch {
+	case errors.Is(err, ErrRequestCanceled):
+		return cd.NewError(common.RequestCanceled, err.Error())
+	case errors.Is(err, ErrTimeout):
+		return cd.NewError(common.RequestTimeout, err.Error())
+	}
+
+	return cd.NewError(cd.UnExpected, err.Error())
+}
+
+func (s *Master) ReadCoils(ctx context.Context, slaveID string, policy *common.RequestPolicy, address, count uint16) (ret []bool, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("readCoils failed, error:%s", mbErr.Reason)
+		err = mbErr
+		return
+	}
+
+	readVal, readExCode, readErr := mbMasterPtr.ReadCoils(ctx, policy, address, count)
 	if readErr != nil {
 		log.Errorf("readCoils failed, error:%s", readErr.Error())
-		err = cd.NewError(cd.UnExpected, readErr.Error())
+		err = requestError(readErr)
 		return
 	}
 	if readExCode != model.SuccessCode {
@@ -121,33 +393,28 @@ func (s *Master) ReadCoils(slaveID string, address, count uint16) (ret []bool, e
 		err = cd.NewError(cd.UnExpected, boolErr.Error())
 		return
 	}
+	if len(boolVal) < int(count) {
+		log.Errorf("readCoils failed, illegal read value count, expect:%d, actual:%d", count, len(boolVal))
+		err = cd.NewError(cd.UnExpected, illegalValueCount)
+		return
+	}
 
 	ret = boolVal[:count]
 	return
 }
 
-func (s *Master) ReadDiscreteInputs(slaveID string, address, count uint16) (ret []bool, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("readDiscreteInputs failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadDiscreteInputs(ctx context.Context, slaveID string, policy *common.RequestPolicy, address, count uint16) (ret []bool, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("readDiscreteInputs failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("readDiscreteInputs failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
 
-	readVal, readExCode, readErr := mbMasterPtr.ReadDiscreteInputs(address, count)
+	readVal, readExCode, readErr := mbMasterPtr.ReadDiscreteInputs(ctx, policy, address, count)
 	if readErr != nil {
 		log.Errorf("readDiscreteInputs failed, error:%s", readErr.Error())
-		err = cd.NewError(cd.UnExpected, readErr.Error())
+		err = requestError(readErr)
 		return
 	}
 	if readExCode != model.SuccessCode {
@@ -164,6 +431,11 @@ func (s *Master) ReadDiscreteInputs(slaveID string, address, count uint16) (ret
 		err = cd.NewError(cd.UnExpected, boolErr.Error())
 		return
 	}
+	if len(boolVal) < int(count) {
+		log.Errorf("readDiscreteInputs failed, illegal read value count, expect:%d, actual:%d", count, len(boolVal))
+		err = cd.NewError(cd.UnExpected, illegalValueCount)
+		return
+	}
 
 	ret = boolVal[:count]
 	return
@@ -227,35 +499,24 @@ func (s *Master) decodeReadVal(readVal []byte, valueType, count uint16, endianTy
 	return itemVal, itemErr
 }
 
-func (s *Master) ReadHoldingRegisters(slaveID string, address, count, valueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadHoldingRegisters failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadHoldingRegisters(ctx context.Context, slaveID string, policy *common.RequestPolicy, address, count, valueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadHoldingRegisters failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadHoldingRegisters failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	dataCount, dataErr := s.prepareReadData(count, valueType)
 	if dataErr != nil {
 		err = cd.NewError(cd.UnExpected, dataErr.Error())
 		return
 	}
 
-	readVal, readExCode, readErr := mbMasterPtr.ReadHoldingRegisters(address, dataCount)
+	readVal, readExCode, readErr := mbMasterPtr.ReadHoldingRegisters(ctx, policy, address, dataCount)
 	if readErr != nil {
 		log.Errorf("ReadHoldingRegisters failed, error:%s", readErr.Error())
-		err = cd.NewError(cd.UnExpected, readErr.Error())
+		err = requestError(readErr)
 		return
 	}
 	if readExCode != model.SuccessCode {
@@ -288,23 +549,13 @@ func (s *Master) ReadHoldingRegisters(slaveID string, address, count, valueType
 	return
 }
 
-func (s *Master) ReadInputRegisters(slaveID string, address, count, valueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadInputRegisters failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadInputRegisters(ctx context.Context, slaveID string, policy *common.RequestPolicy, address, count, valueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadInputRegisters failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadInputRegisters failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
 
 	dataCount, dataErr := s.prepareReadData(count, valueType)
 	if dataErr != nil {
@@ -313,10 +564,10 @@ func (s *Master) ReadInputRegisters(slaveID string, address, count, valueType ui
 		return
 	}
 
-	readVal, readExCode, readErr := mbMasterPtr.ReadInputRegisters(address, dataCount)
+	readVal, readExCode, readErr := mbMasterPtr.ReadInputRegisters(ctx, policy, address, dataCount)
 	if readErr != nil {
 		log.Errorf("ReadInputRegisters failed, error:%s", readErr.Error())
-		err = cd.NewError(cd.UnExpected, readErr.Error())
+		err = requestError(readErr)
 		return
 	}
 	if readExCode != model.SuccessCode {
@@ -349,25 +600,14 @@ func (s *Master) ReadInputRegisters(slaveID string, address, count, valueType ui
 	return
 }
 
-func (s *Master) WriteSingleCoil(slaveID string, address uint16, value bool) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("readCoils failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) WriteSingleCoil(ctx context.Context, slaveID string, policy *common.RequestPolicy, address uint16, value bool) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("readCoils failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("readCoils failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	var byteVal []byte
 	if value {
 		byteVal = model.CoilON
@@ -375,10 +615,10 @@ func (s *Master) WriteSingleCoil(slaveID string, address uint16, value bool) (ex
 		byteVal = model.CoilOFF
 	}
 
-	writeAddr, writeData, writeExCode, writeErr := mbMasterPtr.WriteSingleCoil(address, byteVal)
+	writeAddr, writeData, writeExCode, writeErr := mbMasterPtr.WriteSingleCoil(ctx, policy, address, byteVal)
 	if writeErr != nil {
 		log.Errorf("writeCoils failed, error:%s", writeErr.Error())
-		err = cd.NewError(cd.UnExpected, writeErr.Error())
+		err = requestError(writeErr)
 		return
 	}
 	if writeExCode != model.SuccessCode {
@@ -398,25 +638,14 @@ func (s *Master) WriteSingleCoil(slaveID string, address uint16, value bool) (ex
 	return
 }
 
-func (s *Master) WriteMultipleCoils(slaveID string, address uint16, value []bool) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("writeMultipleCoils failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) WriteMultipleCoils(ctx context.Context, slaveID string, policy *common.RequestPolicy, address uint16, value []bool) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("writeMultipleCoils failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("writeMultipleCoils failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	valCount := uint16(len(value))
 	var byteVal []byte
 	var byteErr error
@@ -427,10 +656,10 @@ func (s *Master) WriteMultipleCoils(slaveID string, address uint16, value []bool
 		return
 	}
 
-	writeAddr, writeCount, writeExCode, writeErr := mbMasterPtr.WriteMultipleCoils(address, valCount, byteVal)
+	writeAddr, writeCount, writeExCode, writeErr := mbMasterPtr.WriteMultipleCoils(ctx, policy, address, valCount, byteVal)
 	if writeErr != nil {
 		log.Errorf("writeMultipleCoils failed, error:%s", writeErr.Error())
-		err = cd.NewError(cd.UnExpected, writeErr.Error())
+		err = requestError(writeErr)
 		return
 	}
 	if writeExCode != model.SuccessCode {
@@ -450,25 +679,14 @@ func (s *Master) WriteMultipleCoils(slaveID string, address uint16, value []bool
 	return
 }
 
-func (s *Master) WriteSingleRegister(slaveID string, address, value uint16, endianType byte) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("WriteSingleRegister failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) WriteSingleRegister(ctx context.Context, slaveID string, policy *common.RequestPolicy, address, value uint16, endianType byte) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("WriteSingleRegister failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("WriteSingleRegister failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	var byteVal []byte
 	var byteErr error
 
@@ -482,10 +700,10 @@ func (s *Master) WriteSingleRegister(slaveID string, address, value uint16, endi
 		return
 	}
 
-	writeAddr, writeData, writeExCode, writeErr := mbMasterPtr.WriteSingleRegister(address, byteVal)
+	writeAddr, writeData, writeExCode, writeErr := mbMasterPtr.WriteSingleRegister(ctx, policy, address, byteVal)
 	if writeErr != nil {
 		log.Errorf("WriteSingleRegister failed, error:%s", writeErr.Error())
-		err = cd.NewError(cd.UnExpected, writeErr.Error())
+		err = requestError(writeErr)
 		return
 	}
 	if writeExCode != model.SuccessCode {
@@ -504,24 +722,13 @@ func (s *Master) WriteSingleRegister(slaveID string, address, value uint16, endi
 	return
 }
 
-func (s *Master) WriteMultipleRegisters(slaveID string, address uint16, values []float64, valueTyp uint16, endianType byte) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("writeMultipleRegisters failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) WriteMultipleRegisters(ctx context.Context, slaveID string, policy *common.RequestPolicy, address uint16, values []float64, valueTyp uint16, endianType byte) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("writeMultipleRegisters failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
-
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("writeMultipleRegisters failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
 	if endianType == common.DefaultEndian {
 		endianType = mbMasterPtr.EndianType()
 	}
@@ -570,10 +777,10 @@ func (s *Master) WriteMultipleRegisters(slaveID string, address uint16, values [
 		}
 	}
 
-	writeAddr, writeCount, writeExCode, writeErr := mbMasterPtr.WriteMultipleRegisters(address, valCount, byteVal)
+	writeAddr, writeCount, writeExCode, writeErr := mbMasterPtr.WriteMultipleRegisters(ctx, policy, address, valCount, byteVal)
 	if writeErr != nil {
 		log.Errorf("writeMultipleRegisters failed, error:%s", writeErr.Error())
-		err = cd.NewError(cd.UnExpected, writeErr.Error())
+		err = requestError(writeErr)
 		return
 	}
 	if writeExCode != model.SuccessCode {
@@ -593,25 +800,14 @@ func (s *Master) WriteMultipleRegisters(slaveID string, address uint16, values [
 	return
 }
 
-func (s *Master) MaskWriteRegister(slaveID string, address uint16, andMask uint16, orMask uint16) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("MaskWriteRegister failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) MaskWriteRegister(ctx context.Context, slaveID string, policy *common.RequestPolicy, address uint16, andMask uint16, orMask uint16) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("MaskWriteRegister failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("readCoils failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	var andByteVal []byte
 	var andErr error
 	andByteVal, andErr = common.AppendUint16(andByteVal, andMask, common.DefaultEndian)
@@ -630,10 +826,10 @@ func (s *Master) MaskWriteRegister(slaveID string, address uint16, andMask uint1
 		return
 	}
 
-	maskAddr, maskAnd, maskOr, maskExCode, maskErr := mbMasterPtr.MaskWriteRegister(address, andByteVal, orByteVal)
+	maskAddr, maskAnd, maskOr, maskExCode, maskErr := mbMasterPtr.MaskWriteRegister(ctx, policy, address, andByteVal, orByteVal)
 	if maskErr != nil {
 		log.Errorf("MaskWriteRegister failed, error:%s", maskErr.Error())
-		err = cd.NewError(cd.UnExpected, maskErr.Error())
+		err = requestError(maskErr)
 		return
 	}
 	if maskExCode != model.SuccessCode {
@@ -653,24 +849,13 @@ func (s *Master) MaskWriteRegister(slaveID string, address uint16, andMask uint1
 	return
 }
 
-func (s *Master) ReadWriteMultipleRegisters(slaveID string, readAddr, readCount, readValueType uint16, writeAddr uint16, writeValues []float64, writeValueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadWriteMultipleRegisters failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadWriteMultipleRegisters(ctx context.Context, slaveID string, policy *common.RequestPolicy, readAddr, readCount, readValueType uint16, writeAddr uint16, writeValues []float64, writeValueType uint16, endianType byte) (ret interface{}, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadWriteMultipleRegisters failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
-
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadWriteMultipleRegisters failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
 	if endianType == common.DefaultEndian {
 		endianType = mbMasterPtr.EndianType()
 	}
@@ -688,10 +873,10 @@ func (s *Master) ReadWriteMultipleRegisters(slaveID string, readAddr, readCount,
 		return
 	}
 
-	retVal, retExCode, retErr := mbMasterPtr.ReadWriteMultipleRegisters(readAddr, readValCount, writeAddr, writeCount, writeByteVal)
+	retVal, retExCode, retErr := mbMasterPtr.ReadWriteMultipleRegisters(ctx, policy, readAddr, readValCount, writeAddr, writeCount, writeByteVal)
 	if retErr != nil {
 		log.Errorf("ReadWriteMultipleRegisters failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -789,29 +974,18 @@ func (s *Master) prepareWriteData(values []float64, valueType uint16, endianType
 	return writeByteVal, writeCount, nil
 }
 
-func (s *Master) ReadExceptionStatus(slaveID string) (status, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadExceptionStatus failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadExceptionStatus(ctx context.Context, slaveID string, policy *common.RequestPolicy) (status, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadExceptionStatus failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadExceptionStatus failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retVal, retExCode, retErr := mbMasterPtr.ReadExceptionStatus()
+	retVal, retExCode, retErr := mbMasterPtr.ReadExceptionStatus(ctx, policy)
 	if retErr != nil {
 		log.Errorf("ReadExceptionStatus failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -826,25 +1000,14 @@ func (s *Master) ReadExceptionStatus(slaveID string) (status, exCode byte, err *
 	return
 }
 
-func (s *Master) Diagnostics(slaveID string, subFuncCode uint16, dataVal string) (ret string, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("Diagnostics failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) Diagnostics(ctx context.Context, slaveID string, policy *common.RequestPolicy, subFuncCode uint16, dataVal string) (ret string, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("Diagnostics failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("Diagnostics failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
 	byteVal, byteErr := hex.DecodeString(dataVal)
 	if byteErr != nil {
 		log.Errorf("Diagnostics failed, hex.DecodeString error:%s", byteErr.Error())
@@ -852,10 +1015,10 @@ func (s *Master) Diagnostics(slaveID string, subFuncCode uint16, dataVal string)
 		return
 	}
 
-	retSubFuncCode, retDataVal, retExCode, retErr := mbMasterPtr.Diagnostics(subFuncCode, byteVal)
+	retSubFuncCode, retDataVal, retExCode, retErr := mbMasterPtr.Diagnostics(ctx, policy, subFuncCode, byteVal)
 	if retErr != nil {
 		log.Errorf("ReadExceptionStatus failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -876,29 +1039,18 @@ func (s *Master) Diagnostics(slaveID string, subFuncCode uint16, dataVal string)
 	return
 }
 
-func (s *Master) GetCommEventCounter(slaveID string) (status, eventCount uint16, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("GetCommEventCounter failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) GetCommEventCounter(ctx context.Context, slaveID string, policy *common.RequestPolicy) (status, eventCount uint16, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("GetCommEventCounter failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("GetCommEventCounter failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retStatus, retEventCount, retExCode, retErr := mbMasterPtr.GetCommEventCounter()
+	retStatus, retEventCount, retExCode, retErr := mbMasterPtr.GetCommEventCounter(ctx, policy)
 	if retErr != nil {
 		log.Errorf("GetCommEventCounter failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -914,29 +1066,18 @@ func (s *Master) GetCommEventCounter(slaveID string) (status, eventCount uint16,
 	return
 }
 
-func (s *Master) GetCommEventLog(slaveID string) (status, eventCount, messageCount uint16, events string, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("GetCommEventLog failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) GetCommEventLog(ctx context.Context, slaveID string, policy *common.RequestPolicy) (status, eventCount, messageCount uint16, events string, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("GetCommEventLog failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("GetCommEventLog failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retStatus, retEventCount, retMessageCount, retEvents, retExCode, retErr := mbMasterPtr.GetCommEventLog()
+	retStatus, retEventCount, retMessageCount, retEvents, retExCode, retErr := mbMasterPtr.GetCommEventLog(ctx, policy)
 	if retErr != nil {
 		log.Errorf("GetCommEventLog failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -954,29 +1095,18 @@ func (s *Master) GetCommEventLog(slaveID string) (status, eventCount, messageCou
 	return
 }
 
-func (s *Master) ReportSlaveID(slaveID string) (ret string, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReportSlaveID failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReportSlaveID(ctx context.Context, slaveID string, policy *common.RequestPolicy) (ret string, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReportSlaveID failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReportSlaveID failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retSlaveInfo, retExCode, retErr := mbMasterPtr.ReportSlaveID()
+	retSlaveInfo, retExCode, retErr := mbMasterPtr.ReportSlaveID(ctx, policy)
 	if retErr != nil {
 		log.Errorf("ReportSlaveID failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -991,29 +1121,18 @@ func (s *Master) ReportSlaveID(slaveID string) (ret string, exCode byte, err *cd
 	return
 }
 
-func (s *Master) ReadFileRecord(slaveID string, items []*common.ReadItem) (ret []string, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadFileRecord failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadFileRecord(ctx context.Context, slaveID string, policy *common.RequestPolicy, items []*common.ReadItem) (ret []string, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadFileRecord failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadFileRecord failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retFileContent, retExCode, retErr := mbMasterPtr.ReadFileRecord(items)
+	retFileContent, retExCode, retErr := mbMasterPtr.ReadFileRecord(ctx, policy, items)
 	if retErr != nil {
 		log.Errorf("ReadFileRecord failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -1030,29 +1149,18 @@ func (s *Master) ReadFileRecord(slaveID string, items []*common.ReadItem) (ret [
 	return
 }
 
-func (s *Master) WriteFileRecord(slaveID string, items []*common.WriteItem) (exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("WriteFileRecord failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) WriteFileRecord(ctx context.Context, slaveID string, policy *common.RequestPolicy, items []*common.WriteItem) (exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("WriteFileRecord failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("WriteFileRecord failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	retExCode, retErr := mbMasterPtr.WriteFileRecord(items)
+	retExCode, retErr := mbMasterPtr.WriteFileRecord(ctx, policy, items)
 	if retErr != nil {
 		log.Errorf("WriteFileRecord failed, error:%s", retErr.Error())
-		err = cd.NewError(cd.UnExpected, retErr.Error())
+		err = requestError(retErr)
 		return
 	}
 	if retExCode != model.SuccessCode {
@@ -1066,29 +1174,18 @@ func (s *Master) WriteFileRecord(slaveID string, items []*common.WriteItem) (exC
 	return
 }
 
-func (s *Master) ReadFIFOQueue(slaveID string, address uint16) (retData []string, exCode byte, err *cd.Result) {
-	vVal := s.slaveInfoCache.Fetch(slaveID)
-	if vVal == nil {
-		errMsg := fmt.Sprintf("no exist slave device %s", slaveID)
-		log.Errorf("ReadFIFOQueue failed, error:%s", errMsg)
-		err = cd.NewError(cd.UnExpected, errMsg)
+func (s *Master) ReadFIFOQueue(ctx context.Context, slaveID string, policy *common.RequestPolicy, address uint16) (retData []string, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(slaveID)
+	if mbErr != nil {
+		log.Errorf("ReadFIFOQueue failed, error:%s", mbErr.Reason)
+		err = mbErr
 		return
 	}
 
-	mbMasterPtr := vVal.(MBMaster)
-	if !mbMasterPtr.IsConnect() {
-		connErr := mbMasterPtr.ReConnect()
-		if connErr != nil {
-			log.Errorf("ReadFIFOQueue failed, reconnect slave error:%s", connErr.Error())
-			err = cd.NewError(cd.UnExpected, connErr.Error())
-			return
-		}
-	}
-
-	readDataCount, readDataVal, readExCode, readErr := mbMasterPtr.ReadFIFOQueue(address)
+	readDataCount, readDataVal, readExCode, readErr := mbMasterPtr.ReadFIFOQueue(ctx, policy, address)
 	if readErr != nil {
 		log.Errorf("ReadFIFOQueue failed, error:%s", readErr.Error())
-		err = cd.NewError(cd.UnExpected, readErr.Error())
+		err = requestError(readErr)
 		return
 	}
 	if readExCode != model.SuccessCode {
@@ -0,0 +1,77 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// ExecuteCommand 执行写入命令,使用从站默认的请求策略
+func (s *Master) ExecuteCommand(ctx context.Context, cmd *common.WriteCommand) (exCode byte, err *cd.Result) {
+	if cmd.Tag != "" {
+		return s.WriteTag(ctx, nil, cmd.Tag, cmd.Value)
+	}
+
+	switch cmd.Function {
+	case common.WriteSingleCoilFunction:
+		boolVal, boolOK := cmd.Value.(bool)
+		if !boolOK {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal coil value %v", cmd.Value))
+			break
+		}
+		exCode, err = s.WriteSingleCoil(ctx, cmd.SlaveID, nil, cmd.Address, boolVal)
+	case common.WriteSingleRegisterFunction:
+		registerVal, registerErr := cmd.RegisterValue()
+		if registerErr != nil {
+			err = cd.NewError(cd.IllegalParam, registerErr.Error())
+			break
+		}
+		exCode, err = s.WriteSingleRegister(ctx, cmd.SlaveID, nil, cmd.Address, registerVal, cmd.EndianType)
+	case common.WriteMultipleCoilsFunction:
+		boolValues, boolErr := cmd.BoolValues()
+		if boolErr != nil {
+			err = cd.NewError(cd.IllegalParam, boolErr.Error())
+			break
+		}
+		exCode, err = s.WriteMultipleCoils(ctx, cmd.SlaveID, nil, cmd.Address, boolValues)
+	case common.WriteMultipleRegistersFunction:
+		floatValues, floatErr := cmd.FloatValues()
+		if floatErr != nil {
+			err = cd.NewError(cd.IllegalParam, floatErr.Error())
+			break
+		}
+		valueType := cmd.ValueType
+		if valueType == common.RawValue {
+			valueType = common.UInt16Value
+		}
+		exCode, err = s.WriteMultipleRegisters(ctx, cmd.SlaveID, nil, cmd.Address, floatValues, valueType, cmd.EndianType)
+	default:
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal write function '%s'", cmd.Function))
+	}
+
+	if err != nil {
+		log.Errorf("executeCommand failed, id:%s, error:%s", cmd.ID, err.Reason)
+	}
+	return
+}
+
+// handleWriteCommand 处理WriteCommandEvent,结果为从站返回的异常码
+func (s *Master) handleWriteCommand(ev event.Event, result event.Result) {
+	cmdPtr, cmdOK := ev.Data().(*common.WriteCommand)
+	if !cmdOK {
+		if result != nil {
+			result.Set(nil, cd.NewError(cd.IllegalParam, "illegal write command"))
+		}
+		return
+	}
+
+	exCode, err := s.ExecuteCommand(context.Background(), cmdPtr)
+	if result != nil {
+		result.Set(exCode, err)
+	}
+}
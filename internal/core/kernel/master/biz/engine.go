@@ -0,0 +1,465 @@
+package biz
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// mbMaster 链路上的一个从站,请求经由共享的mbLink收发,slaveID用于指标的标签
+type mbMaster struct {
+	link       *mbLink
+	slaveID    string
+	unitID     byte
+	endianType byte
+	option     requestOption
+	stopOnce   sync.Once
+	statsRecorder
+}
+
+func newMaster(link *mbLink, slaveID string, unitID, endianType byte) *mbMaster {
+	return &mbMaster{
+		link:       link,
+		slaveID:    slaveID,
+		unitID:     unitID,
+		endianType: endianType,
+		option:     defaultRequestOption(),
+	}
+}
+
+func (s *mbMaster) Start(serverAddr string) (err error) {
+	s.link.acquire()
+	err = s.link.Start(serverAddr)
+	if err != nil {
+		s.link.release()
+	}
+	return
+}
+
+// Stop 释放对链路的引用,最后一个从站释放时关闭链路
+func (s *mbMaster) Stop() {
+	s.stopOnce.Do(func() {
+		s.link.release()
+	})
+}
+
+func (s *mbMaster) IsConnect() bool {
+	return s.link.IsConnect()
+}
+
+func (s *mbMaster) ReConnect() (err error) {
+	return s.link.ReConnect()
+}
+
+// SetPolicy 设置从站默认的超时与重试参数
+func (s *mbMaster) SetPolicy(policy common.RequestPolicy) {
+	s.option = defaultRequestOption().merge(&policy)
+}
+
+func (s *mbMaster) EndianType() byte {
+	return s.endianType
+}
+
+// transact 发送请求并等待对应的应答,超时或者发送失败时按照重试参数重发
+// ctx结束时立即放弃,返回ErrRequestCanceled且不计入链路失败
+func (s *mbMaster) transact(ctx context.Context, name string, policy *common.RequestPolicy, protocol model.MBProtocol) (ret model.MBProtocol, err error) {
+	option := s.option.merge(policy)
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, option.timeout)
+		startTime := time.Now()
+		ret, err = s.link.sendRequest(attemptCtx, name, s.slaveID, s.unitID, protocol)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			err = canceledError(ctx)
+			return
+		}
+
+		s.record(ret, err)
+		observeRequest(s.slaveID, protocol.FuncCode(), ret, err, time.Since(startTime))
+		if err == nil || attempt >= option.retries {
+			return
+		}
+
+		backoff := option.backoff(attempt)
+		log.Warnf("%s failed, retry %d/%d after %v, error:%s", name, attempt+1, option.retries, backoff, err.Error())
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			err = canceledError(ctx)
+			return
+		}
+	}
+}
+
+func illegalResponse(name, desc string) error {
+	err := fmt.Errorf("recv illegal %s response", desc)
+	log.Errorf("%s failed, error:%s", name, err.Error())
+	return err
+}
+
+func (s *mbMaster) ReadCoils(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadCoils", policy, model.NewReadCoilsReq(address, count))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadCoilsRsp)
+	if !readOK {
+		err = illegalResponse("ReadCoils", "read coils")
+		return
+	}
+
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadDiscreteInputs(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadDiscreteInputs", policy, model.NewReadDiscreteInputsReq(address, count))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadDiscreteInputsRsp)
+	if !readOK {
+		err = illegalResponse("ReadDiscreteInputs", "read discrete inputs")
+		return
+	}
+
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadHoldingRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadHoldingRegisters", policy, model.NewReadHoldingRegistersReq(address, count))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadHoldingRegistersRsp)
+	if !readOK {
+		err = illegalResponse("ReadHoldingRegisters", "read holding registers")
+		return
+	}
+
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadInputRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadInputRegisters", policy, model.NewReadInputRegistersReq(address, count))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadInputRegistersRsp)
+	if !readOK {
+		err = illegalResponse("ReadInputRegisters", "read input registers")
+		return
+	}
+
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) WriteSingleCoil(ctx context.Context, policy *common.RequestPolicy, address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "WriteSingleCoil", policy, model.NewWriteSingleCoilReq(address, data))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBWriteSingleCoilRsp)
+	if !readOK {
+		err = illegalResponse("WriteSingleCoil", "write single coil")
+		return
+	}
+
+	retAddr = readVal.Address()
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) WriteMultipleCoils(ctx context.Context, policy *common.RequestPolicy, address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "WriteMultipleCoils", policy, model.NewWriteMultipleCoilsReq(address, count, data))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBWriteMultipleCoilsRsp)
+	if !readOK {
+		err = illegalResponse("WriteMultipleCoils", "write multiple coils")
+		return
+	}
+
+	retAddr = readVal.Address()
+	retCount = readVal.Count()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) WriteSingleRegister(ctx context.Context, policy *common.RequestPolicy, address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "WriteSingleRegister", policy, model.NewWriteSingleRegisterReq(address, data))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBWriteSingleRegisterRsp)
+	if !readOK {
+		err = illegalResponse("WriteSingleRegister", "write single register")
+		return
+	}
+
+	retAddr = readVal.Address()
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) WriteMultipleRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "WriteMultipleRegisters", policy, model.NewWriteMultipleRegistersReq(address, count, data))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBWriteMultipleRegistersRsp)
+	if !readOK {
+		err = illegalResponse("WriteMultipleRegisters", "write multiple registers")
+		return
+	}
+
+	retAddr = readVal.Address()
+	retCount = readVal.Count()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadExceptionStatus(ctx context.Context, policy *common.RequestPolicy) (retStatus, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadExceptionStatus", policy, model.NewReadExceptionStatusReq())
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadExceptionStatusRsp)
+	if !readOK {
+		err = illegalResponse("ReadExceptionStatus", "read exception status")
+		return
+	}
+
+	exCode = readVal.ExceptionCode()
+	retStatus = readVal.Status()
+	return
+}
+
+func (s *mbMaster) Diagnostics(ctx context.Context, policy *common.RequestPolicy, subFuncCode uint16, data []byte) (retSubFuncCode uint16, retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "Diagnostics", policy, model.NewDiagnosticsReq(subFuncCode, data))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBDiagnosticsRsp)
+	if !readOK {
+		err = illegalResponse("Diagnostics", "diagnostics")
+		return
+	}
+
+	retSubFuncCode = readVal.SubFunctionCode()
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) GetCommEventCounter(ctx context.Context, policy *common.RequestPolicy) (status uint16, eventCount uint16, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "GetCommEventCounter", policy, model.NewGetCommEventCounterReq())
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBGetCommEventCounterRsp)
+	if !readOK {
+		err = illegalResponse("GetCommEventCounter", "get comm event counter")
+		return
+	}
+
+	status = readVal.CommStatus()
+	eventCount = readVal.EventCount()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) GetCommEventLog(ctx context.Context, policy *common.RequestPolicy) (status uint16, eventCount, messageCount uint16, events []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "GetCommEventLog", policy, model.NewGetCommEventLogReq())
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBGetCommEventLogRsp)
+	if !readOK {
+		err = illegalResponse("GetCommEventLog", "get comm event log")
+		return
+	}
+
+	status = readVal.CommStatus()
+	eventCount = readVal.EventCount()
+	messageCount = readVal.MessageCount()
+	events = readVal.CommonEvents()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReportSlaveID(ctx context.Context, policy *common.RequestPolicy) (ret []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReportSlaveID", policy, model.NewReportSlaveIDReq())
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReportSlaveIDRsp)
+	if !readOK {
+		err = illegalResponse("ReportSlaveID", "report slave id")
+		return
+	}
+
+	exCode = readVal.ExceptionCode()
+	ret = readVal.SlaveIDInfo()
+	return
+}
+
+func (s *mbMaster) ReadFileRecord(ctx context.Context, policy *common.RequestPolicy, items []*common.ReadItem) (ret [][]byte, exCode byte, err error) {
+	reqItems := []*model.ReadRequestItem{}
+	for _, val := range items {
+		reqItems = append(reqItems, model.NewReadRequestItem(val.FileNumber, val.RecordNumber, val.RecordLength))
+	}
+
+	recvVal, recvErr := s.transact(ctx, "ReadFileRecord", policy, model.NewReadFileRecordReq(reqItems))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadFileRecordRsp)
+	if !readOK {
+		err = illegalResponse("ReadFileRecord", "read file record")
+		return
+	}
+
+	exCode = readVal.ExceptionCode()
+	for _, val := range readVal.Items() {
+		ret = append(ret, val.Data())
+	}
+	return
+}
+
+func (s *mbMaster) WriteFileRecord(ctx context.Context, policy *common.RequestPolicy, items []*common.WriteItem) (exCode byte, err error) {
+	reqItems := []*model.WriteItem{}
+	for _, val := range items {
+		byteVal, byteErr := hex.DecodeString(val.RecordData)
+		if byteErr != nil {
+			err = byteErr
+			return
+		}
+
+		reqItems = append(reqItems, model.NewWriteItem(val.FileNumber, val.RecordNumber, byteVal))
+	}
+
+	recvVal, recvErr := s.transact(ctx, "WriteFileRecord", policy, model.NewWriteFileRecordReq(reqItems))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBWriteFileRecordRsp)
+	if !readOK {
+		err = illegalResponse("WriteFileRecord", "write file record")
+		return
+	}
+
+	exCode = readVal.ExceptionCode()
+	if exCode == model.SuccessCode && len(items) != len(readVal.Items()) {
+		err = fmt.Errorf("mismatch write file record item size")
+		log.Errorf("WriteFileRecord failed, error:%s", err.Error())
+		return
+	}
+	return
+}
+
+func (s *mbMaster) MaskWriteRegister(ctx context.Context, policy *common.RequestPolicy, address uint16, andBytes []byte, orBytes []byte) (retAddr uint16, retAnd []byte, retOr []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "MaskWriteRegister", policy, model.NewMaskWriteRegisterReq(address, andBytes, orBytes))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBMaskWriteRegisterRsp)
+	if !readOK {
+		err = illegalResponse("MaskWriteRegister", "mask write register")
+		return
+	}
+
+	retAddr = readVal.Address()
+	retAnd = readVal.AndMask()
+	retOr = readVal.OrMask()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadWriteMultipleRegisters(ctx context.Context, policy *common.RequestPolicy, readAddr, readCount uint16, writeAddr, writeCount uint16, writeData []byte) (retData []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadWriteMultipleRegisters", policy, model.NewReadWriteMultipleRegistersReq(readAddr, readCount, writeAddr, writeCount, writeData))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadWriteMultipleRegistersRsp)
+	if !readOK {
+		err = illegalResponse("ReadWriteMultipleRegisters", "read&write multiple registers")
+		return
+	}
+
+	retData = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
+
+func (s *mbMaster) ReadFIFOQueue(ctx context.Context, policy *common.RequestPolicy, address uint16) (retDataCount uint16, retDataVal []byte, exCode byte, err error) {
+	recvVal, recvErr := s.transact(ctx, "ReadFIFOQueue", policy, model.NewReadFIFOQueueReq(address))
+	if recvErr != nil {
+		err = recvErr
+		return
+	}
+
+	readVal, readOK := recvVal.(*model.MBReadFIFOQueueRsp)
+	if !readOK {
+		err = illegalResponse("ReadFIFOQueue", "read fifo queue")
+		return
+	}
+
+	retDataCount = readVal.DataCount()
+	retDataVal = readVal.Data()
+	exCode = readVal.ExceptionCode()
+	return
+}
@@ -0,0 +1,133 @@
+package biz
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/history"
+)
+
+const (
+	historyFlushInterval = time.Second
+	historyPurgeInterval = 10 * time.Minute
+)
+
+// EnableHistory 打开历史数据存储,需要在创建扫描组之前调用
+func (s *Master) EnableHistory(options history.Options) (err error) {
+	storePtr, storeErr := history.Open(options)
+	if storeErr != nil {
+		err = storeErr
+		return
+	}
+
+	s.historyPtr = storePtr
+	s.Timer(historyFlushInterval, 0, s.flushHistory)
+	s.Timer(historyPurgeInterval, 0, s.purgeHistory)
+	s.AsyncTask(s.purgeHistory)
+	return
+}
+
+func (s *Master) flushHistory() {
+	err := s.historyPtr.Flush()
+	if err != nil {
+		log.Errorf("flush history failed, error:%s", err.Error())
+	}
+}
+
+func (s *Master) purgeHistory() {
+	removed, err := s.historyPtr.Purge(time.Now())
+	if err != nil {
+		log.Errorf("purge history failed, error:%s", err.Error())
+		return
+	}
+	if removed > 0 {
+		log.Infof("purge history ok, segments:%d", removed)
+	}
+}
+
+/*
+recordHistory 记录每次扫描的取值,布尔值记录为0与1,读取失败时记录此前的取值
+写入失败时只在首次失败及恢复时记录日志
+*/
+func (s *Master) recordHistory(tagVal *common.TagValue) {
+	if s.historyPtr == nil {
+		return
+	}
+
+	sample := history.Sample{Time: tagVal.Timestamp, Value: math.NaN(), Good: tagVal.Quality == common.GoodQuality}
+	switch val := tagVal.Value.(type) {
+	case float64:
+		sample.Value = val
+	case bool:
+		sample.Value = 0
+		if val {
+			sample.Value = 1
+		}
+	}
+
+	err := s.historyPtr.Append(tagVal.Name, sample)
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+	if err != nil && !s.historyFailed {
+		log.Errorf("record history failed, name:%s, error:%s", tagVal.Name, err.Error())
+	}
+	if err == nil && s.historyFailed {
+		log.Infof("record history recovered, name:%s", tagVal.Name)
+	}
+	s.historyFailed = err != nil
+}
+
+// QueryTagHistory 线圈与离散输入的原始取值还原为布尔值
+func (s *Master) QueryTagHistory(name string, param *common.HistoryRequest) (ret []*common.HistoryPoint, err *cd.Result) {
+	if s.historyPtr == nil {
+		err = cd.NewError(cd.UnExpected, "history is disabled")
+		return
+	}
+
+	tagPtr, tagErr := s.QueryTag(name)
+	if tagErr != nil {
+		err = tagErr
+		return
+	}
+
+	samples, samplesErr := s.historyPtr.Query(name, param.From, param.To)
+	if samplesErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("query history failed, %s", samplesErr.Error()))
+		return
+	}
+
+	ret = []*common.HistoryPoint{}
+	if param.Agg != "" {
+		buckets, bucketsErr := history.Downsample(samples, param.From, param.Step, param.Agg)
+		if bucketsErr != nil {
+			err = cd.NewError(cd.IllegalParam, bucketsErr.Error())
+			return
+		}
+
+		for _, val := range buckets {
+			ret = append(ret, &common.HistoryPoint{Timestamp: val.Time, Value: val.Value, Count: val.Count})
+		}
+		return
+	}
+
+	isBool := tagPtr.Table == common.CoilTable || tagPtr.Table == common.DiscreteInputTable
+	for _, val := range samples {
+		pointPtr := &common.HistoryPoint{Timestamp: val.Time, Quality: common.GoodQuality}
+		if !val.Good {
+			pointPtr.Quality = common.BadQuality
+		}
+		if !math.IsNaN(val.Value) {
+			pointPtr.Value = val.Value
+			if isBool {
+				pointPtr.Value = val.Value != 0
+			}
+		}
+		ret = append(ret, pointPtr)
+	}
+	return
+}
@@ -0,0 +1,308 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// mbLink 与网关或者串口之间的一条链路,同一链路上的多个从站共享连接
+type mbLink struct {
+	serverAddr string
+	signalGard pendingTable
+
+	transport     Transport
+	transportLock sync.RWMutex
+	newTransport  TransportFunc
+	framer        Framer
+	recvBuffer    []byte
+
+	// queue 限制同一链路上未完成的事务数
+	queue *requestQueue
+	// lateResponseWindow 请求没有收到应答时延迟释放事务槽,为0时立即释放
+	lateResponseWindow time.Duration
+	// lastSlaveID 最近一次发送请求的从站,用于统计无法解析的应答
+	lastSlaveID atomic.Value
+
+	// connLock 保证同一时刻只有一个连接动作
+	connLock sync.Mutex
+	// refLock 保护引用计数,与connLock分开,连接过程中不阻塞引用计数的操作
+	refLock  sync.Mutex
+	refCount int
+}
+
+func newLink(framer Framer, newTransport TransportFunc, maxTransactions int) *mbLink {
+	return &mbLink{
+		newTransport: newTransport,
+		framer:       framer,
+		queue:        newRequestQueue(maxTransactions),
+	}
+}
+
+func (s *mbLink) getTransport() Transport {
+	s.transportLock.RLock()
+	defer s.transportLock.RUnlock()
+
+	return s.transport
+}
+
+func (s *mbLink) setTransport(transport Transport) {
+	s.transportLock.Lock()
+	defer s.transportLock.Unlock()
+
+	s.transport = transport
+}
+
+func (s *mbLink) reset() {
+	s.framer.Reset()
+	s.recvBuffer = nil
+	s.signalGard.Reset()
+	s.setTransport(nil)
+}
+
+func (s *mbLink) connect(serverAddr string) (ret Transport, err error) {
+	err = s.signalGard.Put(connectID)
+	if err != nil {
+		return
+	}
+
+	transport := s.newTransport(s)
+	err = transport.Connect(serverAddr)
+	if err != nil {
+		s.signalGard.Clean(connectID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeOut*time.Second)
+	defer cancel()
+
+	addrVal, addrErr := s.signalGard.Wait(ctx, connectID)
+	if addrErr != nil {
+		transport.Close()
+		err = addrErr
+		return
+	}
+
+	log.Infof("connect slave %s ok", addrVal)
+	ret = transport
+	return
+}
+
+// Start 链路已经连接时直接复用
+func (s *mbLink) Start(serverAddr string) (err error) {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	if s.IsConnect() {
+		return
+	}
+
+	transport, connErr := s.connect(serverAddr)
+	if connErr != nil {
+		err = connErr
+		log.Errorf("start master %s failed, error:%s", serverAddr, connErr.Error())
+		return
+	}
+
+	s.setTransport(transport)
+	s.serverAddr = serverAddr
+	return
+}
+
+func (s *mbLink) Stop() {
+	transport := s.getTransport()
+	if transport == nil {
+		return
+	}
+
+	transport.Close()
+}
+
+func (s *mbLink) IsConnect() bool {
+	return s.getTransport() != nil
+}
+
+func (s *mbLink) ReConnect() (err error) {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	if s.IsConnect() {
+		return
+	}
+	if s.refs() <= 0 {
+		err = fmt.Errorf("link %s already closed", s.serverAddr)
+		return
+	}
+
+	transport, connErr := s.connect(s.serverAddr)
+	if connErr != nil {
+		err = connErr
+		log.Errorf("reconnect master %s failed, error:%s", s.serverAddr, connErr.Error())
+		return
+	}
+	// 重连过程中链路已经释放
+	if s.refs() <= 0 {
+		transport.Close()
+		err = fmt.Errorf("link %s already closed", s.serverAddr)
+		return
+	}
+
+	s.setTransport(transport)
+	return
+}
+
+// acquire 增加链路引用计数
+func (s *mbLink) acquire() {
+	s.refLock.Lock()
+	defer s.refLock.Unlock()
+
+	s.refCount++
+}
+
+// release 减少链路引用计数,返回剩余引用数,无引用时关闭链路
+func (s *mbLink) release() int {
+	s.refLock.Lock()
+	s.refCount--
+	refCount := s.refCount
+	s.refLock.Unlock()
+
+	if refCount <= 0 {
+		s.Stop()
+	}
+
+	return refCount
+}
+
+func (s *mbLink) refs() int {
+	s.refLock.Lock()
+	defer s.refLock.Unlock()
+
+	return s.refCount
+}
+
+func (s *mbLink) OnConnect(ep tcp.Endpoint) {
+	err := s.signalGard.Trigger(connectID, ep.RemoteAddr().String())
+	if err != nil {
+		log.Errorf("onConnect triggerSignal failed, error:%s", err.Error())
+		return
+	}
+}
+
+func (s *mbLink) OnDisConnect(ep tcp.Endpoint) {
+	log.Warnf("onDisConnect from %s", ep.RemoteAddr().String())
+	s.reset()
+}
+
+// OnRecvData 一次读取可能只包含半帧或者多帧,先缓存再按帧切分
+func (s *mbLink) OnRecvData(ep tcp.Endpoint, data []byte) {
+	s.recvBuffer = append(s.recvBuffer, data...)
+	for len(s.recvBuffer) > 0 {
+		frameLen, frameErr := s.framer.FrameLen(s.recvBuffer)
+		if frameErr != nil {
+			log.Errorf("illegal frame, drop %d bytes, remoteAddr:%s, error:%s", len(s.recvBuffer), ep.RemoteAddr().String(), frameErr.Error())
+			s.recvBuffer = nil
+			return
+		}
+		if frameLen == 0 {
+			return
+		}
+
+		frameVal := s.recvBuffer[:frameLen]
+		s.recvBuffer = s.recvBuffer[frameLen:]
+		s.onRecvFrame(ep, frameVal)
+	}
+
+	s.recvBuffer = nil
+}
+
+func (s *mbLink) onRecvFrame(ep tcp.Endpoint, frame []byte) {
+	signalID, protocolVal, protocolErr := s.framer.Decode(frame)
+	if protocolErr != nil {
+		slaveID, _ := s.lastSlaveID.Load().(string)
+		observeChecksumError(slaveID, protocolErr)
+		log.Errorf("decode mbprotocol failed, remoteAddr:%s, error:%s", ep.RemoteAddr().String(), protocolErr.Error())
+		return
+	}
+
+	err := s.signalGard.Trigger(signalID, protocolVal)
+	if err != nil {
+		log.Errorf("onRecvData triggerSignal failed, remoteAddr:%s, error:%s", ep.RemoteAddr().String(), err.Error())
+	}
+}
+
+// sendRequest 未完成事务数达到上限时排队等待,排队时间计入ctx的超时
+func (s *mbLink) sendRequest(ctx context.Context, name, slaveID string, unitID byte, protocol model.MBProtocol) (ret model.MBProtocol, err error) {
+	err = s.queue.Acquire(ctx, requestPriority(protocol.FuncCode()))
+	if err != nil {
+		err = contextError(ctx)
+		log.Errorf("%s failed, wait request queue error:%s", name, err.Error())
+		return
+	}
+	noResponse := false
+	defer func() {
+		s.releaseQueue(noResponse)
+	}()
+
+	transport := s.getTransport()
+	if transport == nil {
+		err = fmt.Errorf("%s failed, slave not connected", name)
+		log.Errorf(err.Error())
+		return
+	}
+
+	byteVal, signalID, encodeErr := s.framer.Encode(unitID, protocol)
+	if encodeErr != nil {
+		err = fmt.Errorf("%s,encode mbprotocol failed, error:%s", name, encodeErr.Error())
+		log.Errorf(err.Error())
+		return
+	}
+
+	err = s.signalGard.Put(signalID)
+	if err != nil {
+		log.Errorf("%s,signalGard.Put failed, error:%s", name, err.Error())
+		return
+	}
+	s.lastSlaveID.Store(slaveID)
+	err = transport.SendData(byteVal)
+	if err != nil {
+		s.signalGard.Clean(signalID)
+		log.Errorf("%s,transport.SendData failed, error:%s", name, err.Error())
+		return
+	}
+
+	recvVal, recvErr := s.signalGard.Wait(ctx, signalID)
+	if recvErr != nil {
+		noResponse = true
+		err = recvErr
+		log.Errorf("%s failed, error:%s", name, err.Error())
+		return
+	}
+
+	protocolVal, protocolOK := recvVal.(model.MBProtocol)
+	if !protocolOK || protocolVal == nil {
+		err = fmt.Errorf("recv illegal data")
+		log.Errorf("%s failed, error:%s", name, err.Error())
+		return
+	}
+
+	ret = protocolVal
+	return
+}
+
+// releaseQueue 已发出的请求没有收到应答时,在lateResponseWindow之后才允许发送下一个请求
+func (s *mbLink) releaseQueue(noResponse bool) {
+	if noResponse && s.lateResponseWindow > 0 {
+		time.AfterFunc(s.lateResponseWindow, s.queue.Release)
+		return
+	}
+
+	s.queue.Release()
+}
@@ -0,0 +1,199 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// fakeTransport 记录发出的帧,应答由测试通过OnRecvData注入
+type fakeTransport struct {
+	observer tcp.Observer
+	sent     chan []byte
+}
+
+func (s *fakeTransport) Connect(serverAddr string) error {
+	s.observer.OnConnect(s)
+	return nil
+}
+
+func (s *fakeTransport) Close() {
+}
+
+func (s *fakeTransport) SendData(data []byte) error {
+	s.sent <- append([]byte(nil), data...)
+	return nil
+}
+
+func (s *fakeTransport) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func (s *fakeTransport) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 502}
+}
+
+func startFakeLink(t *testing.T, linkPtr *mbLink) *fakeTransport {
+	t.Helper()
+	transport := &fakeTransport{sent: make(chan []byte, 16)}
+	linkPtr.newTransport = func(observer tcp.Observer) Transport {
+		transport.observer = observer
+		return transport
+	}
+	linkPtr.acquire()
+	if err := linkPtr.Start("fake"); err != nil {
+		t.Fatalf("Start failed, error:%s", err.Error())
+	}
+	return transport
+}
+
+func nextFrame(t *testing.T, transport *fakeTransport) []byte {
+	t.Helper()
+	select {
+	case val := <-transport.sent:
+		return val
+	case <-time.After(time.Second):
+		t.Fatalf("wait request frame timeout")
+	}
+	return nil
+}
+
+// rtuRegisterRsp 读保持寄存器的RTU应答
+func rtuRegisterRsp(address byte, value uint16) []byte {
+	return model.EncodeToRTUStream([]byte{address, model.ReadHoldingRegisters, 2, byte(value >> 8), byte(value)})
+}
+
+type linkResult struct {
+	rsp model.MBProtocol
+	err error
+}
+
+func asyncRequest(linkPtr *mbLink, ctx context.Context, address uint16) chan linkResult {
+	ret := make(chan linkResult, 1)
+	go func() {
+		rsp, err := linkPtr.sendRequest(ctx, "ReadHoldingRegisters", "mb001", 1, model.NewReadHoldingRegistersReq(address, 1))
+		ret <- linkResult{rsp: rsp, err: err}
+	}()
+	return ret
+}
+
+func registerValue(t *testing.T, result linkResult) uint16 {
+	t.Helper()
+	if result.err != nil {
+		t.Fatalf("sendRequest failed, error:%s", result.err.Error())
+	}
+	data := result.rsp.(*model.MBReadHoldingRegistersRsp).Data()
+	return uint16(data[0])<<8 | uint16(data[1])
+}
+
+func TestSerialLinkLateResponse(t *testing.T) {
+	linkPtr := newSerialLink(&rtuFramer{}, nil)
+	linkPtr.lateResponseWindow = 50 * time.Millisecond
+	transport := startFakeLink(t, linkPtr)
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer timeoutCancel()
+	firstCh := asyncRequest(linkPtr, timeoutCtx, 0)
+	nextFrame(t, transport)
+	if result := <-firstCh; !errors.Is(result.err, ErrTimeout) {
+		t.Fatalf("first request should timeout, error:%v", result.err)
+	}
+
+	// 超时后迟到的应答与下一个请求的地址和功能码相同,不能被下一个请求误收
+	secondCh := asyncRequest(linkPtr, context.Background(), 0)
+	time.Sleep(10 * time.Millisecond)
+	linkPtr.OnRecvData(transport, rtuRegisterRsp(1, 0x1111))
+	nextFrame(t, transport)
+	linkPtr.OnRecvData(transport, rtuRegisterRsp(1, 0x2222))
+	if val := registerValue(t, <-secondCh); val != 0x2222 {
+		t.Errorf("second request matched the late response, value:%#x", val)
+	}
+}
+
+func TestSerialLinkCapacity(t *testing.T) {
+	linkPtr := newSerialLink(&rtuFramer{}, nil)
+	transport := startFakeLink(t, linkPtr)
+
+	firstCh := asyncRequest(linkPtr, context.Background(), 0)
+	nextFrame(t, transport)
+	secondCh := asyncRequest(linkPtr, context.Background(), 0)
+
+	// 串行链路同一时刻只有一个事务在途
+	select {
+	case <-transport.sent:
+		t.Fatalf("serial link should not send before the first response")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	linkPtr.OnRecvData(transport, rtuRegisterRsp(1, 1))
+	if val := registerValue(t, <-firstCh); val != 1 {
+		t.Errorf("illegal first response, value:%d", val)
+	}
+	nextFrame(t, transport)
+	linkPtr.OnRecvData(transport, rtuRegisterRsp(1, 2))
+	if val := registerValue(t, <-secondCh); val != 2 {
+		t.Errorf("illegal second response, value:%d", val)
+	}
+}
+
+// tcpRegisterRsp 按照请求帧的事务号构造读保持寄存器应答,取值为请求地址加100
+func tcpRegisterRsp(t *testing.T, frame []byte) (transaction uint16, rsp []byte) {
+	t.Helper()
+	header, reqVal, err := model.DecodeMBTcpProtocol(bytes.NewBuffer(frame), model.RequestAction)
+	if err != model.SuccessCode {
+		t.Fatalf("decode request failed, error:%d", err)
+	}
+
+	value := reqVal.(*model.MBReadHoldingRegistersReq).Address() + 100
+	rspVal := model.NewReadHoldingRegistersRsp([]byte{byte(value >> 8), byte(value)})
+	buffVal := bytes.NewBuffer(nil)
+	model.EncodeMBTcpProtocol(model.NewTcpHeader(header.Transaction(), rspVal.CalcLen(), header.UnitID()), rspVal, buffVal)
+	return header.Transaction(), buffVal.Bytes()
+}
+
+func TestTCPLinkPipelined(t *testing.T) {
+	linkPtr := newTCPLink(3)
+	transport := startFakeLink(t, linkPtr)
+
+	resultChs := []chan linkResult{}
+	for idx := 0; idx < 3; idx++ {
+		resultChs = append(resultChs, asyncRequest(linkPtr, context.Background(), uint16(idx)))
+	}
+
+	// 三个请求同时在途,事务号互不相同
+	type pendingRsp struct {
+		transaction uint16
+		rsp         []byte
+	}
+	items := []pendingRsp{}
+	for idx := 0; idx < 3; idx++ {
+		transaction, rsp := tcpRegisterRsp(t, nextFrame(t, transport))
+		items = append(items, pendingRsp{transaction: transaction, rsp: rsp})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].transaction > items[j].transaction })
+	if items[0].transaction == items[1].transaction || items[1].transaction == items[2].transaction {
+		t.Fatalf("duplicate transaction, items:%v", items)
+	}
+
+	// 应答乱序且合并在一次读取中到达
+	coalesced := []byte{}
+	for _, val := range items {
+		coalesced = append(coalesced, val.rsp...)
+	}
+	linkPtr.OnRecvData(transport, coalesced[:5])
+	linkPtr.OnRecvData(transport, coalesced[5:])
+
+	for idx, val := range resultChs {
+		if value := registerValue(t, <-val); value != uint16(idx)+100 {
+			t.Errorf("response matched the wrong transaction, idx:%d, value:%d", idx, value)
+		}
+	}
+}
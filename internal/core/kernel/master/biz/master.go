@@ -1,7 +1,7 @@
 package biz
 
 import (
-	"github.com/muidea/magicEngine/tcp"
+	"context"
 	"github.com/muidea/quickModbus/pkg/common"
 )
 
@@ -13,26 +13,25 @@ type MBMaster interface {
 	Stop()
 	IsConnect() bool
 	ReConnect() (err error)
+	SetPolicy(policy common.RequestPolicy)
 	EndianType() byte
-	OnConnect(ep tcp.Endpoint)
-	OnDisConnect(ep tcp.Endpoint)
-	OnRecvData(ep tcp.Endpoint, data []byte)
-	ReadCoils(address, count uint16) (retData []byte, exCode byte, err error)
-	ReadDiscreteInputs(address, count uint16) (retData []byte, exCode byte, err error)
-	ReadHoldingRegisters(address, count uint16) (retData []byte, exCode byte, err error)
-	ReadInputRegisters(address, count uint16) (retData []byte, exCode byte, err error)
-	WriteSingleCoil(address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error)
-	WriteMultipleCoils(address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error)
-	WriteSingleRegister(address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error)
-	WriteMultipleRegisters(address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error)
-	ReadExceptionStatus() (retStatus, exCode byte, err error)
-	Diagnostics(subFuncCode uint16, data []byte) (retSubFuncCode uint16, retData []byte, exCode byte, err error)
-	GetCommEventCounter() (status uint16, eventCount uint16, exCode byte, err error)
-	GetCommEventLog() (status uint16, eventCount, messageCount uint16, events []byte, exCode byte, err error)
-	ReportSlaveID() (ret []byte, exCode byte, err error)
-	ReadFileRecord(items []*common.ReadItem) (ret [][]byte, exCode byte, err error)
-	WriteFileRecord(items []*common.WriteItem) (exCode byte, err error)
-	MaskWriteRegister(address uint16, andBytes []byte, orBytes []byte) (retAddr uint16, retAnd []byte, retOr []byte, exCode byte, err error)
-	ReadWriteMultipleRegisters(readAddr, readCount uint16, writeAddr, writeCount uint16, writeData []byte) (retData []byte, exCode byte, err error)
-	ReadFIFOQueue(address uint16) (retDataCount uint16, retDataVal []byte, exCode byte, err error)
+	Stats() Stats
+	ReadCoils(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error)
+	ReadDiscreteInputs(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error)
+	ReadHoldingRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error)
+	ReadInputRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16) (retData []byte, exCode byte, err error)
+	WriteSingleCoil(ctx context.Context, policy *common.RequestPolicy, address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error)
+	WriteMultipleCoils(ctx context.Context, policy *common.RequestPolicy, address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error)
+	WriteSingleRegister(ctx context.Context, policy *common.RequestPolicy, address uint16, data []byte) (retAddr uint16, retData []byte, exCode byte, err error)
+	WriteMultipleRegisters(ctx context.Context, policy *common.RequestPolicy, address, count uint16, data []byte) (retAddr, retCount uint16, exCode byte, err error)
+	ReadExceptionStatus(ctx context.Context, policy *common.RequestPolicy) (retStatus, exCode byte, err error)
+	Diagnostics(ctx context.Context, policy *common.RequestPolicy, subFuncCode uint16, data []byte) (retSubFuncCode uint16, retData []byte, exCode byte, err error)
+	GetCommEventCounter(ctx context.Context, policy *common.RequestPolicy) (status uint16, eventCount uint16, exCode byte, err error)
+	GetCommEventLog(ctx context.Context, policy *common.RequestPolicy) (status uint16, eventCount, messageCount uint16, events []byte, exCode byte, err error)
+	ReportSlaveID(ctx context.Context, policy *common.RequestPolicy) (ret []byte, exCode byte, err error)
+	ReadFileRecord(ctx context.Context, policy *common.RequestPolicy, items []*common.ReadItem) (ret [][]byte, exCode byte, err error)
+	WriteFileRecord(ctx context.Context, policy *common.RequestPolicy, items []*common.WriteItem) (exCode byte, err error)
+	MaskWriteRegister(ctx context.Context, policy *common.RequestPolicy, address uint16, andBytes []byte, orBytes []byte) (retAddr uint16, retAnd []byte, retOr []byte, exCode byte, err error)
+	ReadWriteMultipleRegisters(ctx context.Context, policy *common.RequestPolicy, readAddr, readCount uint16, writeAddr, writeCount uint16, writeData []byte) (retData []byte, exCode byte, err error)
+	ReadFIFOQueue(ctx context.Context, policy *common.RequestPolicy, address uint16) (retDataCount uint16, retDataVal []byte, exCode byte, err error)
 }
@@ -0,0 +1,85 @@
+package biz
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/metrics"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+var (
+	masterRequestCounter = metrics.Default.NewCounter("quickmodbus_master_requests_total",
+		"Modbus requests sent by the master, each retry is counted.", "slave", "function")
+	masterDurationHistogram = metrics.Default.NewHistogram("quickmodbus_master_request_duration_seconds",
+		"Time from queuing a master request to receiving its response.", nil, "slave", "function")
+	masterTimeoutCounter = metrics.Default.NewCounter("quickmodbus_master_timeouts_total",
+		"Master requests without a response before the timeout.", "slave", "function")
+	masterErrorCounter = metrics.Default.NewCounter("quickmodbus_master_request_errors_total",
+		"Master requests failed for reasons other than timeout, such as a closed link.", "slave", "function")
+	masterExceptionCounter = metrics.Default.NewCounter("quickmodbus_master_exceptions_total",
+		"Exception responses received by the master.", "slave", "function", "code")
+	masterChecksumCounter = metrics.Default.NewCounter("quickmodbus_master_checksum_errors_total",
+		"Response frames dropped by the master because of a CRC or LRC mismatch.", "slave", "check")
+	masterReconnectCounter = metrics.Default.NewCounter("quickmodbus_master_reconnects_total",
+		"Slave reconnect attempts by result.", "slave", "result")
+	slaveConnectedGauge = metrics.Default.NewGauge("quickmodbus_master_slave_connected",
+		"Whether the link of the slave is connected.", "slave")
+	connectedSlavesGauge = metrics.Default.NewGauge("quickmodbus_master_connected_slaves",
+		"Number of slaves with a connected link.")
+)
+
+func functionLabel(funcCode byte) string {
+	return fmt.Sprintf("0x%02X", funcCode)
+}
+
+// observeRequest 统计单次发送,超时与其它错误分开计数,异常应答按照异常码计数
+func observeRequest(slaveID string, funcCode byte, rsp interface{}, err error, elapsed time.Duration) {
+	function := functionLabel(funcCode)
+	masterRequestCounter.Inc(slaveID, function)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			masterTimeoutCounter.Inc(slaveID, function)
+		} else {
+			masterErrorCounter.Inc(slaveID, function)
+		}
+		return
+	}
+
+	masterDurationHistogram.Observe(elapsed.Seconds(), slaveID, function)
+	if exVal, exOK := rsp.(exceptionResponse); exOK && exVal.ExceptionCode() != 0 {
+		masterExceptionCounter.Inc(slaveID, function, fmt.Sprintf("%d", exVal.ExceptionCode()))
+	}
+}
+
+// observeChecksumError 校验错误的帧无法确定来源,串行链路同一时刻只有一个请求在途,计入最近一次发送请求的从站
+func observeChecksumError(slaveID string, err error) {
+	switch {
+	case errors.Is(err, model.ErrCRCCheck):
+		masterChecksumCounter.Inc(slaveID, "crc")
+	case errors.Is(err, model.ErrLRCCheck):
+		masterChecksumCounter.Inc(slaveID, "lrc")
+	}
+}
+
+func observeReconnect(slaveID string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "failed"
+	}
+
+	masterReconnectCounter.Inc(slaveID, result)
+}
+
+// deleteSlaveMetrics 从站断开后删除其全部指标,重新连接时从0开始计数
+func deleteSlaveMetrics(slaveID string) {
+	masterRequestCounter.DeleteMatch(slaveID)
+	masterDurationHistogram.DeleteMatch(slaveID)
+	masterTimeoutCounter.DeleteMatch(slaveID)
+	masterErrorCounter.DeleteMatch(slaveID)
+	masterExceptionCounter.DeleteMatch(slaveID)
+	masterChecksumCounter.DeleteMatch(slaveID)
+	masterReconnectCounter.DeleteMatch(slaveID)
+	slaveConnectedGauge.Delete(slaveID)
+}
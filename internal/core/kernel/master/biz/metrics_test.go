@@ -0,0 +1,71 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/metrics"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+func metricsText(t *testing.T) string {
+	t.Helper()
+	buffer := bytes.NewBuffer(nil)
+	if err := metrics.Default.WriteText(buffer); err != nil {
+		t.Fatalf("WriteText failed, error:%s", err.Error())
+	}
+	return buffer.String()
+}
+
+func TestChecksumMetrics(t *testing.T) {
+	linkPtr := newSerialLink(&rtuFramer{}, nil)
+	transport := startFakeLink(t, linkPtr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resultCh := make(chan error, 1)
+	go func() {
+		_, err := linkPtr.sendRequest(ctx, "ReadHoldingRegisters", "mbcrc", 1, model.NewReadHoldingRegistersReq(0, 1))
+		resultCh <- err
+	}()
+	nextFrame(t, transport)
+
+	// CRC错误的应答计入发送请求的从站,而不是链路地址
+	rspVal := rtuRegisterRsp(1, 0x1234)
+	rspVal[len(rspVal)-1] ^= 0xFF
+	linkPtr.OnRecvData(transport, rspVal)
+	<-resultCh
+
+	if text := metricsText(t); !strings.Contains(text, `quickmodbus_master_checksum_errors_total{slave="mbcrc",check="crc"} 1`) {
+		t.Errorf("checksum error should be labelled by slaveID, metrics:\n%s", text)
+	}
+
+	observeRequest("mbcrc", model.ReadHoldingRegisters, nil, nil, time.Millisecond)
+	slaveConnectedGauge.Set(1, "mbcrc")
+	deleteSlaveMetrics("mbcrc")
+	if text := metricsText(t); strings.Contains(text, `slave="mbcrc"`) {
+		t.Errorf("slave metrics should be deleted, metrics:\n%s", text)
+	}
+}
+
+func TestSupervisorMetricsAfterClose(t *testing.T) {
+	linkPtr := newTCPLink(1)
+	startFakeLink(t, linkPtr)
+	infoPtr := newSlaveInfo("mbclose", "fake", 1, 0, 0, common.ReadCoalesce{}, newMaster(linkPtr, "mbclose", 1, 0))
+
+	if !infoPtr.updateConnected() || !strings.Contains(metricsText(t), `quickmodbus_master_slave_connected{slave="mbclose"} 1`) {
+		t.Fatalf("updateConnected should set the gauge")
+	}
+
+	// 断开之后监控循环不能重新创建已删除的指标
+	infoPtr.close()
+	infoPtr.updateConnected()
+	infoPtr.endReconnect(nil)
+	if text := metricsText(t); strings.Contains(text, `slave="mbclose"`) {
+		t.Errorf("closed slave metrics should not be recreated, metrics:\n%s", text)
+	}
+}
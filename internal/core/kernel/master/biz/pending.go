@@ -0,0 +1,113 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTimeout 单次请求在超时时间内没有收到应答
+var ErrTimeout = errors.New("wait response timeout")
+
+// ErrRequestCanceled 调用方取消请求或者调用方的截止时间已到,同时包装ctx.Err()
+var ErrRequestCanceled = errors.New("request canceled")
+
+// pendingTable 等待应答的请求表,与signal.Gard类似但可通过ctx取消
+type pendingTable struct {
+	lock  sync.Mutex
+	items map[int]chan interface{}
+}
+
+func (s *pendingTable) Put(id int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.items == nil {
+		s.items = map[int]chan interface{}{}
+	}
+	if _, ok := s.items[id]; ok {
+		return fmt.Errorf("duplicate signal %d", id)
+	}
+
+	s.items[id] = make(chan interface{}, 1)
+	return nil
+}
+
+func (s *pendingTable) fetch(id int, remove bool) (chan interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ch, ok := s.items[id]
+	if ok && remove {
+		delete(s.items, id)
+	}
+	return ch, ok
+}
+
+func (s *pendingTable) Clean(id int) {
+	s.fetch(id, true)
+}
+
+// Wait 等待应答直到ctx结束,超时返回ErrTimeout
+func (s *pendingTable) Wait(ctx context.Context, id int) (ret interface{}, err error) {
+	ch, ok := s.fetch(id, false)
+	if !ok {
+		err = fmt.Errorf("can't find signal %d", id)
+		return
+	}
+	defer s.Clean(id)
+
+	select {
+	case val, ok := <-ch:
+		if !ok {
+			err = fmt.Errorf("signal %d reset", id)
+			return
+		}
+		ret = val
+	case <-ctx.Done():
+		err = contextError(ctx)
+	}
+	return
+}
+
+// contextError 单次请求的ctx超时返回ErrTimeout,调用方的ctx结束由transact区分
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	return canceledError(ctx)
+}
+
+func canceledError(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrRequestCanceled, ctx.Err())
+}
+
+func (s *pendingTable) Trigger(id int, val interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ch, ok := s.items[id]
+	if !ok {
+		return fmt.Errorf("can't find signal %d", id)
+	}
+
+	select {
+	case ch <- val:
+		return nil
+	default:
+		return fmt.Errorf("duplicate response for signal %d", id)
+	}
+}
+
+// Reset 链路断开时唤醒所有等待者
+func (s *pendingTable) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, ch := range s.items {
+		close(ch)
+		delete(s.items, id)
+	}
+}
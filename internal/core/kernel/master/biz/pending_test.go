@@ -0,0 +1,69 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func TestPendingTableWait(t *testing.T) {
+	table := pendingTable{}
+
+	_ = table.Put(1)
+	go func() {
+		_ = table.Trigger(1, "ok")
+	}()
+	val, err := table.Wait(context.Background(), 1)
+	if err != nil || val != "ok" {
+		t.Errorf("Wait failed, val:%v, error:%v", val, err)
+	}
+
+	_ = table.Put(2)
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer timeoutCancel()
+	_, err = table.Wait(timeoutCtx, 2)
+	if !errors.Is(err, ErrTimeout) || errors.Is(err, ErrRequestCanceled) {
+		t.Errorf("Wait should timeout, error:%v", err)
+	}
+
+	_ = table.Put(3)
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = table.Wait(cancelCtx, 3)
+	if !errors.Is(err, ErrRequestCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait should be canceled, error:%v", err)
+	}
+
+	if err = table.Trigger(3, "late"); err == nil {
+		t.Errorf("Trigger should fail after Wait returned")
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	deadlineCtx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	<-deadlineCtx.Done()
+
+	items := []struct {
+		err  error
+		code cd.ErrorCode
+	}{
+		{err: canceledError(deadlineCtx), code: common.RequestCanceled},
+		{err: ErrTimeout, code: common.RequestTimeout},
+		{err: errors.New("slave not connected"), code: cd.UnExpected},
+	}
+	for idx, val := range items {
+		result := requestError(val.err)
+		if result.ErrorCode != val.code {
+			t.Errorf("requestError failed, idx:%d, errorCode:%v", idx, result.ErrorCode)
+		}
+	}
+	if !errors.Is(canceledError(deadlineCtx), context.DeadlineExceeded) {
+		t.Errorf("canceledError should wrap ctx.Err()")
+	}
+}
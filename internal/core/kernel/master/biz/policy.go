@@ -0,0 +1,49 @@
+package biz
+
+import (
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// 重试间隔最多倍增到2^maxBackoffShift
+const maxBackoffShift = 6
+
+// requestOption 合并后的超时与重试参数
+type requestOption struct {
+	timeout      time.Duration
+	retries      int
+	retryBackoff time.Duration
+}
+
+func defaultRequestOption() requestOption {
+	return requestOption{
+		timeout:      defaultTimeOut * time.Second,
+		retryBackoff: defaultRetryBackoff,
+	}
+}
+
+// merge policy中未设置的参数沿用当前取值
+func (s requestOption) merge(policy *common.RequestPolicy) requestOption {
+	if policy == nil {
+		return s
+	}
+
+	if policy.Timeout > 0 {
+		s.timeout = time.Duration(policy.Timeout) * time.Millisecond
+	}
+	if policy.Retries != nil && *policy.Retries >= 0 {
+		s.retries = *policy.Retries
+	}
+	if policy.RetryBackoff > 0 {
+		s.retryBackoff = time.Duration(policy.RetryBackoff) * time.Millisecond
+	}
+	return s
+}
+
+// backoff 第attempt次重试前的等待时间
+func (s requestOption) backoff(attempt int) time.Duration {
+	return s.retryBackoff << min(attempt, maxBackoffShift)
+}
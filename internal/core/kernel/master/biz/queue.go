@@ -0,0 +1,125 @@
+package biz
+
+import (
+	"context"
+	"sync"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+const (
+	readPriority = iota
+	writePriority
+)
+
+// requestPriority 写请求优先于轮询读请求
+func requestPriority(funcCode byte) int {
+	switch funcCode {
+	case model.WriteSingleCoil,
+		model.WriteSingleRegister,
+		model.WriteMultipleCoils,
+		model.WriteMultipleRegisters,
+		model.WriteFileRecord,
+		model.MaskWriteRegister,
+		model.ReadWriteMultipleRegisters:
+		return writePriority
+	}
+
+	return readPriority
+}
+
+// queueTicket 排队中的请求,ready关闭时表示已经获得事务槽
+type queueTicket struct {
+	ready chan struct{}
+}
+
+/*
+requestQueue 链路上的请求队列,同一时刻最多capacity个事务在途
+同一优先级内按照先进先出的顺序调度,写请求优先于读请求
+串行链路的capacity固定为1,保证应答不会错配
+*/
+type requestQueue struct {
+	lock     sync.Mutex
+	capacity int
+	active   int
+	waiting  [writePriority + 1][]*queueTicket
+}
+
+func newRequestQueue(capacity int) *requestQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &requestQueue{capacity: capacity}
+}
+
+// Acquire 获取事务槽,ctx结束时放弃排队并返回ctx.Err()
+func (s *requestQueue) Acquire(ctx context.Context, priority int) error {
+	s.lock.Lock()
+	if s.active < s.capacity && s.waitCount() == 0 {
+		s.active++
+		s.lock.Unlock()
+		return nil
+	}
+
+	ticket := &queueTicket{ready: make(chan struct{})}
+	s.waiting[priority] = append(s.waiting[priority], ticket)
+	s.lock.Unlock()
+
+	select {
+	case <-ticket.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.remove(priority, ticket) {
+		return ctx.Err()
+	}
+
+	// 取消与调度同时发生,已经分到的事务槽转交给下一个请求
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+// Release 释放事务槽,优先交给排队中的写请求
+func (s *requestQueue) Release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.releaseLocked()
+}
+
+func (s *requestQueue) releaseLocked() {
+	for priority := writePriority; priority >= readPriority; priority-- {
+		if len(s.waiting[priority]) == 0 {
+			continue
+		}
+
+		ticket := s.waiting[priority][0]
+		s.waiting[priority] = s.waiting[priority][1:]
+		close(ticket.ready)
+		return
+	}
+
+	s.active--
+}
+
+func (s *requestQueue) remove(priority int, ticket *queueTicket) bool {
+	for idx, val := range s.waiting[priority] {
+		if val == ticket {
+			s.waiting[priority] = append(s.waiting[priority][:idx], s.waiting[priority][idx+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *requestQueue) waitCount() (ret int) {
+	for _, val := range s.waiting {
+		ret += len(val)
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// waitFor 等待cond成立,超时后测试失败
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait %s timeout", desc)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *requestQueue) pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.waitCount()
+}
+
+func TestRequestQueueOrder(t *testing.T) {
+	queue := newRequestQueue(1)
+	_ = queue.Acquire(context.Background(), readPriority)
+
+	orderLock := sync.Mutex{}
+	order := []string{}
+	wg := sync.WaitGroup{}
+	items := []struct {
+		name     string
+		priority int
+	}{
+		{"read1", requestPriority(model.ReadHoldingRegisters)},
+		{"read2", requestPriority(model.ReadCoils)},
+		{"write1", requestPriority(model.WriteSingleRegister)},
+		{"read3", requestPriority(model.ReadInputRegisters)},
+		{"write2", requestPriority(model.WriteMultipleCoils)},
+	}
+	for idx, val := range items {
+		wg.Add(1)
+		go func(name string, priority int) {
+			defer wg.Done()
+			if err := queue.Acquire(context.Background(), priority); err != nil {
+				t.Errorf("Acquire failed, name:%s, error:%s", name, err.Error())
+				return
+			}
+
+			orderLock.Lock()
+			order = append(order, name)
+			orderLock.Unlock()
+			queue.Release()
+		}(val.name, val.priority)
+		waitFor(t, val.name, func() bool { return queue.pending() == idx+1 })
+	}
+
+	queue.Release()
+	wg.Wait()
+
+	expect := []string{"write1", "write2", "read1", "read2", "read3"}
+	for idx, val := range expect {
+		if order[idx] != val {
+			t.Fatalf("illegal request order, order:%v", order)
+		}
+	}
+	if queue.active != 0 {
+		t.Errorf("illegal active count:%d", queue.active)
+	}
+}
+
+func TestRequestQueueCancel(t *testing.T) {
+	queue := newRequestQueue(1)
+	_ = queue.Acquire(context.Background(), readPriority)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- queue.Acquire(ctx, writePriority)
+	}()
+	waitFor(t, "enqueue", func() bool { return queue.pending() == 1 })
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire should be canceled, error:%v", err)
+	}
+	if queue.pending() != 0 {
+		t.Errorf("canceled request should leave the queue, pending:%d", queue.pending())
+	}
+
+	// 取消的请求不占用事务槽
+	queue.Release()
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer timeoutCancel()
+	if err := queue.Acquire(timeoutCtx, readPriority); err != nil {
+		t.Errorf("Acquire after cancel failed, error:%s", err.Error())
+	}
+}
+
+func TestRequestQueueCapacity(t *testing.T) {
+	if newRequestQueue(0).capacity != 1 {
+		t.Errorf("capacity should be at least 1")
+	}
+
+	queue := newRequestQueue(2)
+	_ = queue.Acquire(context.Background(), readPriority)
+	_ = queue.Acquire(context.Background(), readPriority)
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer timeoutCancel()
+	if err := queue.Acquire(timeoutCtx, writePriority); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire should wait when queue is full, error:%v", err)
+	}
+
+	queue.Release()
+	if err := queue.Acquire(context.Background(), readPriority); err != nil {
+		t.Errorf("Acquire after release failed, error:%s", err.Error())
+	}
+}
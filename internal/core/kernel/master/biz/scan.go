@@ -0,0 +1,282 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// scanTick 扫描调度的时间粒度,扫描组的实际周期误差不超过该值
+const scanTick = 50 * time.Millisecond
+
+// scanGroup running表示本轮扫描尚未结束,期间到期的扫描被跳过
+type scanGroup struct {
+	common.ScanGroup
+
+	nextScan time.Time
+	running  bool
+}
+
+// SaveScanGroup 新增或者修改扫描组,修改周期后立即按照新周期调度
+func (s *Master) SaveScanGroup(group *common.ScanGroup) (ret *common.ScanGroup, err *cd.Result) {
+	groupVal := *group
+	groupErr := groupVal.Validate()
+	if groupErr != nil {
+		log.Errorf("saveScanGroup failed, error:%s", groupErr.Error())
+		err = cd.NewError(cd.IllegalParam, groupErr.Error())
+		return
+	}
+
+	s.scanLock.Lock()
+	defer s.scanLock.Unlock()
+
+	groupPtr, ok := s.scanGroupMap[groupVal.Name]
+	if !ok {
+		s.scanGroupMap[groupVal.Name] = &scanGroup{ScanGroup: groupVal}
+		ret = &groupVal
+		return
+	}
+
+	if groupPtr.Interval != groupVal.Interval {
+		groupPtr.nextScan = time.Time{}
+	}
+	groupPtr.ScanGroup = groupVal
+	ret = &groupVal
+	return
+}
+
+// DeleteScanGroup 删除扫描组,组内Tag恢复为直接读取从站
+func (s *Master) DeleteScanGroup(name string) (err *cd.Result) {
+	s.scanLock.Lock()
+	_, ok := s.scanGroupMap[name]
+	delete(s.scanGroupMap, name)
+	s.scanLock.Unlock()
+	if !ok {
+		errMsg := fmt.Sprintf("no exist scan group %s", name)
+		log.Errorf("deleteScanGroup failed, error:%s", errMsg)
+		err = cd.NewError(cd.UnExpected, errMsg)
+		return
+	}
+
+	for _, val := range s.scanGroupTags(name) {
+		s.removeTagValue(val.Name)
+	}
+	return
+}
+
+func (s *Master) ListScanGroup() (ret []*common.ScanGroup) {
+	s.scanLock.Lock()
+	defer s.scanLock.Unlock()
+
+	ret = []*common.ScanGroup{}
+	for _, val := range s.scanGroupMap {
+		groupVal := val.ScanGroup
+		ret = append(ret, &groupVal)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return
+}
+
+func (s *Master) existScanGroup(name string) bool {
+	s.scanLock.Lock()
+	defer s.scanLock.Unlock()
+
+	_, ok := s.scanGroupMap[name]
+	return ok
+}
+
+// dispatchScan 由定时任务驱动,到期的扫描组在后台任务中执行
+func (s *Master) dispatchScan() {
+	now := time.Now()
+	dueGroups := []*scanGroup{}
+
+	s.scanLock.Lock()
+	for _, val := range s.scanGroupMap {
+		if val.running || now.Before(val.nextScan) {
+			continue
+		}
+
+		interval := time.Duration(val.Interval) * time.Millisecond
+		val.nextScan = val.nextScan.Add(interval)
+		if val.nextScan.Before(now) {
+			val.nextScan = now.Add(interval)
+		}
+		val.running = true
+		dueGroups = append(dueGroups, val)
+	}
+	s.scanLock.Unlock()
+
+	for _, val := range dueGroups {
+		groupPtr := val
+		s.AsyncTask(func() {
+			s.scanTags(groupPtr.Name)
+
+			s.scanLock.Lock()
+			groupPtr.running = false
+			s.scanLock.Unlock()
+		})
+	}
+}
+
+func (s *Master) scanGroupTags(name string) (ret []*common.Tag) {
+	s.tagLock.RLock()
+	defer s.tagLock.RUnlock()
+
+	for _, val := range s.tagMap {
+		if val.ScanGroup != name {
+			continue
+		}
+
+		tagVal := *val
+		ret = append(ret, &tagVal)
+	}
+	return
+}
+
+// scanTags 按照从站的合并参数合并读取扫描组内的Tag,从站离线时不发起请求,由后台重连恢复
+func (s *Master) scanTags(name string) {
+	for _, blockPtr := range common.PlanReadBlocks(s.scanGroupTags(name), s.slaveCoalesce) {
+		if !s.slaveConnected(blockPtr.SlaveID) {
+			offlineErr := cd.NewError(cd.UnExpected, fmt.Sprintf("slave %s is offline", blockPtr.SlaveID))
+			for _, tagPtr := range blockPtr.Tags {
+				s.updateTagValue(tagPtr, nil, 0, offlineErr)
+			}
+			continue
+		}
+
+		values, exCode, err := s.readBlock(context.Background(), nil, blockPtr)
+		// 合并的地址间隙可能超出从站的地址范围,从站返回异常或者取值个数不足时改为逐个读取
+		if err != nil && (exCode != model.SuccessCode || err.Reason == illegalValueCount) && len(blockPtr.Tags) > 1 {
+			for _, tagPtr := range blockPtr.Tags {
+				itemVal, itemExCode, itemErr := s.readTagValue(context.Background(), nil, tagPtr)
+				s.updateTagValue(tagPtr, itemVal, itemExCode, itemErr)
+			}
+			continue
+		}
+
+		for idx, tagPtr := range blockPtr.Tags {
+			var itemVal interface{}
+			if err == nil {
+				itemVal = values[idx]
+			}
+			s.updateTagValue(tagPtr, itemVal, exCode, err)
+		}
+	}
+}
+
+// slaveCoalesce 从站的合并读取参数,从站不存在时使用缺省参数
+func (s *Master) slaveCoalesce(slaveID string) common.ReadCoalesce {
+	vVal := s.slaveInfoCache.Fetch(slaveID)
+	if vVal == nil {
+		return common.ReadCoalesce{}
+	}
+
+	return vVal.(*slaveInfo).coalesce
+}
+
+func (s *Master) slaveConnected(slaveID string) bool {
+	vVal := s.slaveInfoCache.Fetch(slaveID)
+	if vVal == nil {
+		return false
+	}
+
+	return vVal.(*slaveInfo).master.IsConnect()
+}
+
+// updateTagValue 记录扫描结果并推送给订阅者,历史数据记录每次扫描的取值,不经过死区过滤
+func (s *Master) updateTagValue(tagPtr *common.Tag, itemVal interface{}, exCode byte, err *cd.Result) {
+	tagVal := s.storeTagValue(tagPtr, itemVal, exCode, err)
+	if tagVal != nil {
+		s.publishValue(tagPtr, tagVal)
+		s.reportChange(tagPtr, tagVal)
+		s.recordHistory(tagVal)
+	}
+}
+
+// storeTagValue 读取失败时保留此前的取值,扫描期间Tag被删除时不再记录并返回nil
+func (s *Master) storeTagValue(tagPtr *common.Tag, itemVal interface{}, exCode byte, err *cd.Result) *common.TagValue {
+	tagVal := &common.TagValue{
+		Name:      tagPtr.Name,
+		Value:     itemVal,
+		Unit:      tagPtr.Unit,
+		Quality:   common.GoodQuality,
+		Timestamp: time.Now(),
+	}
+
+	s.tagLock.RLock()
+	defer s.tagLock.RUnlock()
+	if _, ok := s.tagMap[tagPtr.Name]; !ok {
+		return nil
+	}
+
+	s.valueLock.Lock()
+	defer s.valueLock.Unlock()
+
+	if err != nil {
+		tagVal.Quality = common.BadQuality
+		tagVal.ExceptionCode = exCode
+		tagVal.Error = err.Reason
+		if preVal, ok := s.valueCache[tagPtr.Name]; ok {
+			tagVal.Value = preVal.Value
+		}
+	}
+
+	s.valueCache[tagPtr.Name] = tagVal
+	ret := *tagVal
+	return &ret
+}
+
+// cachedTagValue Tag不属于扫描组或者尚未扫描时返回nil
+func (s *Master) cachedTagValue(tagPtr *common.Tag) *common.TagValue {
+	if tagPtr.ScanGroup == "" || !s.existScanGroup(tagPtr.ScanGroup) {
+		return nil
+	}
+
+	s.valueLock.RLock()
+	defer s.valueLock.RUnlock()
+
+	tagVal, ok := s.valueCache[tagPtr.Name]
+	if !ok {
+		return nil
+	}
+
+	ret := *tagVal
+	return &ret
+}
+
+func (s *Master) removeTagValue(name string) {
+	s.valueLock.Lock()
+	defer s.valueLock.Unlock()
+
+	delete(s.valueCache, name)
+	delete(s.reportMap, name)
+}
+
+// reportChange 与上次上报的取值相比超过Tag的死区时,在event.Hub上广播TagChangeEvent
+func (s *Master) reportChange(tagPtr *common.Tag, tagVal *common.TagValue) {
+	s.valueLock.Lock()
+	changed := common.ValueChanged(s.reportMap[tagPtr.Name], tagVal, tagPtr.Deadband, tagPtr.DeadbandPercent)
+	if changed {
+		s.reportMap[tagPtr.Name] = tagVal
+	}
+	s.valueLock.Unlock()
+	if !changed {
+		return
+	}
+
+	header := event.NewValues()
+	header.Set("slaveID", tagPtr.SlaveID)
+	header.Set("scanGroup", tagPtr.ScanGroup)
+	s.BroadCast(common.TagChangeEventID(tagPtr.Name), header, tagVal)
+}
@@ -0,0 +1,82 @@
+package biz
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/cache"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// coilResponder 读取多个线圈时返回不含数据的应答,读取单个线圈时返回1
+func coilResponder(t *testing.T, linkPtr *mbLink, transport *fakeTransport, done chan struct{}) {
+	for {
+		select {
+		case frame := <-transport.sent:
+			header, reqVal, err := model.DecodeMBTcpProtocol(bytes.NewBuffer(frame), model.RequestAction)
+			if err != model.SuccessCode {
+				t.Errorf("decode request failed, error:%d", err)
+				return
+			}
+
+			data := []byte{}
+			if reqVal.(*model.MBReadCoilsReq).Count() == 1 {
+				data = []byte{0x01}
+			}
+			rspVal := model.NewReadCoilsRsp(data)
+			buffVal := bytes.NewBuffer(nil)
+			model.EncodeMBTcpProtocol(model.NewTcpHeader(header.Transaction(), rspVal.CalcLen(), header.UnitID()), rspVal, buffVal)
+			linkPtr.OnRecvData(transport, buffVal.Bytes())
+		case <-done:
+			return
+		}
+	}
+}
+
+func TestScanShortBitResponse(t *testing.T) {
+	eventHub := event.NewHub(10)
+	defer eventHub.Terminate()
+	masterPtr := New(eventHub, task.NewBackgroundRoutine(10))
+
+	linkPtr := newTCPLink(1)
+	transport := startFakeLink(t, linkPtr)
+	done := make(chan struct{})
+	defer close(done)
+	go coilResponder(t, linkPtr, transport, done)
+
+	slaveMaster := newMaster(linkPtr, "mb001", 1, common.ABCDEndian)
+	slaveMaster.SetPolicy(common.RequestPolicy{Timeout: 200})
+	masterPtr.slaveInfoCache.Put("mb001", newSlaveInfo("mb001", "fake", 1, common.ModbusTcp, common.ABCDEndian, common.ReadCoalesce{}, slaveMaster), cache.ForeverAgeValue)
+	for idx, name := range []string{"c0", "c1"} {
+		_, tagErr := masterPtr.SaveTag(&common.Tag{Name: name, SlaveID: "mb001", Table: common.CoilTable, Address: uint16(idx), ValueType: common.BoolValue, ScanGroup: "fast"})
+		if tagErr != nil {
+			t.Fatalf("SaveTag failed, error:%s", tagErr.Error())
+		}
+	}
+
+	// 合并读取的应答取值个数不足时不能panic,改为逐个读取
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		masterPtr.scanTags("fast")
+	}()
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("scanTags timeout")
+	}
+
+	for _, name := range []string{"c0", "c1"} {
+		masterPtr.valueLock.RLock()
+		tagVal := masterPtr.valueCache[name]
+		masterPtr.valueLock.RUnlock()
+		if tagVal == nil || tagVal.Quality != common.GoodQuality || tagVal.Value != true {
+			t.Errorf("illegal tag value, name:%s, value:%v", name, tagVal)
+		}
+	}
+}
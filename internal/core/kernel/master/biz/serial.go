@@ -0,0 +1,199 @@
+package biz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/serial"
+)
+
+// ASCII模式下字符间隔超过1s视为帧异常
+const asciiCharTimeout = time.Second
+
+const serialRecvBuffSize = 256
+
+type serialAddr struct {
+	name string
+}
+
+func (s *serialAddr) Network() string {
+	return "serial"
+}
+
+func (s *serialAddr) String() string {
+	return s.name
+}
+
+// serialClient 以tcp.Client的形式封装串口,按照RTU帧间隔或ASCII结束符切分数据帧后回调Observer
+type serialClient struct {
+	observer tcp.Observer
+	mode     byte
+	config   serial.Config
+
+	port         *serial.Port
+	addr         *serialAddr
+	lastActivity time.Time
+	portLock     sync.Mutex
+}
+
+func newSerialClient(observer tcp.Observer, mode byte, config serial.Config) tcp.Client {
+	return &serialClient{
+		observer: observer,
+		mode:     mode,
+		config:   config,
+	}
+}
+
+func (s *serialClient) Connect(serverAddr string) (err error) {
+	port, portErr := serial.Open(serverAddr, s.config)
+	if portErr != nil {
+		log.Errorf("open serial port %s failed, error:%s", serverAddr, portErr.Error())
+		err = portErr
+		return
+	}
+
+	s.port = port
+	s.addr = &serialAddr{name: serverAddr}
+	s.lastActivity = time.Now()
+	go s.recvData()
+	return
+}
+
+func (s *serialClient) recvData() {
+	s.observer.OnConnect(s)
+	defer s.observer.OnDisConnect(s)
+
+	var err error
+	if s.mode == common.ModbusASCII {
+		err = s.recvASCIIFrame()
+	} else {
+		err = s.recvRTUFrame()
+	}
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Errorf("recv serial data failed, port:%s, error:%s", s.addr.String(), err.Error())
+	}
+}
+
+// recvRTUFrame 超过3.5个字符时间没有新数据即认为一帧结束
+func (s *serialClient) recvRTUFrame() error {
+	silence := s.config.Silence()
+	frameVal := []byte{}
+	buffVal := make([]byte, serialRecvBuffSize)
+	for {
+		deadline := time.Time{}
+		if len(frameVal) > 0 {
+			deadline = time.Now().Add(silence)
+		}
+		_ = s.port.SetReadDeadline(deadline)
+
+		rSize, rErr := s.port.Read(buffVal)
+		if rErr != nil {
+			if os.IsTimeout(rErr) {
+				s.observer.OnRecvData(s, frameVal)
+				frameVal = []byte{}
+				continue
+			}
+
+			return rErr
+		}
+
+		s.updateActivity()
+		frameVal = append(frameVal, buffVal[:rSize]...)
+	}
+}
+
+// recvASCIIFrame 以':'开始,以CRLF结束
+func (s *serialClient) recvASCIIFrame() error {
+	frameVal := []byte{}
+	buffVal := make([]byte, serialRecvBuffSize)
+	for {
+		deadline := time.Time{}
+		if len(frameVal) > 0 {
+			deadline = time.Now().Add(asciiCharTimeout)
+		}
+		_ = s.port.SetReadDeadline(deadline)
+
+		rSize, rErr := s.port.Read(buffVal)
+		if rErr != nil {
+			if os.IsTimeout(rErr) {
+				log.Warnf("drop incomplete ascii frame, port:%s", s.addr.String())
+				frameVal = []byte{}
+				continue
+			}
+
+			return rErr
+		}
+
+		s.updateActivity()
+		frameVal = append(frameVal, buffVal[:rSize]...)
+		for {
+			startIdx := bytes.IndexByte(frameVal, ':')
+			if startIdx < 0 {
+				frameVal = []byte{}
+				break
+			}
+
+			frameVal = frameVal[startIdx:]
+			endIdx := bytes.Index(frameVal, []byte("\r\n"))
+			if endIdx < 0 {
+				break
+			}
+
+			s.observer.OnRecvData(s, frameVal[:endIdx+2])
+			frameVal = frameVal[endIdx+2:]
+		}
+	}
+}
+
+func (s *serialClient) updateActivity() {
+	s.portLock.Lock()
+	defer s.portLock.Unlock()
+
+	s.lastActivity = time.Now()
+}
+
+func (s *serialClient) Close() {
+	if s.port == nil {
+		return
+	}
+
+	_ = s.port.Close()
+}
+
+// SendData RTU模式下发送前需要保证总线上已经保持了至少3.5个字符时间的静默
+func (s *serialClient) SendData(data []byte) error {
+	if s.port == nil {
+		return fmt.Errorf("illegal serial port, must connect first")
+	}
+
+	s.portLock.Lock()
+	defer s.portLock.Unlock()
+
+	if s.mode == common.ModbusRTU {
+		waitVal := s.config.Silence() - time.Since(s.lastActivity)
+		if waitVal > 0 {
+			time.Sleep(waitVal)
+		}
+	}
+
+	_, err := s.port.Write(data)
+	s.lastActivity = time.Now()
+	return err
+}
+
+func (s *serialClient) LocalAddr() net.Addr {
+	return s.addr
+}
+
+func (s *serialClient) RemoteAddr() net.Addr {
+	return s.addr
+}
@@ -0,0 +1,136 @@
+//go:build linux
+
+package biz
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+	"github.com/muidea/quickModbus/pkg/serial"
+)
+
+// openPty 打开一对伪终端,返回主设备和从设备路径
+func openPty(t *testing.T) (*os.File, string) {
+	master, masterErr := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if masterErr != nil {
+		t.Skipf("open /dev/ptmx failed, error:%s", masterErr.Error())
+	}
+
+	var unlock int32
+	if _, _, errNo := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errNo != 0 {
+		master.Close()
+		t.Skipf("unlock pty failed, error:%s", errNo.Error())
+	}
+
+	var ptyNo uint32
+	if _, _, errNo := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptyNo))); errNo != 0 {
+		master.Close()
+		t.Skipf("get pty number failed, error:%s", errNo.Error())
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNo)
+}
+
+// frameObserver 记录serialClient切分出的帧
+type frameObserver struct {
+	frames chan []byte
+}
+
+func (s *frameObserver) OnConnect(ep tcp.Endpoint) {
+}
+
+func (s *frameObserver) OnDisConnect(ep tcp.Endpoint) {
+}
+
+func (s *frameObserver) OnRecvData(ep tcp.Endpoint, data []byte) {
+	s.frames <- append([]byte(nil), data...)
+}
+
+func (s *frameObserver) nextFrame(t *testing.T, timeout time.Duration) []byte {
+	t.Helper()
+	select {
+	case val := <-s.frames:
+		return val
+	case <-time.After(timeout):
+		t.Fatalf("wait serial frame timeout")
+	}
+	return nil
+}
+
+func startSerialClient(t *testing.T, mode byte, config serial.Config) (*serialClient, *frameObserver, *os.File) {
+	t.Helper()
+	master, slaveName := openPty(t)
+	t.Cleanup(func() { master.Close() })
+
+	observer := &frameObserver{frames: make(chan []byte, 16)}
+	clientPtr := newSerialClient(observer, mode, config).(*serialClient)
+	if err := clientPtr.Connect(slaveName); err != nil {
+		t.Fatalf("Connect failed, error:%s", err.Error())
+	}
+	t.Cleanup(clientPtr.Close)
+	return clientPtr, observer, master
+}
+
+func TestSerialClientRTU(t *testing.T) {
+	config := serial.DefaultConfig()
+	config.FrameSilence = 50000
+	clientPtr, observer, master := startSerialClient(t, common.ModbusRTU, config)
+
+	// 帧内的间隔小于3.5个字符时间,两次写入合并为一帧
+	rspVal := rtuRegisterRsp(1, 0x1234)
+	_, _ = master.Write(rspVal[:3])
+	time.Sleep(5 * time.Millisecond)
+	_, _ = master.Write(rspVal[3:])
+	if frame := observer.nextFrame(t, time.Second); !bytes.Equal(frame, rspVal) {
+		t.Errorf("split rtu frame should be reassembled, frame:% x", frame)
+	}
+
+	// 超过静默时间后到达的数据是新的一帧
+	nextVal := rtuRegisterRsp(1, 0x5678)
+	_, _ = master.Write(nextVal)
+	if frame := observer.nextFrame(t, time.Second); !bytes.Equal(frame, nextVal) {
+		t.Errorf("illegal rtu frame:% x", frame)
+	}
+
+	// 刚收到数据时发送需要先等待静默时间
+	startTime := time.Now()
+	clientPtr.updateActivity()
+	if err := clientPtr.SendData([]byte{0x01, 0x03}); err != nil {
+		t.Fatalf("SendData failed, error:%s", err.Error())
+	}
+	if elapsed := time.Since(startTime); elapsed < config.Silence() {
+		t.Errorf("SendData should wait for bus silence, elapsed:%v", elapsed)
+	}
+}
+
+func TestSerialClientASCII(t *testing.T) {
+	_, observer, master := startSerialClient(t, common.ModbusASCII, serial.DefaultConfig())
+
+	frameVal := model.EncodeToASCIIStream([]byte{0x01, model.ReadHoldingRegisters, 2, 0x12, 0x34})
+
+	// 字符间隔超时的残帧被丢弃
+	_, _ = master.Write(frameVal[:5])
+	time.Sleep(asciiCharTimeout + 200*time.Millisecond)
+	// 起始符之前的数据被忽略,一次读取中的两帧分别回调
+	_, _ = master.Write(append(append([]byte("0B\r\n"), frameVal...), frameVal...))
+
+	for idx := 0; idx < 2; idx++ {
+		if frame := observer.nextFrame(t, time.Second); !bytes.Equal(frame, frameVal) {
+			t.Errorf("illegal ascii frame, idx:%d, frame:%q", idx, frame)
+		}
+	}
+	select {
+	case frame := <-observer.frames:
+		t.Errorf("torn ascii frame should be dropped, frame:%q", frame)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,51 @@
+package biz
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats 链路请求统计,Failures为超时或者发送失败的请求数
+type Stats struct {
+	Requests            uint64
+	Failures            uint64
+	Exceptions          uint64
+	ConsecutiveFailures uint64
+	LastError           string
+	LastActivity        time.Time
+}
+
+type statsRecorder struct {
+	lock  sync.RWMutex
+	stats Stats
+}
+
+type exceptionResponse interface {
+	ExceptionCode() byte
+}
+
+func (s *statsRecorder) record(rsp interface{}, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats.Requests++
+	if err != nil {
+		s.stats.Failures++
+		s.stats.ConsecutiveFailures++
+		s.stats.LastError = err.Error()
+		return
+	}
+
+	s.stats.ConsecutiveFailures = 0
+	s.stats.LastActivity = time.Now()
+	if exVal, exOK := rsp.(exceptionResponse); exOK && exVal.ExceptionCode() != 0 {
+		s.stats.Exceptions++
+	}
+}
+
+func (s *statsRecorder) Stats() Stats {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.stats
+}
@@ -0,0 +1,74 @@
+package biz
+
+import (
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// subscribeQueueSize 订阅者的推送队列长度,队列已满时丢弃新的扫描结果
+const subscribeQueueSize = 256
+
+type valueSubscriber struct {
+	match   func(*common.Tag) bool
+	valueCh chan *common.TagValue
+}
+
+/*
+SubscribeValue 订阅match匹配的Tag的扫描结果,每次扫描的结果都会推送,由订阅者自行过滤
+snapshot为订阅时已有的扫描结果,订阅者不再使用时需要调用UnsubscribeValue
+*/
+func (s *Master) SubscribeValue(match func(*common.Tag) bool) (id int, valueCh <-chan *common.TagValue, snapshot []*common.TagValue) {
+	subscriber := &valueSubscriber{match: match, valueCh: make(chan *common.TagValue, subscribeQueueSize)}
+
+	s.subscribeLock.Lock()
+	s.subscribeID++
+	id = s.subscribeID
+	s.subscriberMap[id] = subscriber
+	s.subscribeLock.Unlock()
+
+	valueCh = subscriber.valueCh
+	snapshot = []*common.TagValue{}
+	for _, val := range s.ListTag() {
+		if !match(val) {
+			continue
+		}
+
+		cacheVal := s.cachedTagValue(val)
+		if cacheVal != nil {
+			snapshot = append(snapshot, cacheVal)
+		}
+	}
+	return
+}
+
+// UnsubscribeValue 取消订阅并关闭推送通道
+func (s *Master) UnsubscribeValue(id int) {
+	s.subscribeLock.Lock()
+	defer s.subscribeLock.Unlock()
+
+	subscriber, ok := s.subscriberMap[id]
+	if !ok {
+		return
+	}
+
+	delete(s.subscriberMap, id)
+	close(subscriber.valueCh)
+}
+
+func (s *Master) publishValue(tagPtr *common.Tag, tagVal *common.TagValue) {
+	s.subscribeLock.RLock()
+	defer s.subscribeLock.RUnlock()
+
+	for id, val := range s.subscriberMap {
+		if !val.match(tagPtr) {
+			continue
+		}
+
+		select {
+		case val.valueCh <- tagVal:
+		default:
+			log.Warnf("publish value failed, subscriber:%d, tag:%s, queue is full", id, tagPtr.Name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package biz
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+const (
+	superviseInterval      = time.Second
+	minReconnectInterval   = time.Second
+	maxReconnectInterval   = time.Minute
+	maxReconnectBackoffExp = 6
+)
+
+// slaveInfo 从站连接参数及其重连状态
+type slaveInfo struct {
+	slaveID    string
+	slaveAddr  string
+	devID      byte
+	devType    byte
+	endianType byte
+	coalesce   common.ReadCoalesce
+	master     MBMaster
+
+	lock        sync.Mutex
+	connectTime time.Time
+	connecting  bool
+	closed      bool
+	// reconnectCount 累计的重连次数,failedReconnects 上次连接成功之后连续失败的次数,用于计算重连间隔
+	reconnectCount   int
+	failedReconnects int
+	nextReconnect    time.Time
+	lastError        string
+}
+
+func newSlaveInfo(slaveID, slaveAddr string, devID, devType, endianType byte, coalesce common.ReadCoalesce, master MBMaster) *slaveInfo {
+	return &slaveInfo{
+		slaveID:     slaveID,
+		slaveAddr:   slaveAddr,
+		devID:       devID,
+		devType:     devType,
+		endianType:  endianType,
+		coalesce:    coalesce,
+		master:      master,
+		connectTime: time.Now(),
+	}
+}
+
+// reconnectInterval 连续失败后重连间隔按照2的幂次增长,最长一分钟
+func reconnectInterval(failCount int) time.Duration {
+	interval := minReconnectInterval << min(failCount, maxReconnectBackoffExp)
+	return min(interval, maxReconnectInterval)
+}
+
+func (s *slaveInfo) Status() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.connecting {
+		return common.SlaveConnecting
+	}
+	if s.closed || !s.master.IsConnect() {
+		return common.SlaveOffline
+	}
+	if s.master.Stats().ConsecutiveFailures > 0 {
+		return common.SlaveDegraded
+	}
+
+	return common.SlaveOnline
+}
+
+// beginReconnect force为false时需等待到下次重连时间
+func (s *slaveInfo) beginReconnect(force bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed || s.connecting || s.master.IsConnect() {
+		return false
+	}
+	if !force && time.Now().Before(s.nextReconnect) {
+		return false
+	}
+
+	s.connecting = true
+	return true
+}
+
+func (s *slaveInfo) endReconnect(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.connecting = false
+	if !s.closed {
+		observeReconnect(s.slaveID, err)
+	}
+	s.reconnectCount++
+	if err != nil {
+		s.lastError = err.Error()
+		s.nextReconnect = time.Now().Add(reconnectInterval(s.failedReconnects))
+		s.failedReconnects++
+		return
+	}
+
+	s.failedReconnects = 0
+	s.lastError = ""
+	s.connectTime = time.Now()
+}
+
+func (s *slaveInfo) reconnect() {
+	err := s.master.ReConnect()
+	if err != nil {
+		log.Warnf("reconnect slave %s failed, error:%s", s.slaveID, err.Error())
+	} else {
+		log.Infof("reconnect slave %s ok", s.slaveID)
+	}
+	s.endReconnect(err)
+}
+
+// ensureConnect 请求前检查连接,未连接时立即重连一次
+func (s *slaveInfo) ensureConnect() error {
+	if s.master.IsConnect() {
+		return nil
+	}
+
+	if !s.beginReconnect(true) {
+		return fmt.Errorf("slave %s is %s", s.slaveID, s.Status())
+	}
+
+	s.reconnect()
+	if !s.master.IsConnect() {
+		return fmt.Errorf("slave %s is offline, error:%s", s.slaveID, s.LastError())
+	}
+
+	return nil
+}
+
+func (s *slaveInfo) LastError() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.lastError
+}
+
+// ReconnectCount 返回累计的重连次数以及上次连接成功之后连续失败的次数
+func (s *slaveInfo) ReconnectCount() (total, failed int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.reconnectCount, s.failedReconnects
+}
+
+func (s *slaveInfo) View() *common.SlaveView {
+	s.lock.Lock()
+	connectTime := s.connectTime
+	s.lock.Unlock()
+
+	statsVal := s.master.Stats()
+	ret := &common.SlaveView{
+		SlaveID:     s.slaveID,
+		SlaveAddr:   s.slaveAddr,
+		DeviceID:    s.devID,
+		DeviceType:  s.devType,
+		EndianType:  s.endianType,
+		Status:      s.Status(),
+		ConnectTime: connectTime,
+		Requests:    statsVal.Requests,
+		Failures:    statsVal.Failures,
+		Exceptions:  statsVal.Exceptions,
+	}
+	if !statsVal.LastActivity.IsZero() {
+		ret.LastActivity = &statsVal.LastActivity
+	}
+
+	return ret
+}
+
+// close 在持有lock的情况下删除从站的指标,updateConnected与endReconnect在同一把锁下检查closed,避免指标被重新创建
+func (s *slaveInfo) close() {
+	s.lock.Lock()
+	s.closed = true
+	deleteSlaveMetrics(s.slaveID)
+	s.lock.Unlock()
+
+	s.master.Stop()
+}
+
+// updateConnected 更新连接状态指标,从站已断开时不再更新
+func (s *slaveInfo) updateConnected() bool {
+	connected := s.master.IsConnect()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return false
+	}
+
+	connectedVal := 0.0
+	if connected {
+		connectedVal = 1
+	}
+	slaveConnectedGauge.Set(connectedVal, s.slaveID)
+	return connected
+}
+
+// superviseSlaves 定时检查所有从站,更新连接状态指标并对断开的从站在后台重连
+func (s *Master) superviseSlaves() {
+	connected := 0
+	for _, val := range s.slaveInfoCache.GetAll() {
+		infoPtr := val.(*slaveInfo)
+		if infoPtr.updateConnected() {
+			connected++
+		}
+		if !infoPtr.beginReconnect(false) {
+			continue
+		}
+
+		s.AsyncTask(infoPtr.reconnect)
+	}
+	connectedSlavesGauge.Set(float64(connected))
+}
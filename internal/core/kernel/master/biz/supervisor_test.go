@@ -0,0 +1,26 @@
+package biz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func TestReconnectCount(t *testing.T) {
+	linkPtr := newTCPLink(1)
+	startFakeLink(t, linkPtr)
+	infoPtr := newSlaveInfo("mbretry", "fake", 1, 0, 0, common.ReadCoalesce{}, newMaster(linkPtr, "mbretry", 1, 0))
+	defer infoPtr.close()
+
+	// 累计次数只增不减,连续失败次数在连接成功后清零
+	infoPtr.endReconnect(errors.New("connect refused"))
+	infoPtr.endReconnect(errors.New("connect refused"))
+	if total, failed := infoPtr.ReconnectCount(); total != 2 || failed != 2 {
+		t.Errorf("illegal reconnect count, total:%d, failed:%d", total, failed)
+	}
+	infoPtr.endReconnect(nil)
+	if total, failed := infoPtr.ReconnectCount(); total != 3 || failed != 0 {
+		t.Errorf("illegal reconnect count, total:%d, failed:%d", total, failed)
+	}
+}
@@ -0,0 +1,336 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// SaveTag 新增或者替换Tag定义,Tag引用的从站可以在之后再连接
+func (s *Master) SaveTag(tag *common.Tag) (ret *common.Tag, err *cd.Result) {
+	tagVal := *tag
+	tagErr := tagVal.Validate()
+	if tagErr != nil {
+		log.Errorf("saveTag failed, error:%s", tagErr.Error())
+		err = cd.NewError(cd.IllegalParam, tagErr.Error())
+		return
+	}
+
+	s.tagLock.Lock()
+	s.tagMap[tagVal.Name] = &tagVal
+	s.tagLock.Unlock()
+	s.removeTagValue(tagVal.Name)
+
+	ret = &tagVal
+	return
+}
+
+func (s *Master) DeleteTag(name string) (err *cd.Result) {
+	s.tagLock.Lock()
+	defer s.tagLock.Unlock()
+
+	_, ok := s.tagMap[name]
+	if !ok {
+		errMsg := fmt.Sprintf("no exist tag %s", name)
+		log.Errorf("deleteTag failed, error:%s", errMsg)
+		err = cd.NewError(cd.UnExpected, errMsg)
+		return
+	}
+
+	delete(s.tagMap, name)
+	s.removeTagValue(name)
+	return
+}
+
+func (s *Master) ListTag() (ret []*common.Tag) {
+	s.tagLock.RLock()
+	defer s.tagLock.RUnlock()
+
+	ret = []*common.Tag{}
+	for _, val := range s.tagMap {
+		tagVal := *val
+		ret = append(ret, &tagVal)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return
+}
+
+func (s *Master) QueryTag(name string) (ret *common.Tag, err *cd.Result) {
+	s.tagLock.RLock()
+	defer s.tagLock.RUnlock()
+
+	tagPtr, ok := s.tagMap[name]
+	if !ok {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("no exist tag %s", name))
+		return
+	}
+
+	tagVal := *tagPtr
+	ret = &tagVal
+	return
+}
+
+// ReadTag 属于扫描组的Tag返回最近一次扫描的结果,其余Tag直接读取从站
+func (s *Master) ReadTag(ctx context.Context, policy *common.RequestPolicy, name string) (ret *common.TagValue, exCode byte, err *cd.Result) {
+	tagPtr, tagErr := s.QueryTag(name)
+	if tagErr != nil {
+		log.Errorf("readTag failed, error:%s", tagErr.Reason)
+		err = tagErr
+		return
+	}
+
+	cacheVal := s.cachedTagValue(tagPtr)
+	if cacheVal != nil {
+		ret = cacheVal
+		return
+	}
+
+	itemVal, itemExCode, itemErr := s.readTagValue(ctx, policy, tagPtr)
+	if itemErr != nil {
+		exCode = itemExCode
+		err = itemErr
+		return
+	}
+
+	ret = &common.TagValue{Name: tagPtr.Name, Value: itemVal, Unit: tagPtr.Unit, Quality: common.GoodQuality, Timestamp: time.Now()}
+	return
+}
+
+func (s *Master) readTagValue(ctx context.Context, policy *common.RequestPolicy, tagPtr *common.Tag) (ret interface{}, exCode byte, err *cd.Result) {
+	blockPtr := &common.ReadBlock{SlaveID: tagPtr.SlaveID, Table: tagPtr.Table, Address: tagPtr.Address, Count: tagPtr.Size(), Tags: []*common.Tag{tagPtr}}
+	values, exCode, err := s.readBlock(ctx, policy, blockPtr)
+	if err != nil {
+		return
+	}
+
+	ret = values[0]
+	return
+}
+
+// illegalValueCount 应答中的取值个数少于请求的个数
+const illegalValueCount = "illegal read value count"
+
+// readBlock 读取整个读取块后拆分出各个Tag的取值,取值顺序与block.Tags一致
+func (s *Master) readBlock(ctx context.Context, policy *common.RequestPolicy, blockPtr *common.ReadBlock) (ret []interface{}, exCode byte, err *cd.Result) {
+	switch blockPtr.Table {
+	case common.CoilTable, common.DiscreteInputTable:
+		var boolVal []bool
+		if blockPtr.Table == common.CoilTable {
+			boolVal, exCode, err = s.ReadCoils(ctx, blockPtr.SlaveID, policy, blockPtr.Address, blockPtr.Count)
+		} else {
+			boolVal, exCode, err = s.ReadDiscreteInputs(ctx, blockPtr.SlaveID, policy, blockPtr.Address, blockPtr.Count)
+		}
+		if err != nil {
+			return
+		}
+		if len(boolVal) < int(blockPtr.Count) {
+			log.Errorf("readBlock failed, slaveID:%s, illegal read value count, expect:%d, actual:%d", blockPtr.SlaveID, blockPtr.Count, len(boolVal))
+			err = cd.NewError(cd.UnExpected, illegalValueCount)
+			return
+		}
+
+		for _, val := range blockPtr.Tags {
+			ret = append(ret, boolVal[blockPtr.Offset(val)])
+		}
+	default:
+		readVal, endianType, readExCode, readErr := s.readRegisters(ctx, policy, blockPtr)
+		if readErr != nil {
+			exCode = readExCode
+			err = readErr
+			return
+		}
+
+		values := make([]interface{}, 0, len(blockPtr.Tags))
+		for _, val := range blockPtr.Tags {
+			tagEndian := val.EndianType
+			if tagEndian == common.DefaultEndian {
+				tagEndian = endianType
+			}
+
+			offset := blockPtr.Offset(val) * 2
+			itemVal, itemErr := s.decodeReadVal(readVal[offset:offset+int(val.Size())*2], val.ValueType, 1, tagEndian)
+			var rawVal float64
+			if itemErr == nil {
+				rawVal, itemErr = firstFloat64(itemVal)
+			}
+			if itemErr != nil {
+				log.Errorf("readBlock failed, tag:%s, error:%s", val.Name, itemErr.Error())
+				err = cd.NewError(cd.UnExpected, itemErr.Error())
+				return
+			}
+
+			values = append(values, val.ToEngineering(rawVal))
+		}
+		ret = values
+	}
+
+	return
+}
+
+// readRegisters 读取原始的寄存器数据,同时返回从站的字节序
+func (s *Master) readRegisters(ctx context.Context, policy *common.RequestPolicy, blockPtr *common.ReadBlock) (ret []byte, endianType byte, exCode byte, err *cd.Result) {
+	mbMasterPtr, mbErr := s.fetchMaster(blockPtr.SlaveID)
+	if mbErr != nil {
+		log.Errorf("readRegisters failed, error:%s", mbErr.Reason)
+		err = mbErr
+		return
+	}
+
+	var readVal []byte
+	var readExCode byte
+	var readErr error
+	if blockPtr.Table == common.HoldingRegisterTable {
+		readVal, readExCode, readErr = mbMasterPtr.ReadHoldingRegisters(ctx, policy, blockPtr.Address, blockPtr.Count)
+	} else {
+		readVal, readExCode, readErr = mbMasterPtr.ReadInputRegisters(ctx, policy, blockPtr.Address, blockPtr.Count)
+	}
+	if readErr != nil {
+		log.Errorf("readRegisters failed, error:%s", readErr.Error())
+		err = requestError(readErr)
+		return
+	}
+	if readExCode != model.SuccessCode {
+		exCode = readExCode
+		errMsg := fmt.Sprintf("modbus exception code:%v", readExCode)
+		log.Errorf("readRegisters failed, error:%s", errMsg)
+		err = cd.NewError(cd.UnExpected, errMsg)
+		return
+	}
+	if len(readVal) != int(blockPtr.Count)*2 {
+		errMsg := fmt.Sprintf("illegal read value count")
+		log.Errorf("readRegisters failed, error:%s", errMsg)
+		err = cd.NewError(cd.UnExpected, errMsg)
+		return
+	}
+
+	ret = readVal
+	endianType = mbMasterPtr.EndianType()
+	return
+}
+
+// ReadTags 批量读取Tag,单个Tag的失败记录在对应的TagValue中
+func (s *Master) ReadTags(ctx context.Context, policy *common.RequestPolicy, names []string) (ret []*common.TagValue) {
+	ret = []*common.TagValue{}
+	for _, name := range names {
+		tagVal, exCode, tagErr := s.ReadTag(ctx, policy, name)
+		if tagErr != nil {
+			tagVal = &common.TagValue{Name: name, Quality: common.BadQuality, Timestamp: time.Now(), ExceptionCode: exCode, Error: tagErr.Reason}
+		}
+
+		ret = append(ret, tagVal)
+	}
+
+	return
+}
+
+// WriteTag 线圈Tag的value为bool,寄存器Tag的value为工程值,16位类型使用写单个寄存器
+func (s *Master) WriteTag(ctx context.Context, policy *common.RequestPolicy, name string, value interface{}) (exCode byte, err *cd.Result) {
+	tagPtr, tagErr := s.QueryTag(name)
+	if tagErr != nil {
+		log.Errorf("writeTag failed, error:%s", tagErr.Reason)
+		err = tagErr
+		return
+	}
+	if !tagPtr.Writable() {
+		errMsg := fmt.Sprintf("tag %s is read only", name)
+		log.Errorf("writeTag failed, error:%s", errMsg)
+		err = cd.NewError(cd.IllegalParam, errMsg)
+		return
+	}
+
+	if tagPtr.Table == common.CoilTable {
+		boolVal, boolOK := value.(bool)
+		if !boolOK {
+			errMsg := fmt.Sprintf("illegal value %v for tag %s", value, name)
+			log.Errorf("writeTag failed, error:%s", errMsg)
+			err = cd.NewError(cd.IllegalParam, errMsg)
+			return
+		}
+
+		exCode, err = s.WriteSingleCoil(ctx, tagPtr.SlaveID, policy, tagPtr.Address, boolVal)
+		return
+	}
+
+	floatVal, floatOK := value.(float64)
+	if !floatOK {
+		errMsg := fmt.Sprintf("illegal value %v for tag %s", value, name)
+		log.Errorf("writeTag failed, error:%s", errMsg)
+		err = cd.NewError(cd.IllegalParam, errMsg)
+		return
+	}
+
+	rawVal, rawErr := tagPtr.ToRaw(floatVal)
+	if rawErr != nil {
+		log.Errorf("writeTag failed, error:%s", rawErr.Error())
+		err = cd.NewError(cd.IllegalParam, rawErr.Error())
+		return
+	}
+
+	switch tagPtr.ValueType {
+	case common.Int16Value:
+		exCode, err = s.WriteSingleRegister(ctx, tagPtr.SlaveID, policy, tagPtr.Address, uint16(int16(rawVal)), tagPtr.EndianType)
+	case common.UInt16Value:
+		exCode, err = s.WriteSingleRegister(ctx, tagPtr.SlaveID, policy, tagPtr.Address, uint16(rawVal), tagPtr.EndianType)
+	default:
+		exCode, err = s.WriteMultipleRegisters(ctx, tagPtr.SlaveID, policy, tagPtr.Address, []float64{rawVal}, tagPtr.ValueType, tagPtr.EndianType)
+	}
+	return
+}
+
+func firstFloat64(readVal interface{}) (ret float64, err error) {
+	switch val := readVal.(type) {
+	case []int16:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []uint16:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []int32:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []uint32:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []int64:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []uint64:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []float32:
+		if len(val) > 0 {
+			ret = float64(val[0])
+			return
+		}
+	case []float64:
+		if len(val) > 0 {
+			ret = val[0]
+			return
+		}
+	}
+
+	err = fmt.Errorf("illegal read value %v", readVal)
+	return
+}
@@ -0,0 +1,116 @@
+package biz
+
+import (
+	"fmt"
+	"os"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// ImportTemplate 连接模板中的从站并保存其全部Tag,从站已连接时直接使用该从站
+// 任一Tag定义非法或者与其它从站的Tag重名时不保存任何Tag,本次新建的从站连接也会断开
+func (s *Master) ImportTemplate(template *common.DeviceTemplate) (slaveID string, tags []*common.Tag, err *cd.Result) {
+	for _, val := range template.ScanGroups {
+		groupErr := val.Validate()
+		if groupErr != nil {
+			log.Errorf("importTemplate failed, slaveAddr:%s, error:%s", template.SlaveAddr, groupErr.Error())
+			err = cd.NewError(cd.IllegalParam, groupErr.Error())
+			return
+		}
+	}
+
+	newSlave := false
+	slaveID = s.findSlaveID(template.SlaveAddr, template.DeviceID)
+	if slaveID == "" {
+		slaveID, err = s.ConnectSlave(template.SlaveAddr, template.DeviceID, template.DeviceType, template.EndianType, template.SerialConfig, template.RequestPolicy, template.MaxTransactions, template.ReadCoalesce)
+		if err != nil {
+			return
+		}
+		newSlave = true
+	}
+
+	tagList := []*common.Tag{}
+	nameMap := map[string]bool{}
+	for _, val := range template.Tags {
+		tagVal := *val
+		tagVal.Name = template.NamePrefix + tagVal.Name
+		tagVal.SlaveID = slaveID
+		var errCode cd.ErrorCode = cd.IllegalParam
+		tagErr := tagVal.Validate()
+		if tagErr == nil && nameMap[tagVal.Name] {
+			tagErr = fmt.Errorf("duplicate tag %s", tagVal.Name)
+		}
+		if tagErr == nil {
+			tagErr = s.checkTagOwner(&tagVal)
+			errCode = cd.Duplicated
+		}
+		if tagErr != nil {
+			log.Errorf("importTemplate failed, slaveAddr:%s, error:%s", template.SlaveAddr, tagErr.Error())
+			err = cd.NewError(errCode, tagErr.Error())
+			if newSlave {
+				_ = s.DisConnectSlave(slaveID)
+			}
+			return
+		}
+
+		nameMap[tagVal.Name] = true
+		tagList = append(tagList, &tagVal)
+	}
+
+	for _, val := range template.ScanGroups {
+		_, err = s.SaveScanGroup(val)
+		if err != nil {
+			log.Errorf("importTemplate failed, slaveAddr:%s, scanGroup:%s, error:%s", template.SlaveAddr, val.Name, err.Error())
+			if newSlave {
+				_ = s.DisConnectSlave(slaveID)
+			}
+			return
+		}
+	}
+
+	for _, val := range tagList {
+		_, err = s.SaveTag(val)
+		if err != nil {
+			log.Errorf("importTemplate failed, slaveAddr:%s, tag:%s, error:%s", template.SlaveAddr, val.Name, err.Error())
+			return
+		}
+	}
+
+	tags = tagList
+	return
+}
+
+// checkTagOwner 同名的Tag已经属于其它从站时返回错误,重复导入同一从站的模板时覆盖原有Tag
+func (s *Master) checkTagOwner(tag *common.Tag) error {
+	s.tagLock.RLock()
+	defer s.tagLock.RUnlock()
+
+	tagPtr, ok := s.tagMap[tag.Name]
+	if !ok || tagPtr.SlaveID == tag.SlaveID {
+		return nil
+	}
+
+	return fmt.Errorf("tag %s already exists on slave %s", tag.Name, tagPtr.SlaveID)
+}
+
+// ImportTemplateFile 导入本地模板文件,扩展名为.csv时按照CSV模板解析
+func (s *Master) ImportTemplateFile(fileName string) (slaveID string, tags []*common.Tag, err *cd.Result) {
+	data, dataErr := os.ReadFile(fileName)
+	if dataErr != nil {
+		log.Errorf("importTemplateFile failed, fileName:%s, error:%s", fileName, dataErr.Error())
+		err = cd.NewError(cd.IllegalParam, dataErr.Error())
+		return
+	}
+
+	templatePtr, templateErr := common.ParseDeviceTemplate(data, common.TemplateFormat(fileName))
+	if templateErr != nil {
+		log.Errorf("importTemplateFile failed, fileName:%s, error:%s", fileName, templateErr.Error())
+		err = cd.NewError(cd.IllegalParam, templateErr.Error())
+		return
+	}
+
+	return s.ImportTemplate(templatePtr)
+}
@@ -0,0 +1,144 @@
+package biz
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func TestImportTemplate(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, error:%s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, connErr := listener.Accept()
+			if connErr != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	eventHub := event.NewHub(10)
+	defer eventHub.Terminate()
+	masterPtr := New(eventHub, task.NewBackgroundRoutine(10))
+	defer masterPtr.Teardown()
+
+	newTemplate := func(devID byte) *common.DeviceTemplate {
+		return &common.DeviceTemplate{
+			ConnectSlaveRequest: common.ConnectSlaveRequest{SlaveAddr: listener.Addr().String(), DeviceID: devID, DeviceType: common.ModbusTcp},
+			Tags:                []*common.Tag{{Name: "temp", Table: common.HoldingRegisterTable, Address: 1, ValueType: common.UInt16Value}},
+		}
+	}
+
+	slaveID, _, importErr := masterPtr.ImportTemplate(newTemplate(1))
+	if importErr != nil {
+		t.Fatalf("ImportTemplate failed, error:%s", importErr.Error())
+	}
+	// 重复导入同一从站的模板覆盖原有Tag
+	if _, _, importErr = masterPtr.ImportTemplate(newTemplate(1)); importErr != nil {
+		t.Errorf("reimport template failed, error:%s", importErr.Error())
+	}
+
+	// 与其它从站的Tag重名时拒绝导入,本次新建的从站连接断开
+	_, _, importErr = masterPtr.ImportTemplate(newTemplate(2))
+	if importErr == nil || importErr.ErrorCode != cd.Duplicated {
+		t.Errorf("import template with conflict tag name should fail, error:%v", importErr)
+	}
+	if masterPtr.findSlaveID(listener.Addr().String(), 2) != "" {
+		t.Errorf("new slave should be disconnected after import failed")
+	}
+	tagPtr, tagErr := masterPtr.QueryTag("temp")
+	if tagErr != nil || tagPtr.SlaveID != slaveID {
+		t.Errorf("conflict import should not overwrite tag, tag:%v, error:%v", tagPtr, tagErr)
+	}
+
+	// 扫描组非法时返回错误
+	templatePtr := newTemplate(1)
+	templatePtr.ScanGroups = []*common.ScanGroup{{Name: "fast"}}
+	if _, _, importErr = masterPtr.ImportTemplate(templatePtr); importErr == nil || importErr.ErrorCode != cd.IllegalParam {
+		t.Errorf("import template with illegal scan group should fail, error:%v", importErr)
+	}
+}
+
+// blockingTransport Connect在release关闭之前一直阻塞
+type blockingTransport struct {
+	fakeTransport
+	release chan struct{}
+}
+
+func (s *blockingTransport) Connect(serverAddr string) error {
+	<-s.release
+	return s.fakeTransport.Connect(serverAddr)
+}
+
+func TestConnectSlaveOutsideLock(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, error:%s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, connErr := listener.Accept()
+			if connErr != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	eventHub := event.NewHub(10)
+	defer eventHub.Terminate()
+	masterPtr := New(eventHub, task.NewBackgroundRoutine(10))
+	defer masterPtr.Teardown()
+
+	// 慢速网关的链路连接阻塞
+	transport := &blockingTransport{fakeTransport: fakeTransport{sent: make(chan []byte, 16)}, release: make(chan struct{})}
+	slowLink := newTCPLink(1)
+	slowLink.newTransport = func(observer tcp.Observer) Transport {
+		transport.observer = observer
+		return transport
+	}
+	masterPtr.linkMap[linkKey("slow:502", common.ModbusTcp)] = &linkEntry{link: slowLink}
+
+	slowCh := make(chan *cd.Result, 1)
+	go func() {
+		_, connErr := masterPtr.ConnectSlave("slow:502", 1, common.ModbusTcp, 0, nil, common.RequestPolicy{}, 0, common.ReadCoalesce{})
+		slowCh <- connErr
+	}()
+	waitFor(t, "slow link acquired", func() bool { return slowLink.refs() == 1 })
+
+	// 慢速连接期间其它从站的连接不受影响
+	doneCh := make(chan *cd.Result, 1)
+	go func() {
+		_, connErr := masterPtr.ConnectSlave(listener.Addr().String(), 2, common.ModbusTcp, 0, nil, common.RequestPolicy{}, 0, common.ReadCoalesce{})
+		doneCh <- connErr
+	}()
+	select {
+	case connErr := <-doneCh:
+		if connErr != nil {
+			t.Errorf("ConnectSlave failed, error:%s", connErr.Error())
+		}
+	case <-time.After(time.Second):
+		t.Errorf("ConnectSlave blocked by a pending dial")
+	}
+
+	close(transport.release)
+	if connErr := <-slowCh; connErr != nil {
+		t.Errorf("slow ConnectSlave failed, error:%s", connErr.Error())
+	}
+	if masterPtr.findSlaveID("slow:502", 1) == "" {
+		t.Errorf("slow slave should be registered")
+	}
+}
@@ -0,0 +1,89 @@
+package biz
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/model"
+	"github.com/muidea/quickModbus/pkg/serial"
+)
+
+// Framer 负责PDU与链路帧之间的相互转换,并给出请求与应答的匹配标识
+type Framer interface {
+	// Encode 将发往unitID的请求PDU编码为完整的链路帧,signalID用于等待对应的应答
+	Encode(unitID byte, pdu model.MBProtocol) (frame []byte, signalID int, err error)
+	// FrameLen 计算接收缓存中首个完整帧的长度,数据不足时返回0,返回错误表示缓存已无法同步
+	FrameLen(data []byte) (int, error)
+	// Decode 解析应答帧,返回与Encode一致的signalID
+	Decode(frame []byte) (signalID int, pdu model.MBProtocol, err error)
+	// Reset 链路断开后复位Framer内部状态
+	Reset()
+}
+
+// Transport 底层链路,tcp.Client和串口均满足该接口
+type Transport interface {
+	Connect(serverAddr string) error
+	Close()
+	SendData(data []byte) error
+	RemoteAddr() net.Addr
+}
+
+// TransportFunc 创建链路,收到的数据通过observer回调
+type TransportFunc func(observer tcp.Observer) Transport
+
+func newTCPTransport(observer tcp.Observer) Transport {
+	return tcp.NewClient(observer)
+}
+
+func newSerialTransport(mode byte, config serial.Config) TransportFunc {
+	return func(observer tcp.Observer) Transport {
+		return newSerialClient(observer, mode, config)
+	}
+}
+
+/*
+serialLateResponseWindow 串行链路没有事务号,请求没有收到应答时继续占用链路的时长,
+期间到达的迟到应答没有对应的等待者而被丢弃,不会被下一个相同地址与功能码的请求误收
+*/
+const serialLateResponseWindow = 500 * time.Millisecond
+
+// newSerialLink 串行链路同一时刻只能有一个事务
+func newSerialLink(framer Framer, newTransport TransportFunc) *mbLink {
+	ret := newLink(framer, newTransport, 1)
+	ret.lateResponseWindow = serialLateResponseWindow
+	return ret
+}
+
+// serialSignalID 串行链路没有事务号,以从站地址和功能码匹配应答
+func serialSignalID(address, funcCode byte) int {
+	return int(address)<<8 | int(funcCode)
+}
+
+func encodeSerialADU(address byte, pdu model.MBProtocol) (ret []byte, signalID int, err error) {
+	buffVal := bytes.NewBuffer(nil)
+	eErr := model.EncodeMBSerialProtocol(model.NewSerialHeader(address), pdu, buffVal)
+	if eErr != model.SuccessCode {
+		err = fmt.Errorf("encode serial protocol failed, error:%v", eErr)
+		return
+	}
+
+	ret = buffVal.Bytes()
+	signalID = serialSignalID(address, pdu.FuncCode())
+	return
+}
+
+func decodeSerialADU(adu []byte) (signalID int, pdu model.MBProtocol, err error) {
+	header, protocolVal, protocolErr := model.DecodeMBSerialProtocol(bytes.NewBuffer(adu), model.ResponseAction)
+	if protocolErr != model.SuccessCode {
+		err = fmt.Errorf("decode serial protocol failed, error:%v", protocolErr)
+		return
+	}
+
+	signalID = serialSignalID(header.Address(), protocolVal.FuncCode())
+	pdu = protocolVal
+	return
+}
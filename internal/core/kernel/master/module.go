@@ -1,11 +1,15 @@
 package master
 
 import (
+	"net/http"
+
 	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
 	"github.com/muidea/magicCommon/module"
 	"github.com/muidea/magicCommon/task"
 	engine "github.com/muidea/magicEngine/http"
 
+	"github.com/muidea/quickModbus/internal/config"
 	"github.com/muidea/quickModbus/internal/core/kernel/master/biz"
 	"github.com/muidea/quickModbus/internal/core/kernel/master/service"
 	"github.com/muidea/quickModbus/pkg/common"
@@ -41,10 +45,51 @@ func (s *Master) Setup(endpointName string, eventHub event.Hub, backgroundRoutin
 	s.backgroundRoutine = backgroundRoutine
 
 	s.bizPtr = biz.New(eventHub, backgroundRoutine)
+	if historyCfg := config.History(); historyCfg != nil {
+		options, err := historyCfg.Options()
+		if err == nil {
+			err = s.bizPtr.EnableHistory(options)
+		}
+		if err != nil {
+			log.Errorf("enable history failed, dir:%s, error:%s", historyCfg.Dir, err.Error())
+		}
+	}
 	s.servicePtr = service.New(s.bizPtr)
 	s.servicePtr.BindRegistry(s.routeRegistry)
 }
 
 func (s *Master) Run() {
 	s.servicePtr.RegisterRoute()
+
+	for _, val := range config.ScanGroups() {
+		_, err := s.bizPtr.SaveScanGroup(val)
+		if err != nil {
+			log.Errorf("create scan group %s failed, error:%s", val.Name, err.Error())
+		}
+	}
+	for _, val := range config.TemplateFiles() {
+		fileName := val
+		s.bizPtr.AsyncTask(func() {
+			slaveID, tags, err := s.bizPtr.ImportTemplateFile(fileName)
+			if err != nil {
+				log.Errorf("import device template %s failed, error:%s", fileName, err.Error())
+				return
+			}
+
+			log.Infof("import device template %s ok, slaveID:%s, tags:%d", fileName, slaveID, len(tags))
+		})
+	}
+}
+
+// RawHandlers 不经过路由注册、直接使用http.ResponseWriter的接口
+func (s *Master) RawHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		common.StreamValue: s.servicePtr.StreamValue,
+	}
+}
+
+func (s *Master) Teardown() {
+	if s.bizPtr != nil {
+		s.bizPtr.Teardown()
+	}
 }
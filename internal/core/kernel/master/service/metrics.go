@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/muidea/quickModbus/pkg/metrics"
+)
+
+// Metrics 输出进程内的全部指标
+func (s *Master) Metrics(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	metrics.Default.ServeHTTP(res, req)
+}
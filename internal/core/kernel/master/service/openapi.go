@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	engine "github.com/muidea/magicEngine/http"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+const openAPIVersion = "3.0.3"
+
+// routeDoc 路由的OpenAPI描述,request与response为pkg/common中对应结构的指针
+// values为应答中Values字段的实际类型,policyQuery表示GET请求通过query参数传递RequestPolicy
+// csvRequest表示请求体也可以是text/csv格式,queryParams为其它字符串类型的query参数
+// contentType为非JSON应答的类型,设置后response被忽略
+type routeDoc struct {
+	summary     string
+	request     interface{}
+	response    interface{}
+	values      interface{}
+	policyQuery bool
+	csvRequest  bool
+	queryParams []string
+	contentType string
+}
+
+type apiRoute struct {
+	pattern string
+	method  string
+	doc     routeDoc
+}
+
+type routeHandler func(context.Context, http.ResponseWriter, *http.Request)
+
+// addRoute 注册路由并记录其描述,OpenAPI文档由已注册的路由生成
+func (s *Master) addRoute(pattern, method string, handler routeHandler, doc routeDoc, filters ...engine.MiddleWareHandler) {
+	s.routeRegistry.AddHandler(pattern, method, handler, filters...)
+	s.documentRoute(pattern, method, doc)
+}
+
+// documentRoute 只记录路由描述,用于不经过路由注册的接口
+func (s *Master) documentRoute(pattern, method string, doc routeDoc) {
+	s.apiRoutes = append(s.apiRoutes, apiRoute{pattern: pattern, method: method, doc: doc})
+}
+
+func (s *Master) OpenAPI(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	s.openAPIOnce.Do(func() {
+		s.openAPIBlock, s.openAPIErr = json.Marshal(buildOpenAPI(s.apiRoutes))
+	})
+	if s.openAPIErr != nil {
+		res.WriteHeader(http.StatusExpectationFailed)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	_, _ = res.Write(s.openAPIBlock)
+}
+
+var pathParamReg = regexp.MustCompile(`:(\w+)`)
+
+func buildOpenAPI(routes []apiRoute) map[string]interface{} {
+	builder := &schemaBuilder{schemas: enumSchemas()}
+	paths := map[string]interface{}{}
+	for _, val := range routes {
+		pathVal := pathParamReg.ReplaceAllString(val.pattern, "{$1}")
+		pathItem, ok := paths[pathVal].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[pathVal] = pathItem
+		}
+
+		pathItem[strings.ToLower(val.method)] = builder.operation(val)
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "quickModbus",
+			"version":     "1.0.0",
+			"description": "Modbus主站REST接口。除/stream与/metrics外,所有接口均返回HTTP 200,处理结果由应答中的errorCode表示,从站返回的异常应答由exceptionCode表示",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": builder.schemas,
+		},
+	}
+}
+
+// errorCodes 应答中errorCode的取值,与cd及common中的定义保持一致
+var errorCodes = []struct {
+	code cd.ErrorCode
+	name string
+}{
+	{cd.Succeeded, "Succeeded"},
+	{cd.Warned, "Warned"},
+	{cd.NoExist, "NoExist"},
+	{cd.Failed, "Failed"},
+	{cd.IllegalParam, "IllegalParam"},
+	{cd.InvalidAuthority, "InvalidAuthority"},
+	{cd.Redirect, "Redirect"},
+	{cd.UnExpected, "UnExpected"},
+	{cd.Duplicated, "Duplicated"},
+	{common.RequestCanceled, "RequestCanceled"},
+	{common.RequestTimeout, "RequestTimeout"},
+}
+
+func errorCodeSchema() map[string]interface{} {
+	enumVal := []int{}
+	descVal := []string{}
+	for _, val := range errorCodes {
+		enumVal = append(enumVal, int(val.code))
+		descVal = append(descVal, fmt.Sprintf("%d:%s", val.code, val.name))
+	}
+
+	return map[string]interface{}{
+		"type":        "integer",
+		"enum":        enumVal,
+		"description": strings.Join(descVal, " "),
+	}
+}
+
+func enumSchemas() map[string]interface{} {
+	return map[string]interface{}{
+		"ValueType": map[string]interface{}{
+			"type":        "integer",
+			"enum":        []int{common.RawValue, common.BoolValue, common.Int16Value, common.UInt16Value, common.Int32Value, common.UInt32Value, common.Int64Value, common.UInt64Value, common.Float32Value, common.Float64Value},
+			"description": "0:Raw 1:Bool 2:Int16 3:UInt16 4:Int32 5:UInt32 6:Int64 7:UInt64 8:Float32 9:Float64",
+		},
+		"EndianType": map[string]interface{}{
+			"type":        "integer",
+			"enum":        []int{common.DefaultEndian, common.ABCDEndian, common.BADCEndian, common.CDABEndian, common.DCBAEndian, common.ABEndian, common.BAEndian},
+			"description": "0:Default 1:ABCD 2:BADC 3:CDAB 4:DCBA 5:AB 6:BA",
+		},
+		"DeviceType": map[string]interface{}{
+			"type":        "integer",
+			"enum":        []int{common.ModbusTcp, common.ModbusRTUOverTcp, common.ModbusASCIIOverTcp, common.ModbusRTU, common.ModbusASCII},
+			"description": "0:ModbusTcp 1:RTUOverTcp 2:ASCIIOverTcp 3:ModbusRTU 4:ModbusASCII,3和4的slaveAddr为串口设备路径",
+		},
+		"SlaveState": map[string]interface{}{
+			"type": "string",
+			"enum": []string{common.SlaveConnecting, common.SlaveOnline, common.SlaveDegraded, common.SlaveOffline},
+		},
+		"Result": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"errorCode": errorCodeSchema(),
+				"reason":    map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"errorCode"},
+		},
+	}
+}
+
+type enumField struct {
+	name string
+	kind reflect.Kind
+}
+
+// enumFields 按照json字段名及字段类型引用枚举定义
+var enumFields = map[string]enumField{
+	"valueType":      {"ValueType", reflect.Uint16},
+	"readValueType":  {"ValueType", reflect.Uint16},
+	"writeValueType": {"ValueType", reflect.Uint16},
+	"endianType":     {"EndianType", reflect.Uint8},
+	"deviceType":     {"DeviceType", reflect.Uint8},
+	"status":         {"SlaveState", reflect.String},
+}
+
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+func (s *schemaBuilder) operation(route apiRoute) map[string]interface{} {
+	ret := map[string]interface{}{
+		"summary": route.doc.summary,
+	}
+
+	params := []interface{}{}
+	for _, val := range pathParamReg.FindAllStringSubmatch(route.pattern, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     val[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	if route.doc.policyQuery {
+		for _, name := range []string{"timeout", "retries", "retryBackoff"} {
+			params = append(params, map[string]interface{}{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "integer", "minimum": 0},
+			})
+		}
+	}
+	for _, name := range route.doc.queryParams {
+		params = append(params, map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		ret["parameters"] = params
+	}
+
+	if route.doc.request != nil {
+		content := map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": s.ref(reflect.TypeOf(route.doc.request), nil),
+			},
+		}
+		if route.doc.csvRequest {
+			content["text/csv"] = map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string"},
+			}
+		}
+		ret["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	if route.doc.contentType != "" {
+		ret["responses"] = map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": route.doc.summary,
+				"content": map[string]interface{}{
+					route.doc.contentType: map[string]interface{}{
+						"schema": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		}
+		return ret
+	}
+
+	responseSchema := map[string]interface{}{"$ref": "#/components/schemas/Result"}
+	if route.doc.response != nil {
+		responseSchema = s.ref(reflect.TypeOf(route.doc.response), route.doc.values)
+	}
+	ret["responses"] = map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "errorCode为0时成功",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": responseSchema,
+				},
+			},
+		},
+	}
+	return ret
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref 结构类型登记到components中并返回引用,values用于替换interface{}类型的Values字段
+func (s *schemaBuilder) ref(typeVal reflect.Type, values interface{}) map[string]interface{} {
+	for typeVal.Kind() == reflect.Pointer {
+		typeVal = typeVal.Elem()
+	}
+	if typeVal.Kind() != reflect.Struct || typeVal == timeType {
+		return s.schema(typeVal, values)
+	}
+
+	name := typeVal.Name()
+	if name == "" {
+		return s.object(typeVal, values)
+	}
+	if _, ok := s.schemas[name]; !ok {
+		// 先占位,避免结构自引用时无限递归
+		s.schemas[name] = map[string]interface{}{}
+		s.schemas[name] = s.object(typeVal, values)
+	}
+
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func (s *schemaBuilder) object(typeVal reflect.Type, values interface{}) map[string]interface{} {
+	allOf := []interface{}{}
+	properties := map[string]interface{}{}
+	required := []string{}
+	for idx := 0; idx < typeVal.NumField(); idx++ {
+		field := typeVal.Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			allOf = append(allOf, s.ref(field.Type, nil))
+			continue
+		}
+
+		name, omitEmpty := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		if enumVal, ok := enumFields[name]; ok && enumVal.kind == field.Type.Kind() {
+			properties[name] = map[string]interface{}{"$ref": "#/components/schemas/" + enumVal.name}
+		} else if field.Type.Kind() == reflect.Interface && values != nil {
+			properties[name] = s.schema(reflect.TypeOf(values), nil)
+		} else {
+			properties[name] = s.ref(field.Type, nil)
+		}
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer && field.Type.Kind() != reflect.Interface {
+			required = append(required, name)
+		}
+	}
+
+	objectVal := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		objectVal["required"] = required
+	}
+	if len(allOf) == 0 {
+		return objectVal
+	}
+
+	return map[string]interface{}{"allOf": append(allOf, objectVal)}
+}
+
+func (s *schemaBuilder) schema(typeVal reflect.Type, values interface{}) map[string]interface{} {
+	if typeVal == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch typeVal.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "maximum": maxUint(typeVal)}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.ref(typeVal.Elem(), nil)}
+	case reflect.Pointer, reflect.Struct:
+		return s.ref(typeVal, values)
+	}
+
+	return map[string]interface{}{}
+}
+
+func maxUint(typeVal reflect.Type) uint64 {
+	return 1<<(typeVal.Bits()-1) - 1 + 1<<(typeVal.Bits()-1)
+}
+
+func jsonName(field reflect.StructField) (name string, omitEmpty bool) {
+	tagVal := field.Tag.Get("json")
+	items := strings.Split(tagVal, ",")
+	name = items[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, val := range items[1:] {
+		if val == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine/http"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func (s *Master) registerScanRoute() {
+	s.addRoute(common.ListScanGroup, engine.GET, s.ListScanGroup, routeDoc{summary: "查询所有扫描组", response: &common.ListScanGroupResponse{}})
+	s.addRoute(common.SaveScanGroup, engine.POST, s.SaveScanGroup, routeDoc{summary: "新增或者修改扫描组", request: &common.ScanGroup{}, response: &common.SaveScanGroupResponse{}})
+	s.addRoute(common.DeleteScanGroup, engine.DELETE, s.DeleteScanGroup, routeDoc{summary: "删除扫描组"}, &nameFilter{})
+}
+
+func (s *Master) ListScanGroup(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListScanGroupResponse{}
+	result.Groups = s.bizPtr.ListScanGroup()
+	result.ErrorCode = cd.Succeeded
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) SaveScanGroup(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.SaveScanGroupResponse{}
+	for {
+		param := &common.ScanGroup{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+
+		groupVal, groupErr := s.bizPtr.SaveScanGroup(param)
+		if groupErr != nil {
+			log.Errorf("save scan group failed, name:%s, error:%s", param.Name, groupErr.Error())
+			result.Result = *groupErr
+			break
+		}
+
+		result.Group = groupVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) DeleteScanGroup(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &cd.Result{}
+	for {
+		name := ctx.Value(nameContextKey).(string)
+		deleteErr := s.bizPtr.DeleteScanGroup(name)
+		if deleteErr != nil {
+			log.Errorf("delete scan group failed, name:%s, error:%s", name, deleteErr.Error())
+			result = deleteErr
+			break
+		}
+
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
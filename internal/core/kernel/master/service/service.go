@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"github.com/muidea/magicCommon/foundation/log"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	cd "github.com/muidea/magicCommon/def"
 	fn "github.com/muidea/magicCommon/foundation/net"
@@ -18,10 +20,17 @@ import (
 
 const slaveIDContextKey = "_slaveID"
 
+const nameContextKey = "_name"
+
 type Master struct {
 	routeRegistry engine.RouteRegistry
 
 	bizPtr *biz.Master
+
+	apiRoutes    []apiRoute
+	openAPIOnce  sync.Once
+	openAPIBlock []byte
+	openAPIErr   error
 }
 
 func New(bizPtr *biz.Master) *Master {
@@ -35,26 +44,37 @@ func (s *Master) BindRegistry(router engine.RouteRegistry) {
 }
 
 func (s *Master) RegisterRoute() {
-	s.routeRegistry.AddHandler(common.ConnectSlave, engine.POST, s.ConnectSlave)
-	s.routeRegistry.AddHandler(common.DisConnectSlave, engine.DELETE, s.DisConnectSlave, s)
-	s.routeRegistry.AddHandler(common.ReadCoils, engine.POST, s.ReadCoils, s)
-	s.routeRegistry.AddHandler(common.ReadDiscreteInputs, engine.POST, s.ReadDiscreteInputs, s)
-	s.routeRegistry.AddHandler(common.ReadHoldingRegisters, engine.POST, s.ReadHoldingRegisters, s)
-	s.routeRegistry.AddHandler(common.ReadInputRegisters, engine.POST, s.ReadInputRegisters, s)
-	s.routeRegistry.AddHandler(common.WriteSingleCoil, engine.POST, s.WriteSingleCoil, s)
-	s.routeRegistry.AddHandler(common.WriteSingleRegister, engine.POST, s.WriteSingleRegister, s)
-	s.routeRegistry.AddHandler(common.ReadExceptionStatus, engine.GET, s.ReadExceptionStatus, s)
-	s.routeRegistry.AddHandler(common.Diagnostics, engine.POST, s.Diagnostics, s)
-	s.routeRegistry.AddHandler(common.GetCommEventCounter, engine.GET, s.GetCommEventCounter, s)
-	s.routeRegistry.AddHandler(common.GetCommEventLog, engine.GET, s.GetCommEventLog, s)
-	s.routeRegistry.AddHandler(common.WriteMultipleCoils, engine.POST, s.WriteMultipleCoils, s)
-	s.routeRegistry.AddHandler(common.WriteMultipleRegisters, engine.POST, s.WriteMultipleRegisters, s)
-	s.routeRegistry.AddHandler(common.ReportSlaveID, engine.GET, s.ReportSlaveID, s)
-	s.routeRegistry.AddHandler(common.ReadFileRecord, engine.POST, s.ReadFileRecord, s)
-	s.routeRegistry.AddHandler(common.WriteFileRecord, engine.POST, s.WriteFileRecord, s)
-	s.routeRegistry.AddHandler(common.MaskWriteRegister, engine.POST, s.MaskWriteRegister, s)
-	s.routeRegistry.AddHandler(common.ReadWriteMultipleRegisters, engine.POST, s.ReadWriteMultipleRegisters, s)
-	s.routeRegistry.AddHandler(common.ReadFIFOQueue, engine.POST, s.ReadFIFOQueue, s)
+	s.addRoute(common.ListSlave, engine.GET, s.ListSlave, routeDoc{summary: "查询所有从站", response: &common.ListSlaveResponse{}})
+	s.addRoute(common.QuerySlave, engine.GET, s.QuerySlave, routeDoc{summary: "查询从站", response: &common.QuerySlaveResponse{}}, s)
+	s.addRoute(common.ConnectSlave, engine.POST, s.ConnectSlave, routeDoc{summary: "连接从站", request: &common.ConnectSlaveRequest{}, response: &common.ConnectSlaveResponse{}})
+	s.addRoute(common.ImportTemplate, engine.POST, s.ImportTemplate, routeDoc{summary: "导入设备模板,连接从站并保存其全部Tag", request: &common.DeviceTemplate{}, response: &common.ImportTemplateResponse{}, csvRequest: true})
+	s.addRoute(common.DisConnectSlave, engine.DELETE, s.DisConnectSlave, routeDoc{summary: "断开从站"}, s)
+	s.addRoute(common.ReadCoils, engine.POST, s.ReadCoils, routeDoc{summary: "读线圈(0x01)", request: &common.ReadCoilsRequest{}, response: &common.ReadCoilsResponse{}, values: []bool{}}, s)
+	s.addRoute(common.ReadDiscreteInputs, engine.POST, s.ReadDiscreteInputs, routeDoc{summary: "读离散输入(0x02)", request: &common.ReadDiscreteInputsRequest{}, response: &common.ReadDiscreteInputsResponse{}, values: []bool{}}, s)
+	s.addRoute(common.ReadHoldingRegisters, engine.POST, s.ReadHoldingRegisters, routeDoc{summary: "读保持寄存器(0x03)", request: &common.ReadHoldingRegistersRequest{}, response: &common.ReadHoldingRegistersResponse{}, values: []float64{}}, s)
+	s.addRoute(common.ReadInputRegisters, engine.POST, s.ReadInputRegisters, routeDoc{summary: "读输入寄存器(0x04)", request: &common.ReadReadInputRegistersRequest{}, response: &common.ReadReadInputRegistersResponse{}, values: []float64{}}, s)
+	s.addRoute(common.WriteSingleCoil, engine.POST, s.WriteSingleCoil, routeDoc{summary: "写单个线圈(0x05)", request: &common.WriteSingleCoilRequest{}, response: &common.WriteSingleCoilResponse{}}, s)
+	s.addRoute(common.WriteSingleRegister, engine.POST, s.WriteSingleRegister, routeDoc{summary: "写单个寄存器(0x06)", request: &common.WriteSingleRegisterRequest{}, response: &common.WriteSingleRegisterResponse{}}, s)
+	s.addRoute(common.ReadExceptionStatus, engine.GET, s.ReadExceptionStatus, routeDoc{summary: "读异常状态(0x07)", response: &common.ReadExceptionStatusResponse{}, policyQuery: true}, s)
+	s.addRoute(common.Diagnostics, engine.POST, s.Diagnostics, routeDoc{summary: "诊断(0x08)", request: &common.DiagnosticsRequest{}, response: &common.DiagnosticsResponse{}}, s)
+	s.addRoute(common.GetCommEventCounter, engine.GET, s.GetCommEventCounter, routeDoc{summary: "读通信事件计数(0x0B)", response: &common.GetCommEventCounterResponse{}, policyQuery: true}, s)
+	s.addRoute(common.GetCommEventLog, engine.GET, s.GetCommEventLog, routeDoc{summary: "读通信事件记录(0x0C)", response: &common.GetCommEventLogResponse{}, policyQuery: true}, s)
+	s.addRoute(common.WriteMultipleCoils, engine.POST, s.WriteMultipleCoils, routeDoc{summary: "写多个线圈(0x0F)", request: &common.WriteMultipleCoilsRequest{}, response: &common.WriteMultipleCoilsResponse{}}, s)
+	s.addRoute(common.WriteMultipleRegisters, engine.POST, s.WriteMultipleRegisters, routeDoc{summary: "写多个寄存器(0x10)", request: &common.WriteMultipleRegistersRequest{}, response: &common.WriteMultipleRegistersResponse{}}, s)
+	s.addRoute(common.ReportSlaveID, engine.GET, s.ReportSlaveID, routeDoc{summary: "报告从站ID(0x11)", response: &common.ReportSlaveIDResponse{}, policyQuery: true}, s)
+	s.addRoute(common.ReadFileRecord, engine.POST, s.ReadFileRecord, routeDoc{summary: "读文件记录(0x14)", request: &common.ReadFileRecordRequest{}, response: &common.ReadFileRecordResponse{}}, s)
+	s.addRoute(common.WriteFileRecord, engine.POST, s.WriteFileRecord, routeDoc{summary: "写文件记录(0x15)", request: &common.WriteFileRecordRequest{}, response: &common.WriteFileRecordResponse{}}, s)
+	s.addRoute(common.MaskWriteRegister, engine.POST, s.MaskWriteRegister, routeDoc{summary: "屏蔽写寄存器(0x16)", request: &common.MaskWriteRegisterRequest{}, response: &common.MaskWriteRegisterResponse{}}, s)
+	s.addRoute(common.ReadWriteMultipleRegisters, engine.POST, s.ReadWriteMultipleRegisters, routeDoc{summary: "读写多个寄存器(0x17)", request: &common.ReadWriteMultipleRegistersRequest{}, response: &common.ReadWriteMultipleRegistersResponse{}, values: []float64{}}, s)
+	s.addRoute(common.ReadFIFOQueue, engine.POST, s.ReadFIFOQueue, routeDoc{summary: "读FIFO队列(0x18)", request: &common.ReadFIFOQueueRequest{}, response: &common.ReadFIFOQueueResponse{}}, s)
+	s.addRoute(common.QuerySlaveStatus, engine.GET, s.QuerySlaveStatus, routeDoc{summary: "查询从站连接状态", response: &common.QuerySlaveStatusResponse{}}, s)
+	s.registerTagRoute()
+	s.registerScanRoute()
+
+	s.addRoute(common.Metrics, engine.GET, s.Metrics, routeDoc{summary: "输出Prometheus文本格式的指标", contentType: "text/plain"})
+	// StreamValue由模块通过RawHandlers提供,这里只记录描述
+	s.documentRoute(common.StreamValue, engine.GET, routeDoc{summary: "以Server-Sent-Events推送扫描组Tag的取值变化,参数格式错误时返回Result", queryParams: []string{"slaveID", "tag", "range", "deadband", "interval"}, contentType: "text/event-stream"})
+	s.routeRegistry.AddHandler(common.OpenAPI, engine.GET, s.OpenAPI)
 }
 
 func (s *Master) MiddleWareHandle(ctx engine.RequestContext, res http.ResponseWriter, req *http.Request) {
@@ -66,6 +86,96 @@ func (s *Master) MiddleWareHandle(ctx engine.RequestContext, res http.ResponseWr
 	ctx.Update(context.WithValue(ctx.Context(), slaveIDContextKey, pathItems[2]))
 }
 
+// nameFilter 从/tags/:name、/groups/:name等路由中提取名称
+type nameFilter struct{}
+
+func (s *nameFilter) MiddleWareHandle(ctx engine.RequestContext, res http.ResponseWriter, req *http.Request) {
+	pathItems := strings.Split(req.URL.Path, "/")
+	if len(pathItems) < 3 {
+		return
+	}
+
+	ctx.Update(context.WithValue(ctx.Context(), nameContextKey, pathItems[2]))
+}
+
+// requestContext 路由上下文不感知客户端断开,合并req.Context()以便取消对从站的请求
+func requestContext(ctx context.Context, req *http.Request) (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(req.Context(), cancel)
+	return reqCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// parseRequestPolicy GET请求通过query参数传递超时与重试参数
+func parseRequestPolicy(req *http.Request) (ret *common.RequestPolicy, err error) {
+	policy := &common.RequestPolicy{}
+	queryVal := req.URL.Query()
+	if strVal := queryVal.Get("timeout"); strVal != "" {
+		policy.Timeout, err = strconv.Atoi(strVal)
+		if err != nil {
+			return
+		}
+	}
+	if strVal := queryVal.Get("retries"); strVal != "" {
+		retries, retriesErr := strconv.Atoi(strVal)
+		if retriesErr != nil {
+			err = retriesErr
+			return
+		}
+		policy.Retries = &retries
+	}
+	if strVal := queryVal.Get("retryBackoff"); strVal != "" {
+		policy.RetryBackoff, err = strconv.Atoi(strVal)
+		if err != nil {
+			return
+		}
+	}
+
+	ret = policy
+	return
+}
+
+func (s *Master) ListSlave(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListSlaveResponse{}
+	result.Slaves = s.bizPtr.ListSlave()
+	result.ErrorCode = cd.Succeeded
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) QuerySlave(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QuerySlaveResponse{}
+	for {
+		slaveID := ctx.Value(slaveIDContextKey).(string)
+		slaveVal, slaveErr := s.bizPtr.QuerySlave(slaveID)
+		if slaveErr != nil {
+			log.Errorf("query slave failed, slaveID:%s, error:%s", slaveID, slaveErr.Error())
+			result.Result = *slaveErr
+			break
+		}
+
+		result.Slave = slaveVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
 func (s *Master) ConnectSlave(ctx context.Context, res http.ResponseWriter, req *http.Request) {
 	result := &common.ConnectSlaveResponse{}
 	for {
@@ -77,7 +187,7 @@ func (s *Master) ConnectSlave(ctx context.Context, res http.ResponseWriter, req
 			break
 		}
 
-		slaveID, slaveErr := s.bizPtr.ConnectSlave(param.SlaveAddr, param.DeviceID, param.DeviceType, param.EndianType)
+		slaveID, slaveErr := s.bizPtr.ConnectSlave(param.SlaveAddr, param.DeviceID, param.DeviceType, param.EndianType, param.SerialConfig, param.RequestPolicy, param.MaxTransactions, param.ReadCoalesce)
 		if slaveErr != nil {
 			log.Errorf("connect slave failed, slaveAddr:%s, deviceID:%v, deviceType:%v, error:%s", param.SlaveAddr, param.DeviceID, param.DeviceType, slaveErr.Error())
 			result.Result = *slaveErr
@@ -123,6 +233,9 @@ func (s *Master) DisConnectSlave(ctx context.Context, res http.ResponseWriter, r
 }
 
 func (s *Master) ReadCoils(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadCoilsResponse{}
 	for {
 		param := &common.ReadCoilsRequest{}
@@ -133,7 +246,7 @@ func (s *Master) ReadCoils(ctx context.Context, res http.ResponseWriter, req *ht
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readVal, readExCode, readErr := s.bizPtr.ReadCoils(slaveID, param.Address, param.Count)
+		readVal, readExCode, readErr := s.bizPtr.ReadCoils(ctx, slaveID, &param.RequestPolicy, param.Address, param.Count)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("read coils failed, slaveID:%s, address:%d, count:%d, exCode:%v, error:%s", slaveID, param.Address, param.Count, readExCode, readErr.Error())
@@ -156,6 +269,9 @@ func (s *Master) ReadCoils(ctx context.Context, res http.ResponseWriter, req *ht
 }
 
 func (s *Master) ReadDiscreteInputs(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadDiscreteInputsResponse{}
 	for {
 		param := &common.ReadDiscreteInputsRequest{}
@@ -166,7 +282,7 @@ func (s *Master) ReadDiscreteInputs(ctx context.Context, res http.ResponseWriter
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readVal, readExCode, readErr := s.bizPtr.ReadDiscreteInputs(slaveID, param.Address, param.Count)
+		readVal, readExCode, readErr := s.bizPtr.ReadDiscreteInputs(ctx, slaveID, &param.RequestPolicy, param.Address, param.Count)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("read discrete inputs failed, slaveID:%s, address:%d, count:%d, exCode:%v, error:%s", slaveID, param.Address, param.Count, readExCode, readErr.Error())
@@ -189,6 +305,9 @@ func (s *Master) ReadDiscreteInputs(ctx context.Context, res http.ResponseWriter
 }
 
 func (s *Master) ReadHoldingRegisters(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadHoldingRegistersResponse{}
 	for {
 		param := &common.ReadHoldingRegistersRequest{}
@@ -199,7 +318,7 @@ func (s *Master) ReadHoldingRegisters(ctx context.Context, res http.ResponseWrit
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readVal, readExCode, readErr := s.bizPtr.ReadHoldingRegisters(slaveID, param.Address, param.Count, param.ValueType, param.EndianType)
+		readVal, readExCode, readErr := s.bizPtr.ReadHoldingRegisters(ctx, slaveID, &param.RequestPolicy, param.Address, param.Count, param.ValueType, param.EndianType)
 		result.ExceptionCode = readExCode
 
 		if readErr != nil {
@@ -223,6 +342,9 @@ func (s *Master) ReadHoldingRegisters(ctx context.Context, res http.ResponseWrit
 }
 
 func (s *Master) ReadInputRegisters(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadReadInputRegistersResponse{}
 	for {
 		param := &common.ReadReadInputRegistersRequest{}
@@ -233,7 +355,7 @@ func (s *Master) ReadInputRegisters(ctx context.Context, res http.ResponseWriter
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readVal, readExCode, readErr := s.bizPtr.ReadInputRegisters(slaveID, param.Address, param.Count, param.ValueType, param.EndianType)
+		readVal, readExCode, readErr := s.bizPtr.ReadInputRegisters(ctx, slaveID, &param.RequestPolicy, param.Address, param.Count, param.ValueType, param.EndianType)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("read input registers failed, slaveID:%s, address:%d, count:%d, valueType:%d, endianType:%d, exCode:%v, error:%s", slaveID, param.Address, param.Count, param.ValueType, param.EndianType, readExCode, readErr.Error())
@@ -256,6 +378,9 @@ func (s *Master) ReadInputRegisters(ctx context.Context, res http.ResponseWriter
 }
 
 func (s *Master) WriteSingleCoil(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.WriteSingleCoilResponse{}
 	for {
 		param := &common.WriteSingleCoilRequest{}
@@ -266,7 +391,7 @@ func (s *Master) WriteSingleCoil(ctx context.Context, res http.ResponseWriter, r
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		writeExCode, writeErr := s.bizPtr.WriteSingleCoil(slaveID, param.Address, param.Value)
+		writeExCode, writeErr := s.bizPtr.WriteSingleCoil(ctx, slaveID, &param.RequestPolicy, param.Address, param.Value)
 		result.ExceptionCode = writeExCode
 		if writeErr != nil {
 			log.Errorf("WriteSingleCoil failed, slaveID:%s, address:%d, exCode:%v, error:%s", slaveID, param.Address, writeExCode, writeErr.Error())
@@ -288,6 +413,9 @@ func (s *Master) WriteSingleCoil(ctx context.Context, res http.ResponseWriter, r
 }
 
 func (s *Master) WriteSingleRegister(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.WriteSingleRegisterResponse{}
 	for {
 		param := &common.WriteSingleRegisterRequest{}
@@ -298,7 +426,7 @@ func (s *Master) WriteSingleRegister(ctx context.Context, res http.ResponseWrite
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		writeExCode, writeErr := s.bizPtr.WriteSingleRegister(slaveID, param.Address, param.Value, param.EndianType)
+		writeExCode, writeErr := s.bizPtr.WriteSingleRegister(ctx, slaveID, &param.RequestPolicy, param.Address, param.Value, param.EndianType)
 		result.ExceptionCode = writeExCode
 		if writeErr != nil {
 			log.Errorf("WriteSingleRegister failed, slaveID:%s, address:%d, exCode:%v, error:%s", slaveID, param.Address, writeExCode, writeErr.Error())
@@ -320,10 +448,19 @@ func (s *Master) WriteSingleRegister(ctx context.Context, res http.ResponseWrite
 }
 
 func (s *Master) ReadExceptionStatus(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadExceptionStatusResponse{}
 	for {
+		param, err := parseRequestPolicy(req)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readStatus, readExCode, readErr := s.bizPtr.ReadExceptionStatus(slaveID)
+		readStatus, readExCode, readErr := s.bizPtr.ReadExceptionStatus(ctx, slaveID, param)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("ReadExceptionStatus failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -346,6 +483,9 @@ func (s *Master) ReadExceptionStatus(ctx context.Context, res http.ResponseWrite
 }
 
 func (s *Master) Diagnostics(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.DiagnosticsResponse{}
 	for {
 		param := &common.DiagnosticsRequest{}
@@ -356,7 +496,7 @@ func (s *Master) Diagnostics(ctx context.Context, res http.ResponseWriter, req *
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		retVal, retExCode, retErr := s.bizPtr.Diagnostics(slaveID, param.Function, param.Value)
+		retVal, retExCode, retErr := s.bizPtr.Diagnostics(ctx, slaveID, &param.RequestPolicy, param.Function, param.Value)
 		result.ExceptionCode = retExCode
 		if retErr != nil {
 			log.Errorf("Diagnostics failed, slaveID:%s, exCode:%v, error:%s", slaveID, retExCode, retErr.Error())
@@ -379,10 +519,19 @@ func (s *Master) Diagnostics(ctx context.Context, res http.ResponseWriter, req *
 }
 
 func (s *Master) GetCommEventCounter(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.GetCommEventCounterResponse{}
 	for {
+		param, err := parseRequestPolicy(req)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readStatus, readEventCount, readExCode, readErr := s.bizPtr.GetCommEventCounter(slaveID)
+		readStatus, readEventCount, readExCode, readErr := s.bizPtr.GetCommEventCounter(ctx, slaveID, param)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("GetCommEventCounter failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -406,10 +555,19 @@ func (s *Master) GetCommEventCounter(ctx context.Context, res http.ResponseWrite
 }
 
 func (s *Master) GetCommEventLog(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.GetCommEventLogResponse{}
 	for {
+		param, err := parseRequestPolicy(req)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readStatus, readEventCount, readMessageCount, readEvents, readExCode, readErr := s.bizPtr.GetCommEventLog(slaveID)
+		readStatus, readEventCount, readMessageCount, readEvents, readExCode, readErr := s.bizPtr.GetCommEventLog(ctx, slaveID, param)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("GetCommEventLog failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -435,6 +593,9 @@ func (s *Master) GetCommEventLog(ctx context.Context, res http.ResponseWriter, r
 }
 
 func (s *Master) WriteMultipleCoils(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.WriteMultipleCoilsResponse{}
 	for {
 		param := &common.WriteMultipleCoilsRequest{}
@@ -445,7 +606,7 @@ func (s *Master) WriteMultipleCoils(ctx context.Context, res http.ResponseWriter
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		writeExCode, writeErr := s.bizPtr.WriteMultipleCoils(slaveID, param.Address, param.Values)
+		writeExCode, writeErr := s.bizPtr.WriteMultipleCoils(ctx, slaveID, &param.RequestPolicy, param.Address, param.Values)
 		result.ExceptionCode = writeExCode
 		if writeErr != nil {
 			log.Errorf("WriteMultipleCoils failed, slaveID:%s, address:%d, exCode:%v, error:%s", slaveID, param.Address, writeExCode, writeErr.Error())
@@ -467,6 +628,9 @@ func (s *Master) WriteMultipleCoils(ctx context.Context, res http.ResponseWriter
 }
 
 func (s *Master) WriteMultipleRegisters(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.WriteMultipleRegistersResponse{}
 	for {
 		param := &common.WriteMultipleRegistersRequest{}
@@ -477,7 +641,7 @@ func (s *Master) WriteMultipleRegisters(ctx context.Context, res http.ResponseWr
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		writeExCode, writeErr := s.bizPtr.WriteMultipleRegisters(slaveID, param.Address, param.Values, param.ValueType, param.EndianType)
+		writeExCode, writeErr := s.bizPtr.WriteMultipleRegisters(ctx, slaveID, &param.RequestPolicy, param.Address, param.Values, param.ValueType, param.EndianType)
 		result.ExceptionCode = writeExCode
 		if writeErr != nil {
 			log.Errorf("WriteMultipleRegisters failed, slaveID:%s, address:%d, exCode:%v, error:%s", slaveID, param.Address, writeExCode, writeErr.Error())
@@ -499,10 +663,19 @@ func (s *Master) WriteMultipleRegisters(ctx context.Context, res http.ResponseWr
 }
 
 func (s *Master) ReportSlaveID(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReportSlaveIDResponse{}
 	for {
+		param, err := parseRequestPolicy(req)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readSlaveInfo, readExCode, readErr := s.bizPtr.ReportSlaveID(slaveID)
+		readSlaveInfo, readExCode, readErr := s.bizPtr.ReportSlaveID(ctx, slaveID, param)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("GetCommEventLog failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -525,6 +698,9 @@ func (s *Master) ReportSlaveID(ctx context.Context, res http.ResponseWriter, req
 }
 
 func (s *Master) ReadFileRecord(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadFileRecordResponse{}
 	for {
 		param := &common.ReadFileRecordRequest{}
@@ -535,7 +711,7 @@ func (s *Master) ReadFileRecord(ctx context.Context, res http.ResponseWriter, re
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readContent, readExCode, readErr := s.bizPtr.ReadFileRecord(slaveID, param.Items)
+		readContent, readExCode, readErr := s.bizPtr.ReadFileRecord(ctx, slaveID, &param.RequestPolicy, param.Items)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("ReadFileRecord failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -558,6 +734,9 @@ func (s *Master) ReadFileRecord(ctx context.Context, res http.ResponseWriter, re
 }
 
 func (s *Master) WriteFileRecord(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.WriteFileRecordResponse{}
 	for {
 		param := &common.WriteFileRecordRequest{}
@@ -568,7 +747,7 @@ func (s *Master) WriteFileRecord(ctx context.Context, res http.ResponseWriter, r
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readExCode, readErr := s.bizPtr.WriteFileRecord(slaveID, param.Items)
+		readExCode, readErr := s.bizPtr.WriteFileRecord(ctx, slaveID, &param.RequestPolicy, param.Items)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("ReadFileRecord failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -590,6 +769,9 @@ func (s *Master) WriteFileRecord(ctx context.Context, res http.ResponseWriter, r
 }
 
 func (s *Master) MaskWriteRegister(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.MaskWriteRegisterResponse{}
 	for {
 		param := &common.MaskWriteRegisterRequest{}
@@ -600,7 +782,7 @@ func (s *Master) MaskWriteRegister(ctx context.Context, res http.ResponseWriter,
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		writeExCode, writeErr := s.bizPtr.MaskWriteRegister(slaveID, param.Address, param.AndMask, param.OrMask)
+		writeExCode, writeErr := s.bizPtr.MaskWriteRegister(ctx, slaveID, &param.RequestPolicy, param.Address, param.AndMask, param.OrMask)
 		result.ExceptionCode = writeExCode
 		if writeErr != nil {
 			log.Errorf("MaskWriteRegister failed, slaveID:%s, address:%d, exCode:%v, error:%s", slaveID, param.Address, writeExCode, writeErr.Error())
@@ -622,6 +804,9 @@ func (s *Master) MaskWriteRegister(ctx context.Context, res http.ResponseWriter,
 }
 
 func (s *Master) ReadWriteMultipleRegisters(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadWriteMultipleRegistersResponse{}
 	for {
 		param := &common.ReadWriteMultipleRegistersRequest{}
@@ -632,7 +817,7 @@ func (s *Master) ReadWriteMultipleRegisters(ctx context.Context, res http.Respon
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		retValues, retExCode, retErr := s.bizPtr.ReadWriteMultipleRegisters(slaveID, param.ReadAddress, param.ReadCount, param.ReadValueType, param.WriteAddress, param.WriteValues, param.WriteValueType, param.EndianType)
+		retValues, retExCode, retErr := s.bizPtr.ReadWriteMultipleRegisters(ctx, slaveID, &param.RequestPolicy, param.ReadAddress, param.ReadCount, param.ReadValueType, param.WriteAddress, param.WriteValues, param.WriteValueType, param.EndianType)
 		result.ExceptionCode = retExCode
 		if retErr != nil {
 			log.Errorf("ReadWriteMultipleRegisters failed, slaveID:%s, exCode:%v, error:%s", slaveID, retExCode, retErr.Error())
@@ -655,6 +840,9 @@ func (s *Master) ReadWriteMultipleRegisters(ctx context.Context, res http.Respon
 }
 
 func (s *Master) ReadFIFOQueue(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
 	result := &common.ReadFIFOQueueResponse{}
 	for {
 		param := &common.ReadFIFOQueueRequest{}
@@ -665,7 +853,7 @@ func (s *Master) ReadFIFOQueue(ctx context.Context, res http.ResponseWriter, req
 			break
 		}
 		slaveID := ctx.Value(slaveIDContextKey).(string)
-		readContent, readExCode, readErr := s.bizPtr.ReadFIFOQueue(slaveID, param.Address)
+		readContent, readExCode, readErr := s.bizPtr.ReadFIFOQueue(ctx, slaveID, &param.RequestPolicy, param.Address)
 		result.ExceptionCode = readExCode
 		if readErr != nil {
 			log.Errorf("ReadFIFOQueue failed, slaveID:%s, exCode:%v, error:%s", slaveID, readExCode, readErr.Error())
@@ -686,3 +874,28 @@ func (s *Master) ReadFIFOQueue(ctx context.Context, res http.ResponseWriter, req
 
 	res.WriteHeader(http.StatusExpectationFailed)
 }
+
+func (s *Master) QuerySlaveStatus(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QuerySlaveStatusResponse{}
+	for {
+		slaveID := ctx.Value(slaveIDContextKey).(string)
+		statusVal, statusErr := s.bizPtr.QuerySlaveStatus(slaveID)
+		if statusErr != nil {
+			log.Errorf("query slave status failed, slaveID:%s, error:%s", slaveID, statusErr.Error())
+			result.Result = *statusErr
+			break
+		}
+
+		result.SlaveStatus = *statusVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// keepAliveInterval 没有取值变化时定期发送注释行,避免连接被中间代理断开
+const keepAliveInterval = 15 * time.Second
+
+// streamPublisher 按照订阅的死区与最小推送间隔过滤取值,lastMap为每个Tag最近一次推送的取值
+type streamPublisher struct {
+	res     http.ResponseWriter
+	flusher http.Flusher
+	param   *common.StreamRequest
+
+	lastMap    map[string]*common.TagValue
+	pendingMap map[string]*common.TagValue
+}
+
+// offer 未设置最小推送间隔时立即推送,否则等待下一次flush
+func (s *streamPublisher) offer(tagVal *common.TagValue) error {
+	if !common.ValueChanged(s.lastMap[tagVal.Name], tagVal, s.param.Deadband, 0) {
+		delete(s.pendingMap, tagVal.Name)
+		return nil
+	}
+
+	if s.param.Interval == 0 {
+		return s.send(tagVal)
+	}
+
+	s.pendingMap[tagVal.Name] = tagVal
+	return nil
+}
+
+func (s *streamPublisher) flush() error {
+	names := []string{}
+	for key := range s.pendingMap {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, val := range names {
+		tagVal := s.pendingMap[val]
+		delete(s.pendingMap, val)
+		err := s.send(tagVal)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *streamPublisher) send(tagVal *common.TagValue) error {
+	block, err := json.Marshal(tagVal)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.res, "event: %s\ndata: %s\n\n", common.ValueEvent, block)
+	if err != nil {
+		return err
+	}
+
+	s.lastMap[tagVal.Name] = tagVal
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *streamPublisher) keepAlive() error {
+	_, err := fmt.Fprint(s.res, ": keepalive\n\n")
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+/*
+StreamValue 推送订阅的Tag的取值变化,需要直接使用http.ResponseWriter以便及时Flush,
+因此不经过路由注册,由模块通过RawHandlers提供
+*/
+func (s *Master) StreamValue(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok || req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	param, err := common.ParseStreamRequest(req.URL.Query())
+	if err != nil {
+		result := cd.NewError(cd.IllegalParam, err.Error())
+		block, _ := json.Marshal(result)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	id, valueCh, snapshot := s.bizPtr.SubscribeValue(param.Match)
+	defer s.bizPtr.UnsubscribeValue(id)
+
+	publisher := &streamPublisher{
+		res:        res,
+		flusher:    flusher,
+		param:      param,
+		lastMap:    map[string]*common.TagValue{},
+		pendingMap: map[string]*common.TagValue{},
+	}
+	for _, val := range snapshot {
+		err = publisher.send(val)
+		if err != nil {
+			return
+		}
+	}
+
+	var flushCh <-chan time.Time
+	if param.Interval > 0 {
+		flushTicker := time.NewTicker(time.Duration(param.Interval) * time.Millisecond)
+		defer flushTicker.Stop()
+		flushCh = flushTicker.C
+	}
+	keepAliveTicker := time.NewTicker(keepAliveInterval)
+	defer keepAliveTicker.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case tagVal, ok := <-valueCh:
+			if !ok {
+				return
+			}
+			err = publisher.offer(tagVal)
+		case <-flushCh:
+			err = publisher.flush()
+		case <-keepAliveTicker.C:
+			err = publisher.keepAlive()
+		}
+		if err != nil {
+			log.Warnf("stream value failed, remoteAddr:%s, error:%s", req.RemoteAddr, err.Error())
+			return
+		}
+	}
+}
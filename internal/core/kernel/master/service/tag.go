@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine/http"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func (s *Master) registerTagRoute() {
+	filter := &nameFilter{}
+	s.addRoute(common.ListTag, engine.GET, s.ListTag, routeDoc{summary: "查询所有Tag", response: &common.ListTagResponse{}})
+	s.addRoute(common.SaveTag, engine.POST, s.SaveTag, routeDoc{summary: "新增或者替换Tag", request: &common.Tag{}, response: &common.SaveTagResponse{}})
+	s.addRoute(common.ReadTags, engine.POST, s.ReadTags, routeDoc{summary: "批量读取Tag", request: &common.ReadTagsRequest{}, response: &common.ReadTagsResponse{}})
+	s.addRoute(common.QueryTag, engine.GET, s.QueryTag, routeDoc{summary: "查询Tag", response: &common.QueryTagResponse{}}, filter)
+	s.addRoute(common.DeleteTag, engine.DELETE, s.DeleteTag, routeDoc{summary: "删除Tag"}, filter)
+	s.addRoute(common.ReadTag, engine.GET, s.ReadTag, routeDoc{summary: "读取Tag", response: &common.ReadTagResponse{}, policyQuery: true}, filter)
+	s.addRoute(common.WriteTag, engine.POST, s.WriteTag, routeDoc{summary: "写入Tag", request: &common.WriteTagRequest{}, response: &common.WriteTagResponse{}}, filter)
+	s.addRoute(common.QueryTagHistory, engine.GET, s.QueryTagHistory, routeDoc{summary: "查询Tag的历史取值,agg为min、max、avg时按照step降采样", response: &common.QueryTagHistoryResponse{}, queryParams: []string{"from", "to", "agg", "step"}}, filter)
+}
+
+func (s *Master) ListTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListTagResponse{}
+	result.Tags = s.bizPtr.ListTag()
+	result.ErrorCode = cd.Succeeded
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) QueryTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryTagResponse{}
+	for {
+		name := ctx.Value(nameContextKey).(string)
+		tagVal, tagErr := s.bizPtr.QueryTag(name)
+		if tagErr != nil {
+			log.Errorf("query tag failed, name:%s, error:%s", name, tagErr.Error())
+			result.Result = *tagErr
+			break
+		}
+
+		result.Tag = tagVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) SaveTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.SaveTagResponse{}
+	for {
+		param := &common.Tag{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+
+		tagVal, tagErr := s.bizPtr.SaveTag(param)
+		if tagErr != nil {
+			log.Errorf("save tag failed, name:%s, error:%s", param.Name, tagErr.Error())
+			result.Result = *tagErr
+			break
+		}
+
+		result.Tag = tagVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) DeleteTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &cd.Result{}
+	for {
+		name := ctx.Value(nameContextKey).(string)
+		deleteErr := s.bizPtr.DeleteTag(name)
+		if deleteErr != nil {
+			log.Errorf("delete tag failed, name:%s, error:%s", name, deleteErr.Error())
+			result = deleteErr
+			break
+		}
+
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) ReadTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
+	result := &common.ReadTagResponse{}
+	for {
+		param, err := parseRequestPolicy(req)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+		name := ctx.Value(nameContextKey).(string)
+		readVal, readExCode, readErr := s.bizPtr.ReadTag(ctx, param, name)
+		result.ExceptionCode = readExCode
+		if readErr != nil {
+			log.Errorf("read tag failed, name:%s, exCode:%v, error:%s", name, readExCode, readErr.Error())
+			result.Result = *readErr
+			break
+		}
+
+		result.Value = readVal
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) ReadTags(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
+	result := &common.ReadTagsResponse{}
+	for {
+		param := &common.ReadTagsRequest{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+
+		result.Values = s.bizPtr.ReadTags(ctx, &param.RequestPolicy, param.Names)
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) WriteTag(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := requestContext(ctx, req)
+	defer cancel()
+
+	result := &common.WriteTagResponse{}
+	for {
+		param := &common.WriteTagRequest{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+		name := ctx.Value(nameContextKey).(string)
+		writeExCode, writeErr := s.bizPtr.WriteTag(ctx, &param.RequestPolicy, name, param.Value)
+		result.ExceptionCode = writeExCode
+		if writeErr != nil {
+			log.Errorf("write tag failed, name:%s, value:%v, exCode:%v, error:%s", name, param.Value, writeExCode, writeErr.Error())
+			result.Result = *writeErr
+			break
+		}
+
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
+
+func (s *Master) QueryTagHistory(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryTagHistoryResponse{}
+	for {
+		name := ctx.Value(nameContextKey).(string)
+		param, paramErr := common.ParseHistoryRequest(req.URL.Query())
+		if paramErr != nil {
+			log.Errorf("illegal history request, name:%s, error:%s", name, paramErr.Error())
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = paramErr.Error()
+			break
+		}
+
+		points, pointsErr := s.bizPtr.QueryTagHistory(name, param)
+		if pointsErr != nil {
+			log.Errorf("query tag history failed, name:%s, error:%s", name, pointsErr.Error())
+			result.Result = *pointsErr
+			break
+		}
+
+		result.Name = name
+		result.Agg = param.Agg
+		if param.Agg != "" {
+			result.Step = param.Step.String()
+		}
+		result.Points = points
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+const maxTemplateSize = 4 << 20
+
+// ImportTemplate Content-Type为text/csv时按照CSV模板解析,否则按照JSON模板解析
+func (s *Master) ImportTemplate(ctx context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ImportTemplateResponse{}
+	for {
+		data, err := io.ReadAll(io.LimitReader(req.Body, maxTemplateSize+1))
+		if err != nil || len(data) > maxTemplateSize {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "invalid param"
+			break
+		}
+
+		templatePtr, templateErr := common.ParseDeviceTemplate(data, common.TemplateFormat(req.Header.Get("Content-Type")))
+		if templateErr != nil {
+			log.Errorf("import template failed, error:%s", templateErr.Error())
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = templateErr.Error()
+			break
+		}
+
+		slaveID, tags, importErr := s.bizPtr.ImportTemplate(templatePtr)
+		if importErr != nil {
+			log.Errorf("import template failed, slaveAddr:%s, deviceID:%v, error:%s", templatePtr.SlaveAddr, templatePtr.DeviceID, importErr.Error())
+			result.Result = *importErr
+			break
+		}
+
+		result.SlaveID = slaveID
+		result.Tags = tags
+		result.ErrorCode = cd.Succeeded
+		break
+	}
+
+	block, err := json.Marshal(result)
+	if err == nil {
+		_, _ = res.Write(block)
+		return
+	}
+
+	res.WriteHeader(http.StatusExpectationFailed)
+}
@@ -0,0 +1,209 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/quickModbus/internal/config"
+	"github.com/muidea/quickModbus/internal/core/base/biz"
+	"github.com/muidea/quickModbus/pkg/common"
+	mq "github.com/muidea/quickModbus/pkg/mqtt"
+)
+
+// reconnectInterval 与Broker断开后的重连检查间隔
+const reconnectInterval = 5 * time.Second
+
+// publishQueueSize 待发布取值的队列长度,Broker处理不过来时丢弃新的取值
+const publishQueueSize = 1024
+
+/*
+Bridge 将扫描组的取值变化(TagChangeEvent)以JSON发布到ValueTopic,
+CommandTopic上收到的WriteCommand通过WriteCommandEvent交由master执行,执行结果发布到ResponseTopic
+*/
+type Bridge struct {
+	biz.Base
+
+	cfg    *config.MQTTConfig
+	client *mq.Client
+	broker *mq.Broker
+
+	publishCh chan *mq.Message
+	closeCh   chan struct{}
+
+	lock      sync.Mutex
+	connected bool
+	closed    bool
+}
+
+func NewBridge(cfg *config.MQTTConfig, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) *Bridge {
+	return &Bridge{
+		Base: biz.New(common.MQTTModule, eventHub, backgroundRoutine),
+		cfg:  cfg,
+		client: mq.NewClient(mq.Options{
+			BrokerAddr: cfg.BrokerAddr,
+			ClientID:   cfg.ClientID,
+			Username:   cfg.Username,
+			Password:   cfg.Password,
+			KeepAlive:  time.Duration(cfg.KeepAlive) * time.Second,
+		}),
+		publishCh: make(chan *mq.Message, publishQueueSize),
+		closeCh:   make(chan struct{}),
+	}
+}
+
+func (s *Bridge) Run() {
+	if s.cfg.EmbeddedBroker != "" {
+		// 先完成监听,保证首次连接时内嵌Broker已经可用
+		listener, err := net.Listen("tcp", s.cfg.EmbeddedBroker)
+		if err != nil {
+			log.Errorf("start mqtt broker failed, addr:%s, error:%s", s.cfg.EmbeddedBroker, err.Error())
+		} else {
+			s.broker = mq.NewBroker()
+			go s.broker.Serve(listener)
+		}
+	}
+
+	err := s.client.Subscribe(s.cfg.CommandTopic, s.cfg.QoS, s.onCommand)
+	if err != nil {
+		log.Errorf("subscribe mqtt command topic failed, topic:%s, error:%s", s.cfg.CommandTopic, err.Error())
+	}
+
+	s.SubscribeFunc(common.TagChangeEvent+"/+", s.onTagChange)
+	go s.publishLoop()
+
+	s.Timer(reconnectInterval, 0, s.ensureConnect)
+	s.AsyncTask(s.ensureConnect)
+}
+
+func (s *Bridge) Close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.closeCh)
+	s.lock.Unlock()
+
+	s.client.Close()
+	if s.broker != nil {
+		s.broker.Close()
+	}
+}
+
+// ensureConnect 只在连接状态变化时记录日志,避免Broker长时间不可用时刷屏
+func (s *Bridge) ensureConnect() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+
+	err := s.client.Connect()
+	if err != nil {
+		if s.connected {
+			log.Warnf("connect mqtt broker failed, brokerAddr:%s, error:%s", s.cfg.BrokerAddr, err.Error())
+		}
+		s.connected = false
+		return
+	}
+
+	if !s.connected {
+		log.Infof("connect mqtt broker ok, brokerAddr:%s", s.cfg.BrokerAddr)
+	}
+	s.connected = true
+}
+
+func (s *Bridge) valueTopic(tagVal *common.TagValue, header event.Values) string {
+	replacer := strings.NewReplacer(
+		"{slaveID}", header.GetString("slaveID"),
+		"{scanGroup}", header.GetString("scanGroup"),
+		"{tag}", tagVal.Name,
+	)
+	return replacer.Replace(s.cfg.ValueTopic)
+}
+
+func (s *Bridge) onTagChange(ev event.Event, result event.Result) {
+	tagVal, ok := ev.Data().(*common.TagValue)
+	if !ok {
+		return
+	}
+
+	payload, err := json.Marshal(tagVal)
+	if err != nil {
+		return
+	}
+
+	msg := &mq.Message{Topic: s.valueTopic(tagVal, ev.Header()), Payload: payload, QoS: s.cfg.QoS, Retain: s.cfg.Retain}
+	select {
+	case s.publishCh <- msg:
+	default:
+		log.Warnf("publish mqtt value failed, topic:%s, queue is full", msg.Topic)
+	}
+}
+
+// publishLoop 按照取值变化的顺序逐个发布,Broker断开期间的取值被丢弃
+func (s *Bridge) publishLoop() {
+	for {
+		select {
+		case <-s.closeCh:
+			return
+		case msg := <-s.publishCh:
+			if !s.client.IsConnected() {
+				continue
+			}
+
+			err := s.client.Publish(msg)
+			if err != nil {
+				log.Warnf("publish mqtt value failed, topic:%s, error:%s", msg.Topic, err.Error())
+			}
+		}
+	}
+}
+
+// onCommand 在客户端的读取routine中调用,写入从站可能耗时较长,放到后台任务中执行
+func (s *Bridge) onCommand(msg *mq.Message) {
+	s.AsyncTask(func() {
+		cmdPtr := &common.WriteCommand{}
+		result := s.executeCommand(msg.Payload, cmdPtr)
+		result.ID = cmdPtr.ID
+
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return
+		}
+		err = s.client.Publish(&mq.Message{Topic: s.cfg.ResponseTopic, Payload: payload, QoS: s.cfg.QoS})
+		if err != nil {
+			log.Warnf("publish mqtt command response failed, id:%s, error:%s", result.ID, err.Error())
+		}
+	})
+}
+
+func (s *Bridge) executeCommand(payload []byte, cmdPtr *common.WriteCommand) (ret *common.WriteCommandResult) {
+	ret = &common.WriteCommandResult{}
+	err := json.Unmarshal(payload, cmdPtr)
+	if err != nil {
+		log.Errorf("illegal mqtt command, error:%s", err.Error())
+		ret.Result = *cd.NewError(cd.IllegalParam, "invalid param")
+		return
+	}
+
+	ev := event.NewEvent(common.WriteCommandEvent, s.ID(), common.MasterModule, event.NewValues(), cmdPtr)
+	exCode, exErr := s.CallEvent(ev).Get()
+	if exErr != nil {
+		log.Errorf("execute mqtt command failed, id:%s, error:%s", cmdPtr.ID, exErr.Reason)
+		ret.Result = *exErr
+	}
+	if codeVal, codeOK := exCode.(byte); codeOK {
+		ret.ExceptionCode = codeVal
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/quickModbus/internal/config"
+	"github.com/muidea/quickModbus/pkg/common"
+	mq "github.com/muidea/quickModbus/pkg/mqtt"
+)
+
+func waitMessage(t *testing.T, msgCh chan *mq.Message) *mq.Message {
+	t.Helper()
+	select {
+	case msg := <-msgCh:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatalf("wait mqtt message timeout")
+	}
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, error:%s", err.Error())
+	}
+	broker := mq.NewBroker()
+	go func() {
+		_ = broker.Serve(listener)
+	}()
+	defer broker.Close()
+
+	eventHub := event.NewHub(10)
+	defer eventHub.Terminate()
+	backgroundRoutine := task.NewBackgroundRoutine(10)
+
+	// 代替master处理写入命令
+	masterObserver := event.NewSimpleObserver(common.MasterModule, eventHub)
+	masterObserver.Subscribe(common.WriteCommandEvent, func(ev event.Event, result event.Result) {
+		cmdPtr := ev.Data().(*common.WriteCommand)
+		if cmdPtr.SlaveID != "mb001" {
+			result.Set(nil, cd.NewError(cd.IllegalParam, "no exist slave"))
+			return
+		}
+		result.Set(byte(0x02), nil)
+	})
+
+	cfg := &config.MQTTConfig{
+		BrokerAddr:    listener.Addr().String(),
+		ClientID:      "bridge",
+		KeepAlive:     30,
+		QoS:           1,
+		ValueTopic:    "quickModbus/{scanGroup}/{slaveID}/{tag}",
+		CommandTopic:  "quickModbus/command",
+		ResponseTopic: "quickModbus/command/response",
+	}
+	bridgePtr := NewBridge(cfg, eventHub, backgroundRoutine)
+	bridgePtr.Run()
+	defer bridgePtr.Close()
+
+	clientPtr := mq.NewClient(mq.Options{BrokerAddr: cfg.BrokerAddr, ClientID: "test", KeepAlive: 30 * time.Second})
+	if err = clientPtr.Connect(); err != nil {
+		t.Fatalf("Connect failed, error:%s", err.Error())
+	}
+	defer clientPtr.Close()
+	valueCh := make(chan *mq.Message, 10)
+	responseCh := make(chan *mq.Message, 10)
+	_ = clientPtr.Subscribe("quickModbus/+/+/+", 0, func(msg *mq.Message) { valueCh <- msg })
+	_ = clientPtr.Subscribe(cfg.ResponseTopic, 0, func(msg *mq.Message) { responseCh <- msg })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !bridgePtr.client.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatalf("bridge connect timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	header := event.NewValues()
+	header.Set("slaveID", "mb001")
+	header.Set("scanGroup", "fast")
+	tagVal := &common.TagValue{Name: "temp", Value: 21.5, Quality: common.GoodQuality, Timestamp: time.Now()}
+	eventHub.Post(event.NewEvent(common.TagChangeEventID(tagVal.Name), common.MasterModule, "/#", header, tagVal))
+
+	msg := waitMessage(t, valueCh)
+	valuePtr := &common.TagValue{}
+	_ = json.Unmarshal(msg.Payload, valuePtr)
+	if msg.Topic != "quickModbus/fast/mb001/temp" || valuePtr.Name != "temp" || valuePtr.Value != 21.5 {
+		t.Errorf("illegal value message, topic:%s, payload:%s", msg.Topic, msg.Payload)
+	}
+
+	items := []struct {
+		command string
+		result  common.WriteCommandResult
+	}{
+		{
+			command: `{"id":"c1","slaveID":"mb001","function":"writeSingleRegister","address":1,"value":5}`,
+			result:  common.WriteCommandResult{ID: "c1", ExceptionCode: 0x02},
+		},
+		{
+			command: `{"id":"c2","slaveID":"mb002","function":"writeSingleRegister","address":1,"value":5}`,
+			result:  common.WriteCommandResult{Result: cd.Result{ErrorCode: cd.IllegalParam, Reason: "no exist slave"}, ID: "c2"},
+		},
+		{
+			command: `{"id":`,
+			result:  common.WriteCommandResult{Result: cd.Result{ErrorCode: cd.IllegalParam, Reason: "invalid param"}},
+		},
+	}
+	for _, val := range items {
+		if err = clientPtr.Publish(&mq.Message{Topic: cfg.CommandTopic, Payload: []byte(val.command), QoS: 1}); err != nil {
+			t.Fatalf("Publish failed, error:%s", err.Error())
+		}
+
+		msg = waitMessage(t, responseCh)
+		result := common.WriteCommandResult{}
+		_ = json.Unmarshal(msg.Payload, &result)
+		if result != val.result {
+			t.Errorf("illegal command response, command:%s, payload:%s", val.command, msg.Payload)
+		}
+	}
+}
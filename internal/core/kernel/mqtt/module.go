@@ -0,0 +1,50 @@
+package mqtt
+
+import (
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/quickModbus/internal/config"
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+// MQTT 北向MQTT模块,未配置mqtt时不启用
+type MQTT struct {
+	bridgePtr *Bridge
+}
+
+func New() *MQTT {
+	return &MQTT{}
+}
+
+func (s *MQTT) ID() string {
+	return common.MQTTModule
+}
+
+func (s *MQTT) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	cfg := config.MQTT()
+	if cfg == nil {
+		return
+	}
+
+	s.bridgePtr = NewBridge(cfg, eventHub, backgroundRoutine)
+}
+
+func (s *MQTT) Run() {
+	if s.bridgePtr == nil {
+		return
+	}
+
+	s.bridgePtr.Run()
+}
+
+func (s *MQTT) Teardown() {
+	if s.bridgePtr != nil {
+		s.bridgePtr.Close()
+	}
+}
@@ -0,0 +1,385 @@
+package slave
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+const (
+	maxReadBitCount       = 0x07D0
+	maxReadRegisterCount  = 0x007D
+	maxWriteBitCount      = 0x07B0
+	maxWriteRegisterCount = 0x007B
+	maxRWWriteCount       = 0x0079
+)
+
+const (
+	diagReturnQueryData             = 0x0000
+	diagRestartCommunications       = 0x0001
+	diagClearCounters               = 0x000A
+	diagReturnBusMessageCount       = 0x000B
+	diagReturnBusCommErrorCount     = 0x000C
+	diagReturnBusExceptionCount     = 0x000D
+	diagReturnServerMessageCount    = 0x000E
+	diagReturnServerNoResponseCount = 0x000F
+)
+
+const (
+	maxCommEventSize = 64
+	commEventRecv    = byte(0x80)
+	commEventSend    = byte(0x40)
+	commEventExcept  = byte(0x02)
+	serverRunStatus  = byte(0xFF)
+)
+
+func registersToBytes(values []uint16) []byte {
+	ret := make([]byte, 0, len(values)*2)
+	for _, val := range values {
+		ret = binary.BigEndian.AppendUint16(ret, val)
+	}
+
+	return ret
+}
+
+func bytesToRegisters(byteVal []byte) []uint16 {
+	ret := make([]uint16, 0, len(byteVal)/2)
+	for idx := 0; idx+1 < len(byteVal); idx += 2 {
+		ret = append(ret, binary.BigEndian.Uint16(byteVal[idx:idx+2]))
+	}
+
+	return ret
+}
+
+func bitsToBytes(values []bool) []byte {
+	ret, _ := common.AppendBoolArray(nil, values)
+	return ret
+}
+
+func bytesToBits(byteVal []byte, count uint16) []bool {
+	ret, _ := common.BytesToBoolArray(byteVal)
+	if len(ret) < int(count) {
+		return nil
+	}
+
+	return ret[:count]
+}
+
+func (s *MBSlave) handleRequest(unitID byte, reqVal model.MBProtocol) (ret model.MBProtocol) {
+	switch req := reqVal.(type) {
+	case *model.MBReadCoilsReq:
+		ret = s.readCoils(unitID, req)
+	case *model.MBReadDiscreteInputsReq:
+		ret = s.readDiscreteInputs(unitID, req)
+	case *model.MBReadHoldingRegistersReq:
+		ret = s.readHoldingRegisters(unitID, req)
+	case *model.MBReadInputRegistersReq:
+		ret = s.readInputRegisters(unitID, req)
+	case *model.MBWriteSingleCoilReq:
+		ret = s.writeSingleCoil(unitID, req)
+	case *model.MBWriteSingleRegisterReq:
+		ret = s.writeSingleRegister(unitID, req)
+	case *model.MBReadExceptionStatusReq:
+		ret = model.NewReadExceptionStatusRsp(s.exceptionStatus)
+	case *model.MBDiagnosticsReq:
+		ret = s.diagnostics(req)
+	case *model.MBGetCommEventCounterReq:
+		ret = s.getCommEventCounter()
+	case *model.MBGetCommEventLogReq:
+		ret = s.getCommEventLog()
+	case *model.MBWriteMultipleCoilsReq:
+		ret = s.writeMultipleCoils(unitID, req)
+	case *model.MBWriteMultipleRegistersReq:
+		ret = s.writeMultipleRegisters(unitID, req)
+	case *model.MBReportSlaveIDReq:
+		ret = s.reportSlaveID(unitID)
+	case *model.MBReadFileRecordReq:
+		ret = s.readFileRecord(unitID, req)
+	case *model.MBWriteFileRecordReq:
+		ret = s.writeFileRecord(unitID, req)
+	case *model.MBMaskWriteRegisterReq:
+		ret = s.maskWriteRegister(unitID, req)
+	case *model.MBReadWriteMultipleRegistersReq:
+		ret = s.readWriteMultipleRegisters(unitID, req)
+	case *model.MBReadFIFOQueueReq:
+		ret = s.readFIFOQueue(unitID, req)
+	default:
+		ret = model.NewExceptionRsp(reqVal.FuncCode(), model.IllegalFuncCode)
+	}
+
+	return
+}
+
+func (s *MBSlave) readCoils(unitID byte, req *model.MBReadCoilsReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxReadBitCount {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	values, exCode := s.dataStore.ReadCoils(unitID, req.Address(), req.Count())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewReadCoilsRsp(bitsToBytes(values))
+}
+
+func (s *MBSlave) readDiscreteInputs(unitID byte, req *model.MBReadDiscreteInputsReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxReadBitCount {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	values, exCode := s.dataStore.ReadDiscreteInputs(unitID, req.Address(), req.Count())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	byteVal := bitsToBytes(values)
+	return model.NewReadDiscreteInputsRsp(byte(len(byteVal)), byteVal)
+}
+
+func (s *MBSlave) readHoldingRegisters(unitID byte, req *model.MBReadHoldingRegistersReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxReadRegisterCount {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	values, exCode := s.dataStore.ReadHoldingRegisters(unitID, req.Address(), req.Count())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewReadHoldingRegistersRsp(registersToBytes(values))
+}
+
+func (s *MBSlave) readInputRegisters(unitID byte, req *model.MBReadInputRegistersReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxReadRegisterCount {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	values, exCode := s.dataStore.ReadInputRegisters(unitID, req.Address(), req.Count())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewReadInputRegistersRsp(registersToBytes(values))
+}
+
+func (s *MBSlave) writeSingleCoil(unitID byte, req *model.MBWriteSingleCoilReq) model.MBProtocol {
+	var value bool
+	if bytes.Equal(req.Data(), model.CoilON) {
+		value = true
+	} else if !bytes.Equal(req.Data(), model.CoilOFF) {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	exCode := s.dataStore.WriteCoils(unitID, req.Address(), []bool{value})
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewWriteSingleCoilRsp(req.Address(), req.Data())
+}
+
+func (s *MBSlave) writeSingleRegister(unitID byte, req *model.MBWriteSingleRegisterReq) model.MBProtocol {
+	values := bytesToRegisters(req.Data())
+	if len(values) != 1 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	exCode := s.dataStore.WriteHoldingRegisters(unitID, req.Address(), values)
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewWriteSingleRegisterRsp(req.Address(), req.Data())
+}
+
+func (s *MBSlave) writeMultipleCoils(unitID byte, req *model.MBWriteMultipleCoilsReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxWriteBitCount || len(req.Data()) != int(req.Count()+7)/8 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	values := bytesToBits(req.Data(), req.Count())
+	exCode := s.dataStore.WriteCoils(unitID, req.Address(), values)
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewWriteMultipleCoilsRsp(req.Address(), req.Count())
+}
+
+func (s *MBSlave) writeMultipleRegisters(unitID byte, req *model.MBWriteMultipleRegistersReq) model.MBProtocol {
+	if req.Count() < 1 || req.Count() > maxWriteRegisterCount || len(req.Data()) != int(req.Count())*2 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	exCode := s.dataStore.WriteHoldingRegisters(unitID, req.Address(), bytesToRegisters(req.Data()))
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewWriteMultipleRegistersRsp(req.Address(), req.Count())
+}
+
+func (s *MBSlave) maskWriteRegister(unitID byte, req *model.MBMaskWriteRegisterReq) model.MBProtocol {
+	if len(req.AndMask()) != 2 || len(req.OrMask()) != 2 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	andMask := binary.BigEndian.Uint16(req.AndMask())
+	orMask := binary.BigEndian.Uint16(req.OrMask())
+	exCode := s.dataStore.MaskWriteHoldingRegister(unitID, req.Address(), andMask, orMask)
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewMaskWriteRegisterRsp(req.Address(), req.AndMask(), req.OrMask())
+}
+
+func (s *MBSlave) readWriteMultipleRegisters(unitID byte, req *model.MBReadWriteMultipleRegistersReq) model.MBProtocol {
+	if req.ReadCount() < 1 || req.ReadCount() > maxReadRegisterCount {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+	if req.WriteCount() < 1 || req.WriteCount() > maxRWWriteCount || len(req.WriteData()) != int(req.WriteCount())*2 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	// 写操作先于读操作执行
+	exCode := s.dataStore.WriteHoldingRegisters(unitID, req.WriteAddress(), bytesToRegisters(req.WriteData()))
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	values, exCode := s.dataStore.ReadHoldingRegisters(unitID, req.ReadAddress(), req.ReadCount())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewReadWriteMultipleRegistersRsp(registersToBytes(values))
+}
+
+func (s *MBSlave) readFileRecord(unitID byte, req *model.MBReadFileRecordReq) model.MBProtocol {
+	if len(req.Items()) == 0 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	rsp := model.NewReadFileRecordRsp()
+	for _, val := range req.Items() {
+		values, exCode := s.dataStore.ReadFileRecord(unitID, val.FileNumber(), val.RecordNumber(), val.RecordLength())
+		if exCode != model.SuccessCode {
+			return model.NewExceptionRsp(req.FuncCode(), exCode)
+		}
+
+		rsp.AppendItem(registersToBytes(values))
+	}
+
+	return rsp
+}
+
+func (s *MBSlave) writeFileRecord(unitID byte, req *model.MBWriteFileRecordReq) model.MBProtocol {
+	if len(req.Items()) == 0 {
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalCount)
+	}
+
+	rsp := model.NewWriteFileRecordRsp()
+	for _, val := range req.Items() {
+		exCode := s.dataStore.WriteFileRecord(unitID, val.FileNumber(), val.RecordNumber(), bytesToRegisters(val.RecordData()))
+		if exCode != model.SuccessCode {
+			return model.NewExceptionRsp(req.FuncCode(), exCode)
+		}
+
+		rsp.AppendItem(val.FileNumber(), val.RecordNumber(), val.RecordData())
+	}
+
+	return rsp
+}
+
+func (s *MBSlave) readFIFOQueue(unitID byte, req *model.MBReadFIFOQueueReq) model.MBProtocol {
+	values, exCode := s.dataStore.ReadFIFOQueue(unitID, req.Address())
+	if exCode != model.SuccessCode {
+		return model.NewExceptionRsp(req.FuncCode(), exCode)
+	}
+
+	return model.NewReadFIFOQueueRsp(uint16(len(values)), registersToBytes(values))
+}
+
+func (s *MBSlave) diagnostics(req *model.MBDiagnosticsReq) model.MBProtocol {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
+
+	var counterVal uint16
+	switch req.SubFunctionCode() {
+	case diagReturnQueryData:
+		return model.NewDiagnosticsRsp(req.SubFunctionCode(), req.Data())
+	case diagRestartCommunications, diagClearCounters:
+		s.commCounter = commCounter{}
+		s.commEvents = nil
+		return model.NewDiagnosticsRsp(req.SubFunctionCode(), req.Data())
+	case diagReturnBusMessageCount:
+		counterVal = s.commCounter.busMessageCount
+	case diagReturnBusCommErrorCount:
+		counterVal = s.commCounter.busCommErrorCount
+	case diagReturnBusExceptionCount:
+		counterVal = s.commCounter.busExceptionCount
+	case diagReturnServerMessageCount:
+		counterVal = s.commCounter.serverMessageCount
+	case diagReturnServerNoResponseCount:
+		counterVal = s.commCounter.serverNoResponseCount
+	default:
+		return model.NewExceptionRsp(req.FuncCode(), model.IllegalFuncCode)
+	}
+
+	return model.NewDiagnosticsRsp(req.SubFunctionCode(), binary.BigEndian.AppendUint16(nil, counterVal))
+}
+
+func (s *MBSlave) getCommEventCounter() model.MBProtocol {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
+
+	return model.NewGetCommEventCounterRsp(0, s.commCounter.eventCount)
+}
+
+func (s *MBSlave) getCommEventLog() model.MBProtocol {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
+
+	events := make([]byte, len(s.commEvents))
+	copy(events, s.commEvents)
+	return model.NewGetCommEventLogRsp(0, s.commCounter.eventCount, s.commCounter.busMessageCount, events)
+}
+
+func (s *MBSlave) reportSlaveID(unitID byte) model.MBProtocol {
+	info := []byte{unitID, serverRunStatus}
+	info = append(info, []byte(serverName)...)
+	return model.NewReportSlaveIDRsp(info)
+}
+
+// updateCounter 按照应答结果刷新诊断计数器与通信事件日志
+func (s *MBSlave) updateCounter(reqVal, rspVal model.MBProtocol) {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
+
+	s.commCounter.busMessageCount++
+	s.commCounter.serverMessageCount++
+
+	rspEvent := commEventSend
+	if exRsp, exOK := rspVal.(*model.MBExceptionRsp); exOK {
+		s.commCounter.busExceptionCount++
+		rspEvent |= commEventExcept
+		if exRsp.ExceptionCode() == model.IllegalFuncCode {
+			rspEvent |= 0x01
+		}
+	} else {
+		switch reqVal.(type) {
+		case *model.MBGetCommEventCounterReq, *model.MBGetCommEventLogReq:
+		default:
+			s.commCounter.eventCount++
+		}
+	}
+
+	s.commEvents = append([]byte{rspEvent, commEventRecv}, s.commEvents...)
+	if len(s.commEvents) > maxCommEventSize {
+		s.commEvents = s.commEvents[:maxCommEventSize]
+	}
+}
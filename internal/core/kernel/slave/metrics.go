@@ -0,0 +1,67 @@
+package slave
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/metrics"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+const (
+	tcpModeLabel   = "tcp"
+	rtuModeLabel   = "rtu"
+	asciiModeLabel = "ascii"
+)
+
+var (
+	slaveRequestCounter = metrics.Default.NewCounter("quickmodbus_slave_requests_total",
+		"Modbus requests handled by the slave server.", "mode", "unit", "function")
+	slaveDurationHistogram = metrics.Default.NewHistogram("quickmodbus_slave_request_duration_seconds",
+		"Time spent by the slave server handling a request.", []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1}, "mode", "function")
+	slaveExceptionCounter = metrics.Default.NewCounter("quickmodbus_slave_exceptions_total",
+		"Exception responses returned by the slave server.", "mode", "function", "code")
+	slaveChecksumCounter = metrics.Default.NewCounter("quickmodbus_slave_checksum_errors_total",
+		"Request frames dropped by the slave server because of a CRC or LRC mismatch.", "mode", "check")
+	slaveIllegalCounter = metrics.Default.NewCounter("quickmodbus_slave_illegal_requests_total",
+		"Request frames the slave server could not decode.", "mode")
+	slaveConnectionGauge = metrics.Default.NewGauge("quickmodbus_slave_connections",
+		"Masters connected to the slave server.", "mode")
+)
+
+func modeLabel(mode byte) string {
+	switch mode {
+	case common.ModbusRTUOverTcp:
+		return rtuModeLabel
+	case common.ModbusASCIIOverTcp:
+		return asciiModeLabel
+	}
+
+	return tcpModeLabel
+}
+
+func functionLabel(funcCode byte) string {
+	return fmt.Sprintf("0x%02X", funcCode&0x7F)
+}
+
+func observeRequest(mode string, unitID byte, reqVal, rspVal model.MBProtocol, elapsed time.Duration) {
+	function := functionLabel(reqVal.FuncCode())
+	slaveRequestCounter.Inc(mode, fmt.Sprintf("%d", unitID), function)
+	slaveDurationHistogram.Observe(elapsed.Seconds(), mode, function)
+	if exRsp, exOK := rspVal.(*model.MBExceptionRsp); exOK {
+		slaveExceptionCounter.Inc(mode, function, fmt.Sprintf("%d", exRsp.ExceptionCode()))
+	}
+}
+
+// observeIllegalFrame 校验失败的帧另外按照校验方式计数
+func observeIllegalFrame(mode string, err error) {
+	slaveIllegalCounter.Inc(mode)
+	switch {
+	case errors.Is(err, model.ErrCRCCheck):
+		slaveChecksumCounter.Inc(mode, "crc")
+	case errors.Is(err, model.ErrLRCCheck):
+		slaveChecksumCounter.Inc(mode, "lrc")
+	}
+}
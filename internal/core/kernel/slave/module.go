@@ -1,6 +1,8 @@
 package slave
 
 import (
+	"io"
+
 	"github.com/muidea/magicCommon/event"
 	"github.com/muidea/magicCommon/foundation/log"
 	"github.com/muidea/magicCommon/module"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/muidea/quickModbus/internal/config"
 	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/datastore"
 )
 
 func init() {
@@ -15,13 +18,12 @@ func init() {
 }
 
 type Slave struct {
-	slavePtr *MBSlave
+	slavePtr  *MBSlave
+	dataStore datastore.DataStore
 }
 
 func New() *Slave {
-	return &Slave{
-		slavePtr: &MBSlave{},
-	}
+	return &Slave{}
 }
 
 func (s *Slave) ID() string {
@@ -29,11 +31,46 @@ func (s *Slave) ID() string {
 }
 
 func (s *Slave) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	dataStore := datastore.NewMemoryStore(config.SlaveUnits())
+	if config.DataFile() != "" {
+		fileStore, fileErr := datastore.NewFileStore(config.DataFile(), config.SlaveUnits())
+		if fileErr != nil {
+			log.Errorf("load modbus slave data failed, dataFile:%s, error:%s", config.DataFile(), fileErr.Error())
+			return
+		}
+
+		dataStore = fileStore
+	}
+
+	s.dataStore = dataStore
+	s.slavePtr = NewSlave(dataStore)
 }
 
 func (s *Slave) Run() {
-	err := s.slavePtr.Run(config.BindAddr())
+	if s.slavePtr == nil {
+		return
+	}
+
+	// tcp.Server.Run会一直阻塞,每个监听都需要放到独立的routine中执行
+	for _, val := range config.SlaveListeners() {
+		go func(listenerCfg config.ListenerConfig) {
+			err := s.slavePtr.Run(listenerCfg.BindAddr(), listenerCfg.Mode)
+			if err != nil {
+				log.Errorf("start modbus slave failed, bindAddr:%s, mode:%d, error:%s", listenerCfg.BindAddr(), listenerCfg.Mode, err.Error())
+			}
+		}(val)
+	}
+}
+
+// Teardown 文件存储异步落盘,退出前需要保存尚未落盘的修改
+func (s *Slave) Teardown() {
+	closer, ok := s.dataStore.(io.Closer)
+	if !ok {
+		return
+	}
+
+	err := closer.Close()
 	if err != nil {
-		log.Errorf("start modbus slave failed, bindAddr:%s, error:%s", config.BindAddr(), err.Error())
+		log.Errorf("save modbus slave data failed, dataFile:%s, error:%s", config.DataFile(), err.Error())
 	}
 }
@@ -1,15 +1,68 @@
 package slave
 
-import "github.com/muidea/magicEngine/tcp"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicEngine/tcp"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/datastore"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+const (
+	serverName       = "quickModbus"
+	defaultMaxConn   = 100
+	tcpHeadLength    = 7
+	broadcastAddress = 0x00
+	asciiStartChar   = ':'
+)
+
+type commCounter struct {
+	eventCount            uint16
+	busMessageCount       uint16
+	busCommErrorCount     uint16
+	busExceptionCount     uint16
+	serverMessageCount    uint16
+	serverNoResponseCount uint16
+}
 
 type MBSlave struct {
-	tcpServer tcp.Server
+	dataStore datastore.DataStore
+
+	exceptionStatus byte
+	commCounter     commCounter
+	commEvents      []byte
+	counterLock     sync.Mutex
+}
+
+func NewSlave(dataStore datastore.DataStore) *MBSlave {
+	return &MBSlave{
+		dataStore: dataStore,
+	}
 }
 
-func (s *MBSlave) Run(bindAddr string) (err error) {
-	server := tcp.NewServer(s, 100)
-	s.tcpServer = server
-	err = server.Run(bindAddr)
+// Run 在bindAddr上按照mode指定的帧格式监听,会一直阻塞
+func (s *MBSlave) Run(bindAddr string, mode byte) (err error) {
+	switch mode {
+	case common.ModbusTcp, common.ModbusRTUOverTcp, common.ModbusASCIIOverTcp:
+	default:
+		err = fmt.Errorf("illegal slave mode:%d", mode)
+		return
+	}
+
+	listenerPtr := &listener{
+		slavePtr:   s,
+		mode:       mode,
+		recvBuffer: map[string][]byte{},
+	}
+	listenerPtr.tcpServer = tcp.NewServer(listenerPtr, defaultMaxConn)
+	err = listenerPtr.tcpServer.Run(bindAddr)
 	if err != nil {
 		return
 	}
@@ -17,14 +70,213 @@ func (s *MBSlave) Run(bindAddr string) (err error) {
 	return
 }
 
-func (s *MBSlave) OnConnect(ep tcp.Endpoint) {
+// listener 每个监听端口对应一种帧格式,共享同一个从站数据
+type listener struct {
+	slavePtr  *MBSlave
+	mode      byte
+	tcpServer tcp.Server
+
+	// recvBuffer 主站可能连续发出多个请求,请求也可能分多次到达,按连接缓存未处理完的数据
+	bufferLock sync.Mutex
+	recvBuffer map[string][]byte
+}
+
+func (s *listener) OnConnect(ep tcp.Endpoint) {
+	log.Infof("modbus master connected, remoteAddr:%s, mode:%d", ep.RemoteAddr().String(), s.mode)
+	slaveConnectionGauge.Inc(modeLabel(s.mode))
+}
+
+func (s *listener) OnDisConnect(ep tcp.Endpoint) {
+	log.Infof("modbus master disconnected, remoteAddr:%s, mode:%d", ep.RemoteAddr().String(), s.mode)
+	slaveConnectionGauge.Dec(modeLabel(s.mode))
+
+	s.bufferLock.Lock()
+	delete(s.recvBuffer, ep.RemoteAddr().String())
+	s.bufferLock.Unlock()
+}
+
+func (s *listener) OnRecvData(ep tcp.Endpoint, data []byte) {
+	for _, frame := range s.splitFrames(ep, data) {
+		switch s.mode {
+		case common.ModbusRTUOverTcp:
+			s.slavePtr.onRTUData(ep, frame)
+		case common.ModbusASCIIOverTcp:
+			s.slavePtr.onASCIIData(ep, frame)
+		default:
+			s.slavePtr.onTCPData(ep, frame)
+		}
+	}
+}
+
+func (s *listener) frameLen(data []byte) (int, error) {
+	switch s.mode {
+	case common.ModbusRTUOverTcp:
+		return model.RTURequestFrameLen(data)
+	case common.ModbusASCIIOverTcp:
+		return model.ASCIIFrameLen(data)
+	default:
+		return model.TCPFrameLen(data)
+	}
+}
+
+// splitFrames 一次读取可能只包含半帧或者多帧,按连接缓存数据并切分出完整的请求,不完整的帧留待下次数据到达
+func (s *listener) splitFrames(ep tcp.Endpoint, data []byte) (ret [][]byte) {
+	remoteAddr := ep.RemoteAddr().String()
+	s.bufferLock.Lock()
+	defer s.bufferLock.Unlock()
+
+	bufferVal := append(s.recvBuffer[remoteAddr], data...)
+	for len(bufferVal) > 0 {
+		frameLen, frameErr := s.frameLen(bufferVal)
+		if frameErr != nil {
+			// 无法同步的数据交给对应的处理函数按照非法请求处理,ASCII帧从下一个起始符处重新同步
+			dropLen := len(bufferVal)
+			if s.mode == common.ModbusASCIIOverTcp {
+				if idx := bytes.IndexByte(bufferVal[1:], asciiStartChar); idx >= 0 {
+					dropLen = idx + 1
+				}
+			}
+			ret = append(ret, bufferVal[:dropLen])
+			bufferVal = bufferVal[dropLen:]
+			continue
+		}
+		if frameLen == 0 {
+			break
+		}
+
+		ret = append(ret, bufferVal[:frameLen])
+		bufferVal = bufferVal[frameLen:]
+	}
+	if len(bufferVal) > 0 {
+		s.recvBuffer[remoteAddr] = append([]byte(nil), bufferVal...)
+	} else {
+		delete(s.recvBuffer, remoteAddr)
+	}
+	return
+}
+
+func (s *MBSlave) onTCPData(ep tcp.Endpoint, data []byte) {
+	header, reqVal, err := model.DecodeMBTcpProtocol(bytes.NewBuffer(data), model.RequestAction)
+	if err != model.SuccessCode {
+		observeIllegalFrame(tcpModeLabel, nil)
+		s.onIllegalTCPRequest(ep, data, err)
+		return
+	}
 
+	startTime := time.Now()
+	rspVal := s.handleRequest(header.UnitID(), reqVal)
+	s.updateCounter(reqVal, rspVal)
+	observeRequest(tcpModeLabel, header.UnitID(), reqVal, rspVal, time.Since(startTime))
+	s.sendTCPResponse(ep, header.Transaction(), header.UnitID(), rspVal)
+}
+
+// onIllegalTCPRequest 请求无法解析时,若能识别出MBAP头和功能码则返回异常应答,否则直接丢弃
+func (s *MBSlave) onIllegalTCPRequest(ep tcp.Endpoint, data []byte, exCode byte) {
+	s.increaseCommError()
+	if len(data) < tcpHeadLength+1 || binary.BigEndian.Uint16(data[2:4]) != model.ModbusProtocol {
+		log.Warnf("drop illegal modbus request, remoteAddr:%s", ep.RemoteAddr().String())
+		return
+	}
+
+	transaction := binary.BigEndian.Uint16(data[0:2])
+	unitID := data[6]
+	funcCode := data[7] & 0x7F
+	s.sendTCPResponse(ep, transaction, unitID, model.NewExceptionRsp(funcCode, exCode))
+}
+
+func (s *MBSlave) sendTCPResponse(ep tcp.Endpoint, transaction uint16, unitID byte, rspVal model.MBProtocol) {
+	buffVal := bytes.NewBuffer(nil)
+	header := model.NewTcpHeader(transaction, rspVal.CalcLen(), unitID)
+	err := model.EncodeMBTcpProtocol(header, rspVal, buffVal)
+	if err != model.SuccessCode {
+		log.Errorf("encode modbus response failed, funcCode:%x, error:%d", rspVal.FuncCode(), err)
+		return
+	}
+
+	s.sendData(ep, buffVal.Bytes())
+}
+
+func (s *MBSlave) onRTUData(ep tcp.Endpoint, data []byte) {
+	// CRC校验失败的帧按照规范直接丢弃,不做任何应答
+	dataVal, dataErr := model.DecodeFromRTUStream(data)
+	if dataErr != nil {
+		observeIllegalFrame(rtuModeLabel, dataErr)
+		s.increaseCommError()
+		log.Warnf("drop rtu frame, remoteAddr:%s, error:%s", ep.RemoteAddr().String(), dataErr.Error())
+		return
+	}
+
+	s.onSerialData(ep, rtuModeLabel, dataVal, model.EncodeToRTUStream)
+}
+
+func (s *MBSlave) onASCIIData(ep tcp.Endpoint, data []byte) {
+	// LRC校验失败的帧按照规范直接丢弃,不做任何应答
+	dataVal, dataErr := model.DecodeFromASCIIStream(data)
+	if dataErr != nil {
+		observeIllegalFrame(asciiModeLabel, dataErr)
+		s.increaseCommError()
+		log.Warnf("drop ascii frame, remoteAddr:%s, error:%s", ep.RemoteAddr().String(), dataErr.Error())
+		return
+	}
+
+	s.onSerialData(ep, asciiModeLabel, dataVal, model.EncodeToASCIIStream)
+}
+
+// onSerialData mode为指标中的帧格式标签
+func (s *MBSlave) onSerialData(ep tcp.Endpoint, mode string, dataVal []byte, encodeFunc func([]byte) []byte) {
+	header, reqVal, err := model.DecodeMBSerialProtocol(bytes.NewBuffer(dataVal), model.RequestAction)
+	if err != model.SuccessCode {
+		observeIllegalFrame(mode, nil)
+		s.increaseCommError()
+		if len(dataVal) < 2 || dataVal[0] == broadcastAddress {
+			return
+		}
+
+		s.sendSerialResponse(ep, dataVal[0], model.NewExceptionRsp(dataVal[1]&0x7F, err), encodeFunc)
+		return
+	}
+
+	startTime := time.Now()
+	rspVal := s.handleRequest(header.Address(), reqVal)
+	s.updateCounter(reqVal, rspVal)
+	observeRequest(mode, header.Address(), reqVal, rspVal, time.Since(startTime))
+	// 广播请求只执行不应答
+	if header.Address() == broadcastAddress {
+		s.increaseNoResponse()
+		return
+	}
+
+	s.sendSerialResponse(ep, header.Address(), rspVal, encodeFunc)
+}
+
+func (s *MBSlave) sendSerialResponse(ep tcp.Endpoint, address byte, rspVal model.MBProtocol, encodeFunc func([]byte) []byte) {
+	buffVal := bytes.NewBuffer(nil)
+	err := model.EncodeMBSerialProtocol(model.NewSerialHeader(address), rspVal, buffVal)
+	if err != model.SuccessCode {
+		log.Errorf("encode modbus response failed, funcCode:%x, error:%d", rspVal.FuncCode(), err)
+		return
+	}
+
+	s.sendData(ep, encodeFunc(buffVal.Bytes()))
+}
+
+func (s *MBSlave) sendData(ep tcp.Endpoint, byteVal []byte) {
+	sendErr := ep.SendData(byteVal)
+	if sendErr != nil {
+		log.Errorf("send modbus response failed, remoteAddr:%s, error:%s", ep.RemoteAddr().String(), sendErr.Error())
+	}
 }
 
-func (s *MBSlave) OnDisConnect(ep tcp.Endpoint) {
+func (s *MBSlave) increaseCommError() {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
 
+	s.commCounter.busCommErrorCount++
 }
 
-func (s *MBSlave) OnRecvData(ep tcp.Endpoint, data []byte) {
+func (s *MBSlave) increaseNoResponse() {
+	s.counterLock.Lock()
+	defer s.counterLock.Unlock()
 
+	s.commCounter.serverNoResponseCount++
 }
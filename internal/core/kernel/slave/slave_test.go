@@ -0,0 +1,196 @@
+package slave
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/datastore"
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+// fakeEndpoint 记录从站发出的应答
+type fakeEndpoint struct {
+	lock sync.Mutex
+	sent [][]byte
+}
+
+func (s *fakeEndpoint) Close() {
+}
+
+func (s *fakeEndpoint) SendData(data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sent = append(s.sent, append([]byte(nil), data...))
+	return nil
+}
+
+func (s *fakeEndpoint) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 502}
+}
+
+func (s *fakeEndpoint) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000}
+}
+
+func (s *fakeEndpoint) frames() [][]byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sent
+}
+
+func newTestListener(t *testing.T, mode byte) *listener {
+	t.Helper()
+	dataStore := datastore.NewMemoryStore([]datastore.UnitConfig{datastore.DefaultUnitConfig(1)})
+	if exCode := dataStore.WriteHoldingRegisters(1, 0, []uint16{10, 20, 30}); exCode != model.SuccessCode {
+		t.Fatalf("WriteHoldingRegisters failed, exCode:%d", exCode)
+	}
+
+	return &listener{slavePtr: NewSlave(dataStore), mode: mode, recvBuffer: map[string][]byte{}}
+}
+
+func tcpReadRequest(transaction, address uint16) []byte {
+	reqVal := model.NewReadHoldingRegistersReq(address, 1)
+	buffVal := bytes.NewBuffer(nil)
+	model.EncodeMBTcpProtocol(model.NewTcpHeader(transaction, reqVal.CalcLen(), 1), reqVal, buffVal)
+	return buffVal.Bytes()
+}
+
+func TestTCPStream(t *testing.T) {
+	listenerPtr := newTestListener(t, common.ModbusTcp)
+	ep := &fakeEndpoint{}
+
+	req1 := tcpReadRequest(1, 0)
+	req2 := tcpReadRequest(2, 1)
+	req3 := tcpReadRequest(3, 2)
+
+	// 拆分的帧在收齐之前不应答
+	listenerPtr.OnRecvData(ep, req1[:5])
+	if len(ep.frames()) != 0 {
+		t.Fatalf("partial frame should not be answered")
+	}
+
+	// 一次读取中包含前一帧的剩余部分、完整的一帧以及下一帧的开头
+	coalesced := append(append(append([]byte(nil), req1[5:]...), req2...), req3[:3]...)
+	listenerPtr.OnRecvData(ep, coalesced)
+	if len(ep.frames()) != 2 {
+		t.Fatalf("coalesced frames should be answered, responses:%d", len(ep.frames()))
+	}
+	listenerPtr.OnRecvData(ep, req3[3:])
+
+	frames := ep.frames()
+	if len(frames) != 3 {
+		t.Fatalf("illegal response count:%d", len(frames))
+	}
+	for idx, frame := range frames {
+		header, rspVal, err := model.DecodeMBTcpProtocol(bytes.NewBuffer(frame), model.ResponseAction)
+		if err != model.SuccessCode {
+			t.Fatalf("decode response failed, idx:%d, error:%d", idx, err)
+		}
+		data := rspVal.(*model.MBReadHoldingRegistersRsp).Data()
+		value := uint16(data[0])<<8 | uint16(data[1])
+		if header.Transaction() != uint16(idx+1) || value != uint16(idx+1)*10 {
+			t.Errorf("illegal response, idx:%d, transaction:%d, value:%d", idx, header.Transaction(), value)
+		}
+	}
+	if len(listenerPtr.recvBuffer) != 0 {
+		t.Errorf("recvBuffer should be empty, size:%d", len(listenerPtr.recvBuffer))
+	}
+}
+
+func TestTCPStreamIllegal(t *testing.T) {
+	listenerPtr := newTestListener(t, common.ModbusTcp)
+	ep := &fakeEndpoint{}
+
+	// 协议号错误的数据无法同步,整体丢弃且不影响后续请求
+	listenerPtr.OnRecvData(ep, []byte{0x00, 0x01, 0x12, 0x34, 0x00, 0x06, 0x01, 0x03})
+	listenerPtr.OnRecvData(ep, tcpReadRequest(9, 2))
+
+	frames := ep.frames()
+	if len(frames) != 1 {
+		t.Fatalf("illegal response count:%d", len(frames))
+	}
+	header, _, err := model.DecodeMBTcpProtocol(bytes.NewBuffer(frames[0]), model.ResponseAction)
+	if err != model.SuccessCode || header.Transaction() != 9 {
+		t.Errorf("illegal response, error:%d", err)
+	}
+}
+
+func serialReadRequest(mode byte, address uint16) []byte {
+	reqVal := model.NewReadHoldingRegistersReq(address, 1)
+	buffVal := bytes.NewBuffer(nil)
+	model.EncodeMBSerialProtocol(model.NewSerialHeader(1), reqVal, buffVal)
+	if mode == common.ModbusASCIIOverTcp {
+		return model.EncodeToASCIIStream(buffVal.Bytes())
+	}
+
+	return model.EncodeToRTUStream(buffVal.Bytes())
+}
+
+func serialRegisterValue(t *testing.T, mode byte, frame []byte) uint16 {
+	t.Helper()
+	decodeFunc := model.DecodeFromRTUStream
+	if mode == common.ModbusASCIIOverTcp {
+		decodeFunc = model.DecodeFromASCIIStream
+	}
+	aduVal, aduErr := decodeFunc(frame)
+	if aduErr != nil {
+		t.Fatalf("decode response failed, mode:%d, error:%s", mode, aduErr.Error())
+	}
+	_, rspVal, err := model.DecodeMBSerialProtocol(bytes.NewBuffer(aduVal), model.ResponseAction)
+	if err != model.SuccessCode {
+		t.Fatalf("decode response failed, mode:%d, error:%d", mode, err)
+	}
+
+	data := rspVal.(*model.MBReadHoldingRegistersRsp).Data()
+	return uint16(data[0])<<8 | uint16(data[1])
+}
+
+func TestSerialStream(t *testing.T) {
+	for _, mode := range []byte{common.ModbusRTUOverTcp, common.ModbusASCIIOverTcp} {
+		listenerPtr := newTestListener(t, mode)
+		ep := &fakeEndpoint{}
+
+		req1 := serialReadRequest(mode, 0)
+		req2 := serialReadRequest(mode, 1)
+		req3 := serialReadRequest(mode, 2)
+
+		listenerPtr.OnRecvData(ep, req1[:3])
+		if len(ep.frames()) != 0 {
+			t.Fatalf("partial frame should not be answered, mode:%d", mode)
+		}
+
+		coalesced := append(append(append([]byte(nil), req1[3:]...), req2...), req3[:4]...)
+		listenerPtr.OnRecvData(ep, coalesced)
+		listenerPtr.OnRecvData(ep, req3[4:])
+
+		frames := ep.frames()
+		if len(frames) != 3 {
+			t.Fatalf("illegal response count, mode:%d, responses:%d", mode, len(frames))
+		}
+		for idx, frame := range frames {
+			if value := serialRegisterValue(t, mode, frame); value != uint16(idx+1)*10 {
+				t.Errorf("illegal response, mode:%d, idx:%d, value:%d", mode, idx, value)
+			}
+		}
+		if len(listenerPtr.recvBuffer) != 0 {
+			t.Errorf("recvBuffer should be empty, mode:%d, size:%d", mode, len(listenerPtr.recvBuffer))
+		}
+	}
+}
+
+func TestASCIIStreamResync(t *testing.T) {
+	listenerPtr := newTestListener(t, common.ModbusASCIIOverTcp)
+	ep := &fakeEndpoint{}
+
+	// 起始符之前的残留数据被丢弃,从下一个起始符重新同步
+	listenerPtr.OnRecvData(ep, append([]byte("0B\r\n"), serialReadRequest(common.ModbusASCIIOverTcp, 2)...))
+	frames := ep.frames()
+	if len(frames) != 1 || serialRegisterValue(t, common.ModbusASCIIOverTcp, frames[0]) != 30 {
+		t.Errorf("ascii stream should resync at the next start char, responses:%d", len(frames))
+	}
+}
@@ -0,0 +1,598 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/muidea/quickModbus/internal/core/kernel/master/biz"
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/model"
+	"github.com/muidea/quickModbus/pkg/serial"
+)
+
+/*
+Dial支持的地址格式,query参数均可省略
+
+	tcp://host:502?maxTransactions=4
+	rtuovertcp://host:port
+	asciiovertcp://host:port
+	rtu:///dev/ttyUSB0?baudRate=9600&dataBits=8&parity=E&stopBits=1&frameSilence=0
+	ascii:///dev/ttyUSB0?baudRate=9600
+
+公共参数:endian为字节序,timeout、retryBackoff单位为毫秒,retries为重试次数
+*/
+const (
+	TCPScheme          = "tcp"
+	RTUOverTCPScheme   = "rtuovertcp"
+	ASCIIOverTCPScheme = "asciiovertcp"
+	RTUScheme          = "rtu"
+	ASCIIScheme        = "ascii"
+)
+
+var (
+	// ErrRequestCanceled 调用方取消请求或者ctx的截止时间已到,同时包装ctx.Err()
+	ErrRequestCanceled = biz.ErrRequestCanceled
+	// ErrTimeout 重试后仍在超时时间内没有收到从站应答
+	ErrTimeout = biz.ErrTimeout
+)
+
+// Stats 请求统计,Failures为超时或者发送失败的请求数
+type Stats struct {
+	Requests            uint64
+	Failures            uint64
+	Exceptions          uint64
+	ConsecutiveFailures uint64
+	LastError           string
+	LastActivity        time.Time
+}
+
+// Client 直接访问单个从站的Modbus主站,不依赖REST服务
+type Client struct {
+	master     biz.MBMaster
+	endianType byte
+
+	connLock sync.Mutex
+}
+
+// Dial 按照rawURL建立与从站unitID的连接
+func Dial(rawURL string, unitID byte) (ret *Client, err error) {
+	urlVal, urlErr := url.Parse(rawURL)
+	if urlErr != nil {
+		err = fmt.Errorf("illegal address %s, error:%s", rawURL, urlErr.Error())
+		return
+	}
+
+	queryVal := urlVal.Query()
+	endianType, endianErr := parseUint(queryVal, "endian", 8)
+	if endianErr != nil {
+		err = endianErr
+		return
+	}
+
+	serverAddr := urlVal.Host
+	var master biz.MBMaster
+	switch urlVal.Scheme {
+	case TCPScheme:
+		maxTransactions, maxErr := parseUint(queryVal, "maxTransactions", 16)
+		if maxErr != nil {
+			err = maxErr
+			return
+		}
+		master = biz.NewPipelinedTCPMaster(unitID, byte(endianType), int(maxTransactions))
+	case RTUOverTCPScheme:
+		master = biz.NewRTUMaster(unitID, byte(endianType))
+	case ASCIIOverTCPScheme:
+		master = biz.NewASCIIMaster(unitID, byte(endianType))
+	case RTUScheme, ASCIIScheme:
+		serverAddr = urlVal.Path
+		serialConfig, serialErr := parseSerialConfig(queryVal)
+		if serialErr != nil {
+			err = serialErr
+			return
+		}
+		if urlVal.Scheme == RTUScheme {
+			master = biz.NewSerialRTUMaster(unitID, byte(endianType), serialConfig)
+		} else {
+			master = biz.NewSerialASCIIMaster(unitID, byte(endianType), serialConfig)
+		}
+	default:
+		err = fmt.Errorf("illegal address scheme %s", urlVal.Scheme)
+		return
+	}
+	if serverAddr == "" {
+		err = fmt.Errorf("illegal address %s, missing server address", rawURL)
+		return
+	}
+
+	policy, policyErr := parseRequestPolicy(queryVal)
+	if policyErr != nil {
+		err = policyErr
+		return
+	}
+	master.SetPolicy(policy)
+
+	err = master.Start(serverAddr)
+	if err != nil {
+		return
+	}
+
+	ret = &Client{
+		master:     master,
+		endianType: byte(endianType),
+	}
+	return
+}
+
+func parseUint(queryVal url.Values, name string, bitSize int) (uint64, error) {
+	strVal := queryVal.Get(name)
+	if strVal == "" {
+		return 0, nil
+	}
+
+	uVal, uErr := strconv.ParseUint(strVal, 10, bitSize)
+	if uErr != nil {
+		return 0, fmt.Errorf("illegal %s:%s", name, strVal)
+	}
+
+	return uVal, nil
+}
+
+func parseRequestPolicy(queryVal url.Values) (ret common.RequestPolicy, err error) {
+	timeout, timeoutErr := parseUint(queryVal, "timeout", 31)
+	if timeoutErr != nil {
+		err = timeoutErr
+		return
+	}
+	retryBackoff, backoffErr := parseUint(queryVal, "retryBackoff", 31)
+	if backoffErr != nil {
+		err = backoffErr
+		return
+	}
+	if queryVal.Has("retries") {
+		retries, retriesErr := parseUint(queryVal, "retries", 31)
+		if retriesErr != nil {
+			err = retriesErr
+			return
+		}
+
+		retriesVal := int(retries)
+		ret.Retries = &retriesVal
+	}
+
+	ret.Timeout = int(timeout)
+	ret.RetryBackoff = int(retryBackoff)
+	return
+}
+
+func parseSerialConfig(queryVal url.Values) (ret serial.Config, err error) {
+	ret = serial.DefaultConfig()
+	items := []struct {
+		name  string
+		value *int
+	}{
+		{"baudRate", &ret.BaudRate},
+		{"dataBits", &ret.DataBits},
+		{"stopBits", &ret.StopBits},
+		{"frameSilence", &ret.FrameSilence},
+	}
+	for _, item := range items {
+		if !queryVal.Has(item.name) {
+			continue
+		}
+
+		uVal, uErr := parseUint(queryVal, item.name, 31)
+		if uErr != nil {
+			err = uErr
+			return
+		}
+		*item.value = int(uVal)
+	}
+	if queryVal.Has("parity") {
+		ret.Parity = queryVal.Get("parity")
+	}
+
+	err = ret.Verify()
+	return
+}
+
+// Close 断开与从站的连接
+func (s *Client) Close() {
+	s.master.Stop()
+}
+
+// SetPolicy 设置默认的超时与重试参数
+func (s *Client) SetPolicy(policy common.RequestPolicy) {
+	s.master.SetPolicy(policy)
+}
+
+// Stats 请求统计
+func (s *Client) Stats() Stats {
+	statsVal := s.master.Stats()
+	return Stats{
+		Requests:            statsVal.Requests,
+		Failures:            statsVal.Failures,
+		Exceptions:          statsVal.Exceptions,
+		ConsecutiveFailures: statsVal.ConsecutiveFailures,
+		LastError:           statsVal.LastError,
+		LastActivity:        statsVal.LastActivity,
+	}
+}
+
+// ensureConnect 连接断开后在下一次请求时重连
+func (s *Client) ensureConnect() error {
+	if s.master.IsConnect() {
+		return nil
+	}
+
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	return s.master.ReConnect()
+}
+
+func (s *Client) ReadCoils(ctx context.Context, address, count uint16) (ret []bool, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReadCoils(ctx, nil, address, count)
+	err = checkResponse("ReadCoils", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = decodeBits("ReadCoils", readVal, count)
+	return
+}
+
+func (s *Client) ReadDiscreteInputs(ctx context.Context, address, count uint16) (ret []bool, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReadDiscreteInputs(ctx, nil, address, count)
+	err = checkResponse("ReadDiscreteInputs", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = decodeBits("ReadDiscreteInputs", readVal, count)
+	return
+}
+
+func (s *Client) ReadHoldingRegisters(ctx context.Context, address, count uint16) (ret Registers, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReadHoldingRegisters(ctx, nil, address, count)
+	err = checkResponse("ReadHoldingRegisters", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = s.newRegisters("ReadHoldingRegisters", readVal, count)
+	return
+}
+
+func (s *Client) ReadInputRegisters(ctx context.Context, address, count uint16) (ret Registers, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReadInputRegisters(ctx, nil, address, count)
+	err = checkResponse("ReadInputRegisters", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = s.newRegisters("ReadInputRegisters", readVal, count)
+	return
+}
+
+func (s *Client) WriteSingleCoil(ctx context.Context, address uint16, value bool) (err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	byteVal := model.CoilOFF
+	if value {
+		byteVal = model.CoilON
+	}
+
+	writeAddr, writeData, writeExCode, writeErr := s.master.WriteSingleCoil(ctx, nil, address, byteVal)
+	err = checkResponse("WriteSingleCoil", writeExCode, writeErr)
+	if err != nil {
+		return
+	}
+	if writeAddr != address || !bytes.Equal(byteVal, writeData) {
+		err = fmt.Errorf("WriteSingleCoil failed, mismatch write single coil value")
+	}
+	return
+}
+
+func (s *Client) WriteMultipleCoils(ctx context.Context, address uint16, values []bool) (err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	byteVal, _ := common.AppendBoolArray(nil, values)
+	valCount := uint16(len(values))
+	writeAddr, writeCount, writeExCode, writeErr := s.master.WriteMultipleCoils(ctx, nil, address, valCount, byteVal)
+	err = checkResponse("WriteMultipleCoils", writeExCode, writeErr)
+	if err != nil {
+		return
+	}
+	if writeAddr != address || writeCount != valCount {
+		err = fmt.Errorf("WriteMultipleCoils failed, mismatch write multiple coil value")
+	}
+	return
+}
+
+func (s *Client) WriteSingleRegister(ctx context.Context, address, value uint16) (err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	byteVal := binary.BigEndian.AppendUint16(nil, value)
+	writeAddr, writeData, writeExCode, writeErr := s.master.WriteSingleRegister(ctx, nil, address, byteVal)
+	err = checkResponse("WriteSingleRegister", writeExCode, writeErr)
+	if err != nil {
+		return
+	}
+	if writeAddr != address || !bytes.Equal(byteVal, writeData) {
+		err = fmt.Errorf("WriteSingleRegister failed, mismatch write single register value")
+	}
+	return
+}
+
+// WriteMultipleRegisters data可由RegisterWriter按照类型编码
+func (s *Client) WriteMultipleRegisters(ctx context.Context, address uint16, data []byte) (err error) {
+	if len(data) == 0 || len(data)%2 != 0 {
+		err = fmt.Errorf("WriteMultipleRegisters failed, illegal data length:%d", len(data))
+		return
+	}
+
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	valCount := uint16(len(data) / 2)
+	writeAddr, writeCount, writeExCode, writeErr := s.master.WriteMultipleRegisters(ctx, nil, address, valCount, data)
+	err = checkResponse("WriteMultipleRegisters", writeExCode, writeErr)
+	if err != nil {
+		return
+	}
+	if writeAddr != address || writeCount != valCount {
+		err = fmt.Errorf("WriteMultipleRegisters failed, mismatch write multiple register value")
+	}
+	return
+}
+
+func (s *Client) MaskWriteRegister(ctx context.Context, address, andMask, orMask uint16) (err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	andByteVal := binary.BigEndian.AppendUint16(nil, andMask)
+	orByteVal := binary.BigEndian.AppendUint16(nil, orMask)
+	maskAddr, maskAnd, maskOr, maskExCode, maskErr := s.master.MaskWriteRegister(ctx, nil, address, andByteVal, orByteVal)
+	err = checkResponse("MaskWriteRegister", maskExCode, maskErr)
+	if err != nil {
+		return
+	}
+	if maskAddr != address || !bytes.Equal(andByteVal, maskAnd) || !bytes.Equal(orByteVal, maskOr) {
+		err = fmt.Errorf("MaskWriteRegister failed, mismatch mask write register value")
+	}
+	return
+}
+
+func (s *Client) ReadWriteMultipleRegisters(ctx context.Context, readAddr, readCount, writeAddr uint16, writeData []byte) (ret Registers, err error) {
+	if len(writeData) == 0 || len(writeData)%2 != 0 {
+		err = fmt.Errorf("ReadWriteMultipleRegisters failed, illegal data length:%d", len(writeData))
+		return
+	}
+
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	writeCount := uint16(len(writeData) / 2)
+	readVal, readExCode, readErr := s.master.ReadWriteMultipleRegisters(ctx, nil, readAddr, readCount, writeAddr, writeCount, writeData)
+	err = checkResponse("ReadWriteMultipleRegisters", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = s.newRegisters("ReadWriteMultipleRegisters", readVal, readCount)
+	return
+}
+
+func (s *Client) ReadExceptionStatus(ctx context.Context) (ret byte, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readStatus, readExCode, readErr := s.master.ReadExceptionStatus(ctx, nil)
+	err = checkResponse("ReadExceptionStatus", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret = readStatus
+	return
+}
+
+func (s *Client) Diagnostics(ctx context.Context, subFuncCode uint16, data []byte) (ret []byte, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	retSubFuncCode, retData, retExCode, retErr := s.master.Diagnostics(ctx, nil, subFuncCode, data)
+	err = checkResponse("Diagnostics", retExCode, retErr)
+	if err != nil {
+		return
+	}
+	if retSubFuncCode != subFuncCode {
+		err = fmt.Errorf("Diagnostics failed, mismatch sub function code")
+		return
+	}
+
+	ret = retData
+	return
+}
+
+func (s *Client) GetCommEventCounter(ctx context.Context) (status, eventCount uint16, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readStatus, readEventCount, readExCode, readErr := s.master.GetCommEventCounter(ctx, nil)
+	err = checkResponse("GetCommEventCounter", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	status = readStatus
+	eventCount = readEventCount
+	return
+}
+
+func (s *Client) GetCommEventLog(ctx context.Context) (status, eventCount, messageCount uint16, events []byte, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readStatus, readEventCount, readMessageCount, readEvents, readExCode, readErr := s.master.GetCommEventLog(ctx, nil)
+	err = checkResponse("GetCommEventLog", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	status = readStatus
+	eventCount = readEventCount
+	messageCount = readMessageCount
+	events = readEvents
+	return
+}
+
+func (s *Client) ReportSlaveID(ctx context.Context) (ret []byte, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReportSlaveID(ctx, nil)
+	err = checkResponse("ReportSlaveID", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret = readVal
+	return
+}
+
+func (s *Client) ReadFileRecord(ctx context.Context, items []*common.ReadItem) (ret [][]byte, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readVal, readExCode, readErr := s.master.ReadFileRecord(ctx, nil, items)
+	err = checkResponse("ReadFileRecord", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret = readVal
+	return
+}
+
+func (s *Client) WriteFileRecord(ctx context.Context, items []*common.WriteItem) (err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	writeExCode, writeErr := s.master.WriteFileRecord(ctx, nil, items)
+	err = checkResponse("WriteFileRecord", writeExCode, writeErr)
+	return
+}
+
+func (s *Client) ReadFIFOQueue(ctx context.Context, address uint16) (ret Registers, err error) {
+	err = s.ensureConnect()
+	if err != nil {
+		return
+	}
+
+	readCount, readVal, readExCode, readErr := s.master.ReadFIFOQueue(ctx, nil, address)
+	err = checkResponse("ReadFIFOQueue", readExCode, readErr)
+	if err != nil {
+		return
+	}
+
+	ret, err = s.newRegisters("ReadFIFOQueue", readVal, readCount)
+	return
+}
+
+func (s *Client) newRegisters(name string, data []byte, count uint16) (ret Registers, err error) {
+	if len(data) != int(count)*2 {
+		err = fmt.Errorf("%s failed, illegal read value count", name)
+		return
+	}
+
+	ret = NewRegisters(data, s.endianType)
+	return
+}
+
+func checkResponse(name string, exCode byte, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s failed, %w", name, err)
+	}
+	if exCode != model.SuccessCode {
+		return &ExceptionError{Name: name, ExceptionCode: exCode}
+	}
+
+	return nil
+}
+
+func decodeBits(name string, data []byte, count uint16) (ret []bool, err error) {
+	boolVal, _ := common.BytesToBoolArray(data)
+	if len(boolVal) < int(count) {
+		err = fmt.Errorf("%s failed, illegal read value count", name)
+		return
+	}
+
+	ret = boolVal[:count]
+	return
+}
+
+// ExceptionError 从站返回的异常应答
+type ExceptionError struct {
+	Name          string
+	ExceptionCode byte
+}
+
+func (s *ExceptionError) Error() string {
+	return fmt.Sprintf("%s failed, modbus exception code:%v", s.Name, s.ExceptionCode)
+}
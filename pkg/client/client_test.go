@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/muidea/quickModbus/pkg/common"
+	"github.com/muidea/quickModbus/pkg/serial"
+)
+
+func TestDialIllegalAddress(t *testing.T) {
+	addrs := []string{
+		"udp://127.0.0.1:502",
+		"tcp://",
+		"tcp://127.0.0.1:502?endian=abc",
+		"tcp://127.0.0.1:502?maxTransactions=-1",
+		"rtu://?baudRate=9600",
+		"rtu:///dev/ttyUSB0?parity=X",
+	}
+	for _, addr := range addrs {
+		_, err := Dial(addr, 1)
+		if err == nil {
+			t.Errorf("dial %s should fail", addr)
+		}
+	}
+}
+
+func TestParseRequestPolicy(t *testing.T) {
+	queryVal, _ := url.ParseQuery("timeout=300&retries=0&retryBackoff=50")
+	policy, err := parseRequestPolicy(queryVal)
+	if err != nil {
+		t.Errorf("parseRequestPolicy failed, error:%s", err.Error())
+		return
+	}
+	if policy.Timeout != 300 || policy.RetryBackoff != 50 || policy.Retries == nil || *policy.Retries != 0 {
+		t.Errorf("parseRequestPolicy failed, policy:%+v", policy)
+	}
+
+	policy, err = parseRequestPolicy(url.Values{})
+	if err != nil || policy.Retries != nil || policy.Timeout != 0 {
+		t.Errorf("parseRequestPolicy default failed, policy:%+v", policy)
+	}
+}
+
+func TestParseSerialConfig(t *testing.T) {
+	queryVal, _ := url.ParseQuery("baudRate=19200&parity=N&stopBits=2")
+	config, err := parseSerialConfig(queryVal)
+	if err != nil {
+		t.Errorf("parseSerialConfig failed, error:%s", err.Error())
+		return
+	}
+
+	expectVal := serial.Config{BaudRate: 19200, DataBits: 8, Parity: serial.NoneParity, StopBits: 2}
+	if config != expectVal {
+		t.Errorf("parseSerialConfig failed, expect:%+v, really:%+v", expectVal, config)
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	byteVal, err := NewRegisterWriter(common.CDABEndian).Float32(12.5).Int32(-2).Bytes()
+	if err != nil {
+		t.Errorf("RegisterWriter failed, error:%s", err.Error())
+		return
+	}
+
+	registers := NewRegisters(byteVal, common.CDABEndian)
+	if registers.Count() != 4 {
+		t.Errorf("illegal register count:%d", registers.Count())
+		return
+	}
+
+	fVal, fErr := registers.Float32s()
+	if fErr != nil || fVal[0] != 12.5 {
+		t.Errorf("Float32s failed, value:%v", fVal)
+	}
+	iVal, iErr := NewRegisters(byteVal[4:], common.CDABEndian).Int32s()
+	if iErr != nil || iVal[0] != -2 {
+		t.Errorf("Int32s failed, value:%v", iVal)
+	}
+
+	_, err = NewRegisters(byteVal[:6], common.ABCDEndian).Float32s()
+	if err == nil {
+		t.Error("Float32s should fail for odd register count")
+	}
+	_, err = registers.Float64s()
+	if err != nil {
+		t.Errorf("Float64s failed, error:%s", err.Error())
+	}
+
+	uVal, uErr := NewRegisters([]byte{0x12, 0x34, 0x56, 0x78}, common.BAEndian).Uint16s()
+	if uErr != nil || uVal[0] != 0x3412 || uVal[1] != 0x7856 {
+		t.Errorf("Uint16s failed, value:%v", uVal)
+	}
+
+	_, err = NewRegisterWriter(0xFF).Uint32(1).Uint16(1).Bytes()
+	if err == nil {
+		t.Error("RegisterWriter should fail with illegal endian")
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, error:%s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		// 只接受连接,不返回应答
+		for {
+			conn, connErr := listener.Accept()
+			if connErr != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	clientPtr, err := Dial("tcp://"+listener.Addr().String()+"?timeout=50&retries=0", 1)
+	if err != nil {
+		t.Fatalf("Dial failed, error:%s", err.Error())
+	}
+	defer clientPtr.Close()
+
+	_, err = clientPtr.ReadCoils(context.Background(), 0, 8)
+	if !errors.Is(err, ErrTimeout) || errors.Is(err, ErrRequestCanceled) {
+		t.Errorf("ReadCoils should timeout, error:%v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = clientPtr.ReadCoils(ctx, 0, 8)
+	if !errors.Is(err, ErrRequestCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadCoils should be canceled, error:%v", err)
+	}
+
+	statsVal := clientPtr.Stats()
+	if statsVal.Requests != 1 || statsVal.Failures != 1 || statsVal.LastError == "" {
+		t.Errorf("Stats failed, stats:%+v", statsVal)
+	}
+}
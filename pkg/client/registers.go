@@ -0,0 +1,154 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// Registers 读取到的寄存器原始数据,按照字节序解码为具体类型
+type Registers struct {
+	data       []byte
+	endianType byte
+}
+
+func NewRegisters(data []byte, endianType byte) Registers {
+	return Registers{data: data, endianType: endianType}
+}
+
+// WithEndian 以指定的字节序解码
+func (s Registers) WithEndian(endianType byte) Registers {
+	s.endianType = endianType
+	return s
+}
+
+// Bytes 寄存器原始数据,每个寄存器两个字节
+func (s Registers) Bytes() []byte {
+	return s.data
+}
+
+// Count 寄存器个数
+func (s Registers) Count() int {
+	return len(s.data) / 2
+}
+
+func (s Registers) checkSize(sizeVal int) error {
+	if len(s.data)%sizeVal != 0 {
+		return fmt.Errorf("illegal register count %d for %d bytes value", s.Count(), sizeVal)
+	}
+
+	return nil
+}
+
+func (s Registers) Uint16s() ([]uint16, error) {
+	return common.BytesToUint16Array(s.data, s.endianType)
+}
+
+func (s Registers) Int16s() ([]int16, error) {
+	return common.BytesToInt16Array(s.data, s.endianType)
+}
+
+func (s Registers) Uint32s() ([]uint32, error) {
+	if err := s.checkSize(4); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToUint32Array(s.data, s.endianType)
+}
+
+func (s Registers) Int32s() ([]int32, error) {
+	if err := s.checkSize(4); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToInt32Array(s.data, s.endianType)
+}
+
+func (s Registers) Float32s() ([]float32, error) {
+	if err := s.checkSize(4); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToFloat32Array(s.data, s.endianType)
+}
+
+func (s Registers) Uint64s() ([]uint64, error) {
+	if err := s.checkSize(8); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToUint64Array(s.data, s.endianType)
+}
+
+func (s Registers) Int64s() ([]int64, error) {
+	if err := s.checkSize(8); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToInt64Array(s.data, s.endianType)
+}
+
+func (s Registers) Float64s() ([]float64, error) {
+	if err := s.checkSize(8); err != nil {
+		return nil, err
+	}
+
+	return common.BytesToFloat64Array(s.data, s.endianType)
+}
+
+// RegisterWriter 按照字节序将数值编码为寄存器数据,出错后忽略后续写入
+type RegisterWriter struct {
+	data       []byte
+	endianType byte
+	err        error
+}
+
+func NewRegisterWriter(endianType byte) *RegisterWriter {
+	return &RegisterWriter{endianType: endianType}
+}
+
+func (s *RegisterWriter) append(appendFunc func() ([]byte, error)) *RegisterWriter {
+	if s.err != nil {
+		return s
+	}
+
+	s.data, s.err = appendFunc()
+	return s
+}
+
+func (s *RegisterWriter) Uint16(val uint16) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendUint16(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Int16(val int16) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendInt16(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Uint32(val uint32) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendUint32(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Int32(val int32) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendInt32(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Float32(val float32) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendFloat32(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Uint64(val uint64) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendUint64(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Int64(val int64) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendInt64(s.data, val, s.endianType) })
+}
+
+func (s *RegisterWriter) Float64(val float64) *RegisterWriter {
+	return s.append(func() ([]byte, error) { return common.AppendFloat64(s.data, val, s.endianType) })
+}
+
+// Bytes 编码结果,可直接用于WriteMultipleRegisters
+func (s *RegisterWriter) Bytes() ([]byte, error) {
+	return s.data, s.err
+}
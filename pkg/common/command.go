@@ -0,0 +1,95 @@
+package common
+
+import (
+	"fmt"
+	"math"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+// WriteCommandEvent 其它模块请求写入从站的事件,事件数据为*WriteCommand,结果为从站返回的异常码
+const WriteCommandEvent = MasterModule + "/write"
+
+/*
+WriteCommand的写入功能
+*/
+const (
+	WriteSingleCoilFunction        = "writeSingleCoil"
+	WriteSingleRegisterFunction    = "writeSingleRegister"
+	WriteMultipleCoilsFunction     = "writeMultipleCoils"
+	WriteMultipleRegistersFunction = "writeMultipleRegisters"
+)
+
+/*
+WriteCommand 写入命令,Tag非空时按照Tag写入Value,否则按照Function写入从站SlaveID的Address
+writeSingleCoil的Value为bool,writeSingleRegister的Value为-32768~65535的整数,
+writeMultipleCoils的Values为bool数组,writeMultipleRegisters的Values为数值数组,
+按照ValueType与EndianType编码,ValueType为空时为UInt16Value
+*/
+type WriteCommand struct {
+	ID         string        `json:"id,omitempty"`
+	Tag        string        `json:"tag,omitempty"`
+	SlaveID    string        `json:"slaveID,omitempty"`
+	Function   string        `json:"function,omitempty"`
+	Address    uint16        `json:"address,omitempty"`
+	Value      interface{}   `json:"value,omitempty"`
+	Values     []interface{} `json:"values,omitempty"`
+	ValueType  uint16        `json:"valueType,omitempty"`
+	EndianType byte          `json:"endianType,omitempty"`
+}
+
+// WriteCommandResult 写入命令的执行结果,ID与命令的ID一致
+type WriteCommandResult struct {
+	cd.Result
+	ID            string `json:"id,omitempty"`
+	ExceptionCode byte   `json:"exceptionCode,omitempty"`
+}
+
+// RegisterValue writeSingleRegister的写入值,负数按照Int16补码写入
+func (s *WriteCommand) RegisterValue() (ret uint16, err error) {
+	floatVal, ok := s.Value.(float64)
+	if !ok || floatVal != math.Trunc(floatVal) || floatVal < math.MinInt16 || floatVal > math.MaxUint16 {
+		err = fmt.Errorf("illegal register value %v", s.Value)
+		return
+	}
+
+	if floatVal < 0 {
+		ret = uint16(int16(floatVal))
+		return
+	}
+
+	ret = uint16(floatVal)
+	return
+}
+
+func (s *WriteCommand) BoolValues() (ret []bool, err error) {
+	for _, val := range s.Values {
+		boolVal, ok := val.(bool)
+		if !ok {
+			err = fmt.Errorf("illegal coil value %v", val)
+			return
+		}
+		ret = append(ret, boolVal)
+	}
+
+	if len(ret) == 0 {
+		err = fmt.Errorf("illegal coil values, values is empty")
+	}
+	return
+}
+
+func (s *WriteCommand) FloatValues() (ret []float64, err error) {
+	for _, val := range s.Values {
+		floatVal, ok := val.(float64)
+		if !ok {
+			err = fmt.Errorf("illegal register value %v", val)
+			return
+		}
+		ret = append(ret, floatVal)
+	}
+
+	if len(ret) == 0 {
+		err = fmt.Errorf("illegal register values, values is empty")
+	}
+	return
+}
@@ -86,19 +86,19 @@ func TestUint16(t *testing.T) {
 	byteVal := []byte{}
 	var byteErr error
 
-	byteVal, byteErr = AppendUint16(byteVal, uVal1)
+	byteVal, byteErr = AppendUint16(byteVal, uVal1, ABEndian)
 	if byteErr != nil {
 		t.Errorf("AppendUint16 failed, error:%s", byteErr.Error())
 		return
 	}
 
-	byteVal, byteErr = AppendUint16(byteVal, uVal2)
+	byteVal, byteErr = AppendUint16(byteVal, uVal2, ABEndian)
 	if byteErr != nil {
 		t.Errorf("AppendUint16 failed, error:%s", byteErr.Error())
 		return
 	}
 
-	u16Val, u16Err := BytesToUint16Array(byteVal)
+	u16Val, u16Err := BytesToUint16Array(byteVal, ABEndian)
 	if u16Err != nil {
 		t.Errorf("BytesToUint16Array failed, error:%s", byteErr.Error())
 		return
@@ -113,7 +113,7 @@ func TestUint16(t *testing.T) {
 		return
 	}
 
-	u16Val, u16Err = BytesToUint16Array(byteVal)
+	u16Val, u16Err = BytesToUint16Array(byteVal, ABEndian)
 	if u16Err != nil {
 		t.Errorf("BytesToUint16Array failed, error:%s", byteErr.Error())
 		return
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/history"
+)
+
+const QueryTagHistory = "/tags/:name/history"
+
+const (
+	defaultHistoryRange   = time.Hour
+	defaultHistoryBuckets = 100
+)
+
+/*
+HistoryRequest 历史查询参数,通过查询参数from、to、agg、step传递
+from与to为RFC3339时间或者Unix秒,包含在查询区间内,to为空时为当前时间,from为空时为to之前1小时
+agg为min、max、avg时按照step(如30s、5m)从from开始降采样,step为空时将查询区间均分为100段,
+agg为空时返回原始取值
+*/
+type HistoryRequest struct {
+	From time.Time
+	To   time.Time
+	Agg  string
+	Step time.Duration
+}
+
+func ParseHistoryRequest(queryVal url.Values) (ret *HistoryRequest, err error) {
+	requestVal := &HistoryRequest{To: time.Now(), Agg: queryVal.Get("agg")}
+	if val := queryVal.Get("to"); val != "" {
+		requestVal.To, err = parseHistoryTime(val)
+		if err != nil {
+			return
+		}
+	}
+	requestVal.From = requestVal.To.Add(-defaultHistoryRange)
+	if val := queryVal.Get("from"); val != "" {
+		requestVal.From, err = parseHistoryTime(val)
+		if err != nil {
+			return
+		}
+	}
+	if requestVal.From.After(requestVal.To) {
+		err = fmt.Errorf("illegal history range, from is after to")
+		return
+	}
+
+	if requestVal.Agg == "" {
+		ret = requestVal
+		return
+	}
+	if !history.ValidAgg(requestVal.Agg) {
+		err = fmt.Errorf("illegal agg '%s'", requestVal.Agg)
+		return
+	}
+
+	if val := queryVal.Get("step"); val != "" {
+		requestVal.Step, err = time.ParseDuration(val)
+		if err != nil || requestVal.Step <= 0 {
+			err = fmt.Errorf("illegal step '%s'", val)
+			return
+		}
+	} else {
+		requestVal.Step = max(requestVal.To.Sub(requestVal.From)/defaultHistoryBuckets, time.Second).Truncate(time.Second)
+	}
+
+	ret = requestVal
+	return
+}
+
+func parseHistoryTime(val string) (ret time.Time, err error) {
+	if unixVal, unixErr := strconv.ParseInt(val, 10, 64); unixErr == nil {
+		ret = time.Unix(unixVal, 0)
+		return
+	}
+
+	ret, err = time.Parse(time.RFC3339Nano, val)
+	if err != nil {
+		err = fmt.Errorf("illegal history time '%s'", val)
+	}
+	return
+}
+
+// Values 转换成查询参数
+func (s *HistoryRequest) Values() url.Values {
+	ret := url.Values{}
+	ret.Set("from", s.From.Format(time.RFC3339Nano))
+	ret.Set("to", s.To.Format(time.RFC3339Nano))
+	if s.Agg != "" {
+		ret.Set("agg", s.Agg)
+		ret.Set("step", s.Step.String())
+	}
+
+	return ret
+}
+
+/*
+HistoryPoint 历史取值,Value为空时表示没有取值
+原始取值的Quality与TagValue一致,降采样时Timestamp为时间段的起始时间,Count为参与聚合的取值数
+*/
+type HistoryPoint struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Value     interface{} `json:"value"`
+	Quality   string      `json:"quality,omitempty"`
+	Count     int         `json:"count,omitempty"`
+}
+
+type QueryTagHistoryResponse struct {
+	cd.Result
+	Name   string          `json:"name"`
+	Agg    string          `json:"agg,omitempty"`
+	Step   string          `json:"step,omitempty"`
+	Points []*HistoryPoint `json:"points"`
+}
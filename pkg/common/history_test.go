@@ -0,0 +1,41 @@
+package common
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryRequest(t *testing.T) {
+	queryVal, _ := url.ParseQuery("from=2026-01-01T08:00:00Z&to=1767258000&agg=avg&step=5m")
+	requestVal, err := ParseHistoryRequest(queryVal)
+	if err != nil {
+		t.Fatalf("ParseHistoryRequest failed, error:%s", err.Error())
+	}
+	from := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	if !requestVal.From.Equal(from) || !requestVal.To.Equal(from.Add(time.Hour)) || requestVal.Agg != "avg" || requestVal.Step != 5*time.Minute {
+		t.Errorf("ParseHistoryRequest failed, request:%+v", requestVal)
+	}
+	if parseVal, _ := ParseHistoryRequest(requestVal.Values()); !parseVal.From.Equal(requestVal.From) || !parseVal.To.Equal(requestVal.To) || parseVal.Step != requestVal.Step {
+		t.Errorf("Values failed, query:%s", requestVal.Values().Encode())
+	}
+
+	// 未指定step时将查询区间均分为100段
+	queryVal, _ = url.ParseQuery("from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z&agg=max")
+	requestVal, _ = ParseHistoryRequest(queryVal)
+	if requestVal == nil || requestVal.Step != 864*time.Second {
+		t.Errorf("ParseHistoryRequest default step failed, request:%+v", requestVal)
+	}
+
+	requestVal, _ = ParseHistoryRequest(url.Values{})
+	if requestVal == nil || requestVal.To.Sub(requestVal.From) != time.Hour || requestVal.Agg != "" {
+		t.Errorf("ParseHistoryRequest default range failed, request:%+v", requestVal)
+	}
+
+	for _, val := range []string{"agg=sum", "agg=min&step=-1s", "from=yesterday", "from=1767258000&to=1767254400"} {
+		queryVal, _ = url.ParseQuery(val)
+		if _, err = ParseHistoryRequest(queryVal); err == nil {
+			t.Errorf("ParseHistoryRequest should fail, query:%s", val)
+		}
+	}
+}
@@ -1,9 +1,25 @@
 package common
 
-import cd "github.com/muidea/magicCommon/def"
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/serial"
+)
 
 const MasterModule = "/kernel/master"
 
+/*
+RequestCanceled 调用方取消请求或者调用方的截止时间已到
+RequestTimeout 重试后仍在超时时间内没有收到从站应答
+其他的链路失败返回cd.UnExpected
+*/
+const (
+	RequestCanceled cd.ErrorCode = 500100
+	RequestTimeout  cd.ErrorCode = 500101
+)
+
 const (
 	RawValue     = 0
 	BoolValue    = 1
@@ -36,13 +52,20 @@ const (
 	BAEndian      = 6
 )
 
+/*
+ModbusRTU和ModbusASCII直接使用本地串口,SlaveAddr为串口设备路径,如/dev/ttyUSB0
+*/
 const (
 	ModbusTcp          = 0
 	ModbusRTUOverTcp   = 1
 	ModbusASCIIOverTcp = 2
+	ModbusRTU          = 3
+	ModbusASCII        = 4
 )
 
 const (
+	ListSlave                  = "/slave"
+	QuerySlave                 = "/slave/:id"
 	ConnectSlave               = "/slave/connect"
 	DisConnectSlave            = "/slave/:id/disconnect"
 	ReadCoils                  = "/slave/:id/coils/read"
@@ -63,13 +86,54 @@ const (
 	MaskWriteRegister          = "/slave/:id/register/write/mask"
 	ReadWriteMultipleRegisters = "/slave/:id/registers/rw"
 	ReadFIFOQueue              = "/slave/:id/queue/read"
+	QuerySlaveStatus           = "/slave/:id/status"
+)
+
+// OpenAPI 接口描述文档
+const OpenAPI = ApiVersion + "/openapi.json"
+
+// Metrics Prometheus文本格式的指标
+const Metrics = "/metrics"
+
+/*
+SlaveConnecting 正在建立连接
+SlaveOnline 链路正常
+SlaveDegraded 链路已连接,但最近的请求超时或者发送失败
+SlaveOffline 链路断开,等待后台重连
+*/
+const (
+	SlaveConnecting = "connecting"
+	SlaveOnline     = "online"
+	SlaveDegraded   = "degraded"
+	SlaveOffline    = "offline"
 )
 
+/*
+RequestPolicy 请求的超时与重试参数,Timeout与RetryBackoff单位为毫秒
+ConnectSlaveRequest中的取值作为该从站的默认值,读写请求中的非零取值覆盖从站默认值
+Retries为空时沿用从站默认值,重试间隔按照重试次数倍增
+*/
+type RequestPolicy struct {
+	Timeout      int  `json:"timeout,omitempty"`
+	Retries      *int `json:"retries,omitempty"`
+	RetryBackoff int  `json:"retryBackoff,omitempty"`
+}
+
+/*
+MaxTransactions 仅对ModbusTcp有效,同一连接上允许同时发出的事务数,默认为1
+串行链路同一时刻只能有一个事务
+ReadCoalesce为扫描组读取该从站时的合并参数
+*/
 type ConnectSlaveRequest struct {
-	SlaveAddr  string `json:"slaveAddr"`
-	DeviceID   byte   `json:"deviceID"`
-	DeviceType byte   `json:"deviceType"`
-	EndianType byte   `json:"endianType"`
+	RequestPolicy
+	ReadCoalesce
+
+	SlaveAddr       string         `json:"slaveAddr"`
+	DeviceID        byte           `json:"deviceID"`
+	DeviceType      byte           `json:"deviceType"`
+	EndianType      byte           `json:"endianType"`
+	SerialConfig    *serial.Config `json:"serialConfig,omitempty"`
+	MaxTransactions int            `json:"maxTransactions,omitempty"`
 }
 
 type ConnectSlaveResponse struct {
@@ -78,6 +142,8 @@ type ConnectSlaveResponse struct {
 }
 
 type ReadCoilsRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 	Count   uint16 `json:"count"`
 }
@@ -89,6 +155,8 @@ type ReadCoilsResponse struct {
 }
 
 type ReadDiscreteInputsRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 	Count   uint16 `json:"count"`
 }
@@ -100,6 +168,8 @@ type ReadDiscreteInputsResponse struct {
 }
 
 type ReadHoldingRegistersRequest struct {
+	RequestPolicy
+
 	Address    uint16 `json:"address"`
 	Count      uint16 `json:"count"`
 	ValueType  uint16 `json:"valueType"`
@@ -113,6 +183,8 @@ type ReadHoldingRegistersResponse struct {
 }
 
 type ReadReadInputRegistersRequest struct {
+	RequestPolicy
+
 	Address    uint16 `json:"address"`
 	Count      uint16 `json:"count"`
 	ValueType  uint16 `json:"valueType"`
@@ -126,6 +198,8 @@ type ReadReadInputRegistersResponse struct {
 }
 
 type WriteSingleCoilRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 	Value   bool   `json:"value"`
 }
@@ -136,6 +210,8 @@ type WriteSingleCoilResponse struct {
 }
 
 type WriteSingleRegisterRequest struct {
+	RequestPolicy
+
 	Address    uint16 `json:"address"`
 	Value      uint16 `json:"value"`
 	EndianType byte   `json:"endianType"`
@@ -147,6 +223,7 @@ type WriteSingleRegisterResponse struct {
 }
 
 type ReadExceptionStatusRequest struct {
+	RequestPolicy
 }
 
 type ReadExceptionStatusResponse struct {
@@ -156,6 +233,8 @@ type ReadExceptionStatusResponse struct {
 }
 
 type DiagnosticsRequest struct {
+	RequestPolicy
+
 	Function uint16 `json:"function"`
 	Value    string `json:"value"`
 }
@@ -167,6 +246,7 @@ type DiagnosticsResponse struct {
 }
 
 type GetCommEventCounterRequest struct {
+	RequestPolicy
 }
 
 type GetCommEventCounterResponse struct {
@@ -177,6 +257,7 @@ type GetCommEventCounterResponse struct {
 }
 
 type GetCommEventLogRequest struct {
+	RequestPolicy
 }
 
 type GetCommEventLogResponse struct {
@@ -189,6 +270,8 @@ type GetCommEventLogResponse struct {
 }
 
 type WriteMultipleCoilsRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 	Values  []bool `json:"values"`
 }
@@ -199,6 +282,8 @@ type WriteMultipleCoilsResponse struct {
 }
 
 type WriteMultipleRegistersRequest struct {
+	RequestPolicy
+
 	Address    uint16    `json:"address"`
 	Values     []float64 `json:"values"`
 	ValueType  uint16    `json:"valueType"`
@@ -211,6 +296,7 @@ type WriteMultipleRegistersResponse struct {
 }
 
 type ReportSlaveIDRequest struct {
+	RequestPolicy
 }
 
 type ReportSlaveIDResponse struct {
@@ -226,6 +312,8 @@ type ReadItem struct {
 }
 
 type ReadFileRecordRequest struct {
+	RequestPolicy
+
 	Items []*ReadItem `json:"items"`
 }
 
@@ -242,6 +330,8 @@ type WriteItem struct {
 }
 
 type WriteFileRecordRequest struct {
+	RequestPolicy
+
 	Items []*WriteItem `json:"items"`
 }
 
@@ -251,6 +341,8 @@ type WriteFileRecordResponse struct {
 }
 
 type MaskWriteRegisterRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 	AndMask uint16 `json:"andMask"`
 	OrMask  uint16 `json:"orMask"`
@@ -262,6 +354,8 @@ type MaskWriteRegisterResponse struct {
 }
 
 type ReadWriteMultipleRegistersRequest struct {
+	RequestPolicy
+
 	ReadAddress    uint16    `json:"readAddress"`
 	ReadCount      uint16    `json:"readCount"`
 	ReadValueType  uint16    `json:"readValueType"`
@@ -278,6 +372,8 @@ type ReadWriteMultipleRegistersResponse struct {
 }
 
 type ReadFIFOQueueRequest struct {
+	RequestPolicy
+
 	Address uint16 `json:"address"`
 }
 
@@ -286,3 +382,42 @@ type ReadFIFOQueueResponse struct {
 	ExceptionCode byte     `json:"exceptionCode"`
 	Data          []string `json:"data"`
 }
+
+// SlaveView 从站连接参数及请求统计,Failures为超时或者发送失败的请求数,Exceptions为返回异常码的请求数
+type SlaveView struct {
+	SlaveID      string     `json:"slaveID"`
+	SlaveAddr    string     `json:"slaveAddr"`
+	DeviceID     byte       `json:"deviceID"`
+	DeviceType   byte       `json:"deviceType"`
+	EndianType   byte       `json:"endianType"`
+	Status       string     `json:"status"`
+	ConnectTime  time.Time  `json:"connectTime"`
+	LastActivity *time.Time `json:"lastActivity,omitempty"`
+	Requests     uint64     `json:"requests"`
+	Failures     uint64     `json:"failures"`
+	Exceptions   uint64     `json:"exceptions"`
+}
+
+type ListSlaveResponse struct {
+	cd.Result
+	Slaves []*SlaveView `json:"slaves"`
+}
+
+type QuerySlaveResponse struct {
+	cd.Result
+	Slave *SlaveView `json:"slave"`
+}
+
+// SlaveStatus ReconnectCount为累计的重连次数,只增不减,FailedReconnects为上次连接成功之后连续重连失败的次数
+type SlaveStatus struct {
+	Status              string `json:"status"`
+	ReconnectCount      int    `json:"reconnectCount"`
+	FailedReconnects    int    `json:"failedReconnects"`
+	ConsecutiveFailures uint64 `json:"consecutiveFailures"`
+	LastError           string `json:"lastError"`
+}
+
+type QuerySlaveStatusResponse struct {
+	cd.Result
+	SlaveStatus
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+// MinScanInterval 扫描组的最小扫描周期,单位为毫秒
+const MinScanInterval = 100
+
+const (
+	ListScanGroup   = "/groups"
+	SaveScanGroup   = "/groups"
+	DeleteScanGroup = "/groups/:name"
+)
+
+// ScanGroup 扫描组,按照Interval(毫秒)周期读取ScanGroup为该组的全部Tag
+type ScanGroup struct {
+	Name     string `json:"name"`
+	Interval int    `json:"interval"`
+}
+
+func (s *ScanGroup) Validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, "/?# ") {
+		return fmt.Errorf("illegal scan group name '%s'", s.Name)
+	}
+	if s.Interval < MinScanInterval {
+		return fmt.Errorf("illegal scan group %s, interval:%d less than %dms", s.Name, s.Interval, MinScanInterval)
+	}
+
+	return nil
+}
+
+type ListScanGroupResponse struct {
+	cd.Result
+	Groups []*ScanGroup `json:"groups"`
+}
+
+type SaveScanGroupResponse struct {
+	cd.Result
+	Group *ScanGroup `json:"group"`
+}
+
+/*
+RegisterReadLimit 单次读取寄存器数量的协议上限
+BitReadLimit 单次读取线圈或者离散输入数量的协议上限
+*/
+const (
+	RegisterReadLimit = 125
+	BitReadLimit      = 2000
+)
+
+/*
+ReadCoalesce 扫描时合并读取的参数,随ConnectSlaveRequest按从站配置
+MaxReadRegisters与MaxReadBits为单次读取的最大寄存器数与线圈/离散输入数,为0或者超过协议上限时取协议上限
+ReadGap为允许合并的地址间隙,间隙内的地址一并读取后丢弃,为0时只合并相邻的地址
+*/
+type ReadCoalesce struct {
+	MaxReadRegisters int `json:"maxReadRegisters,omitempty"`
+	MaxReadBits      int `json:"maxReadBits,omitempty"`
+	ReadGap          int `json:"readGap,omitempty"`
+}
+
+func (s *ReadCoalesce) Validate() error {
+	if s.MaxReadRegisters < 0 || s.MaxReadBits < 0 || s.ReadGap < 0 {
+		return fmt.Errorf("illegal read coalesce, maxReadRegisters:%d, maxReadBits:%d, readGap:%d", s.MaxReadRegisters, s.MaxReadBits, s.ReadGap)
+	}
+
+	return nil
+}
+
+func (s *ReadCoalesce) blockLimit(table string) int {
+	limit, maxVal := RegisterReadLimit, s.MaxReadRegisters
+	if table == CoilTable || table == DiscreteInputTable {
+		limit, maxVal = BitReadLimit, s.MaxReadBits
+	}
+	if maxVal > 0 && maxVal < limit {
+		limit = maxVal
+	}
+
+	return limit
+}
+
+// ReadBlock 合并后的一次读取,Count以寄存器或者位为单位,Tags按照地址排序
+type ReadBlock struct {
+	SlaveID string
+	Table   string
+	Address uint16
+	Count   uint16
+	Tags    []*Tag
+}
+
+// Offset Tag在读取结果中的起始位置,以寄存器或者位为单位
+func (s *ReadBlock) Offset(tag *Tag) int {
+	return int(tag.Address) - int(s.Address)
+}
+
+/*
+PlanReadBlocks 同一从站同一数据区的Tag按照地址合并成读取块,coalesce返回从站的合并参数
+单个Tag的长度超过从站的最大读取数量时单独读取
+*/
+func PlanReadBlocks(tags []*Tag, coalesce func(slaveID string) ReadCoalesce) (ret []*ReadBlock) {
+	sortTags := make([]*Tag, len(tags))
+	copy(sortTags, tags)
+	sort.SliceStable(sortTags, func(i, j int) bool {
+		if sortTags[i].SlaveID != sortTags[j].SlaveID {
+			return sortTags[i].SlaveID < sortTags[j].SlaveID
+		}
+		if sortTags[i].Table != sortTags[j].Table {
+			return sortTags[i].Table < sortTags[j].Table
+		}
+		return sortTags[i].Address < sortTags[j].Address
+	})
+
+	var blockPtr *ReadBlock
+	var coalesceVal ReadCoalesce
+	for _, val := range sortTags {
+		tagEnd := int(val.Address) + int(val.Size())
+		if blockPtr != nil && blockPtr.SlaveID == val.SlaveID && blockPtr.Table == val.Table {
+			blockEnd := int(blockPtr.Address) + int(blockPtr.Count)
+			newEnd := blockEnd
+			if tagEnd > newEnd {
+				newEnd = tagEnd
+			}
+			if int(val.Address)-blockEnd <= coalesceVal.ReadGap && newEnd-int(blockPtr.Address) <= coalesceVal.blockLimit(val.Table) {
+				blockPtr.Count = uint16(newEnd - int(blockPtr.Address))
+				blockPtr.Tags = append(blockPtr.Tags, val)
+				continue
+			}
+		}
+
+		if blockPtr == nil || blockPtr.SlaveID != val.SlaveID {
+			coalesceVal = coalesce(val.SlaveID)
+		}
+		blockPtr = &ReadBlock{SlaveID: val.SlaveID, Table: val.Table, Address: val.Address, Count: val.Size(), Tags: []*Tag{val}}
+		ret = append(ret, blockPtr)
+	}
+
+	return
+}
@@ -0,0 +1,172 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// StreamValue 以Server-Sent-Events推送扫描组的取值变化
+const StreamValue = "/stream"
+
+// ValueEvent 推送事件名,事件数据为TagValue的JSON
+const ValueEvent = "value"
+
+/*
+AddressRange 从站数据区内的地址范围,包含From与To,字符串形式为slaveID:table:from-to
+*/
+type AddressRange struct {
+	SlaveID string
+	Table   string
+	From    uint16
+	To      uint16
+}
+
+func ParseAddressRange(val string) (ret *AddressRange, err error) {
+	items := strings.Split(val, ":")
+	if len(items) != 3 || items[0] == "" {
+		err = fmt.Errorf("illegal address range '%s'", val)
+		return
+	}
+
+	rangeVal := &AddressRange{SlaveID: items[0], Table: items[1]}
+	switch rangeVal.Table {
+	case CoilTable, DiscreteInputTable, HoldingRegisterTable, InputRegisterTable:
+	default:
+		err = fmt.Errorf("illegal address range '%s', table:%s", val, rangeVal.Table)
+		return
+	}
+
+	fromVal, toVal, _ := strings.Cut(items[2], "-")
+	if toVal == "" {
+		toVal = fromVal
+	}
+	from, fromErr := strconv.ParseUint(fromVal, 10, 16)
+	to, toErr := strconv.ParseUint(toVal, 10, 16)
+	if fromErr != nil || toErr != nil || from > to {
+		err = fmt.Errorf("illegal address range '%s'", val)
+		return
+	}
+
+	rangeVal.From = uint16(from)
+	rangeVal.To = uint16(to)
+	ret = rangeVal
+	return
+}
+
+func (s *AddressRange) String() string {
+	return fmt.Sprintf("%s:%s:%d-%d", s.SlaveID, s.Table, s.From, s.To)
+}
+
+/*
+StreamRequest 订阅参数,通过查询参数slaveID、tag、range、deadband、interval传递,
+slaveID、tag与range可以重复或者以逗号分隔
+SlaveIDs订阅从站的全部Tag,Tags订阅指定的Tag,Ranges订阅起始地址在范围内的Tag,均为空时订阅全部Tag
+Deadband为模拟量的绝对死区,与上次推送的差值小于Deadband时不推送,布尔值及质量的变化总是推送
+Interval为同一Tag的最小推送间隔(毫秒),间隔内的多次变化只推送最近一次的取值
+只有属于扫描组的Tag会推送,订阅建立时首先推送已有的扫描结果
+*/
+type StreamRequest struct {
+	SlaveIDs []string
+	Tags     []string
+	Ranges   []*AddressRange
+	Deadband float64
+	Interval int
+}
+
+func ParseStreamRequest(queryVal url.Values) (ret *StreamRequest, err error) {
+	requestVal := &StreamRequest{
+		SlaveIDs: splitQuery(queryVal["slaveID"]),
+		Tags:     splitQuery(queryVal["tag"]),
+	}
+	for _, val := range splitQuery(queryVal["range"]) {
+		rangeVal, rangeErr := ParseAddressRange(val)
+		if rangeErr != nil {
+			err = rangeErr
+			return
+		}
+		requestVal.Ranges = append(requestVal.Ranges, rangeVal)
+	}
+
+	if val := queryVal.Get("deadband"); val != "" {
+		deadband, deadbandErr := strconv.ParseFloat(val, 64)
+		if deadbandErr != nil || deadband < 0 || math.IsInf(deadband, 0) {
+			err = fmt.Errorf("illegal deadband '%s'", val)
+			return
+		}
+		requestVal.Deadband = deadband
+	}
+	if val := queryVal.Get("interval"); val != "" {
+		interval, intervalErr := strconv.Atoi(val)
+		if intervalErr != nil || interval < 0 {
+			err = fmt.Errorf("illegal interval '%s'", val)
+			return
+		}
+		requestVal.Interval = interval
+	}
+
+	ret = requestVal
+	return
+}
+
+func splitQuery(values []string) (ret []string) {
+	for _, val := range values {
+		for _, item := range strings.Split(val, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				ret = append(ret, item)
+			}
+		}
+	}
+
+	return
+}
+
+// Values 转换成查询参数
+func (s *StreamRequest) Values() url.Values {
+	ret := url.Values{}
+	if len(s.SlaveIDs) > 0 {
+		ret.Set("slaveID", strings.Join(s.SlaveIDs, ","))
+	}
+	if len(s.Tags) > 0 {
+		ret.Set("tag", strings.Join(s.Tags, ","))
+	}
+	for _, val := range s.Ranges {
+		ret.Add("range", val.String())
+	}
+	if s.Deadband > 0 {
+		ret.Set("deadband", strconv.FormatFloat(s.Deadband, 'g', -1, 64))
+	}
+	if s.Interval > 0 {
+		ret.Set("interval", strconv.Itoa(s.Interval))
+	}
+
+	return ret
+}
+
+// Match Tag是否在订阅范围内
+func (s *StreamRequest) Match(tag *Tag) bool {
+	if len(s.SlaveIDs) == 0 && len(s.Tags) == 0 && len(s.Ranges) == 0 {
+		return true
+	}
+
+	for _, val := range s.SlaveIDs {
+		if val == tag.SlaveID {
+			return true
+		}
+	}
+	for _, val := range s.Tags {
+		if val == tag.Name {
+			return true
+		}
+	}
+	for _, val := range s.Ranges {
+		if val.SlaveID == tag.SlaveID && val.Table == tag.Table && tag.Address >= val.From && tag.Address <= val.To {
+			return true
+		}
+	}
+
+	return false
+}
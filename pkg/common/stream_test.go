@@ -0,0 +1,50 @@
+package common
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseStreamRequest(t *testing.T) {
+	queryVal, _ := url.ParseQuery("slaveID=mb002&tag=a,b&range=mb001:holdingRegister:10-20&range=mb001:coil:3&deadband=0.5&interval=500")
+	requestVal, err := ParseStreamRequest(queryVal)
+	if err != nil {
+		t.Fatalf("ParseStreamRequest failed, error:%s", err.Error())
+	}
+	if len(requestVal.Tags) != 2 || len(requestVal.Ranges) != 2 || requestVal.Deadband != 0.5 || requestVal.Interval != 500 {
+		t.Errorf("ParseStreamRequest failed, request:%+v", requestVal)
+	}
+	if parseVal, _ := ParseStreamRequest(requestVal.Values()); !reflect.DeepEqual(parseVal, requestVal) {
+		t.Errorf("Values failed, query:%s", requestVal.Values().Encode())
+	}
+
+	matchTags := []*Tag{
+		{Name: "x", SlaveID: "mb002"},
+		{Name: "a", SlaveID: "mb003"},
+		{Name: "y", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 20},
+		{Name: "z", SlaveID: "mb001", Table: CoilTable, Address: 3},
+	}
+	for _, val := range matchTags {
+		if !requestVal.Match(val) {
+			t.Errorf("Match failed, tag:%+v", val)
+		}
+	}
+	unmatchTags := []*Tag{
+		{Name: "y", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 21},
+		{Name: "z", SlaveID: "mb001", Table: InputRegisterTable, Address: 10},
+	}
+	for _, val := range unmatchTags {
+		if requestVal.Match(val) {
+			t.Errorf("Match should fail, tag:%+v", val)
+		}
+	}
+
+	illegalQueries := []string{"range=mb001:holding:0-1", "range=mb001:coil:5-1", "deadband=-1", "interval=abc"}
+	for _, val := range illegalQueries {
+		queryVal, _ = url.ParseQuery(val)
+		if _, err = ParseStreamRequest(queryVal); err == nil {
+			t.Errorf("ParseStreamRequest should fail, query:%s", val)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+/*
+Tag所在的数据区,线圈与离散输入的ValueType固定为BoolValue
+*/
+const (
+	CoilTable            = "coil"
+	DiscreteInputTable   = "discreteInput"
+	HoldingRegisterTable = "holdingRegister"
+	InputRegisterTable   = "inputRegister"
+)
+
+/*
+ReadOnlyAccess 只读
+ReadWriteAccess 可读写,仅线圈与保持寄存器允许写入
+*/
+const (
+	ReadOnlyAccess  = "r"
+	ReadWriteAccess = "rw"
+)
+
+const (
+	ListTag   = "/tags"
+	QueryTag  = "/tags/:name"
+	SaveTag   = "/tags"
+	DeleteTag = "/tags/:name"
+	ReadTag   = "/tags/:name/value"
+	WriteTag  = "/tags/:name/value"
+	ReadTags  = "/tags/read"
+)
+
+/*
+Tag 命名的从站变量,工程值 = 原始值 * Scale + Offset,Scale为0时按1处理
+EndianType为DefaultEndian时沿用从站的字节序,Access为空时为只读
+ScanGroup非空时由该扫描组周期读取,读取Tag时返回最近一次扫描的结果
+Deadband与DeadbandPercent为扫描结果变化上报的绝对死区与相对上次上报取值的百分比死区,见ValueChanged
+*/
+type Tag struct {
+	Name            string  `json:"name"`
+	SlaveID         string  `json:"slaveID"`
+	Table           string  `json:"table"`
+	Address         uint16  `json:"address"`
+	ValueType       uint16  `json:"valueType"`
+	EndianType      byte    `json:"endianType"`
+	Scale           float64 `json:"scale,omitempty"`
+	Offset          float64 `json:"offset,omitempty"`
+	Unit            string  `json:"unit,omitempty"`
+	Access          string  `json:"access"`
+	ScanGroup       string  `json:"scanGroup,omitempty"`
+	Deadband        float64 `json:"deadband,omitempty"`
+	DeadbandPercent float64 `json:"deadbandPercent,omitempty"`
+	Description     string  `json:"description,omitempty"`
+}
+
+// Validate 校验Tag定义并补齐ValueType与Access的缺省值
+func (s *Tag) Validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, "/?# ") {
+		return fmt.Errorf("illegal tag name '%s'", s.Name)
+	}
+	if s.SlaveID == "" {
+		return fmt.Errorf("illegal tag %s, slaveID is empty", s.Name)
+	}
+	if s.EndianType > BAEndian {
+		return fmt.Errorf("illegal tag %s, endianType:%d", s.Name, s.EndianType)
+	}
+	if s.Access == "" {
+		s.Access = ReadOnlyAccess
+	}
+	if s.Access != ReadOnlyAccess && s.Access != ReadWriteAccess {
+		return fmt.Errorf("illegal tag %s, access:%s", s.Name, s.Access)
+	}
+	if strings.ContainsAny(s.ScanGroup, "/?# ") {
+		return fmt.Errorf("illegal tag %s, scanGroup:%s", s.Name, s.ScanGroup)
+	}
+	if s.Deadband < 0 || s.DeadbandPercent < 0 || math.IsInf(s.Deadband, 0) || math.IsInf(s.DeadbandPercent, 0) {
+		return fmt.Errorf("illegal tag %s, deadband:%v, deadbandPercent:%v", s.Name, s.Deadband, s.DeadbandPercent)
+	}
+
+	switch s.Table {
+	case CoilTable, DiscreteInputTable:
+		if s.ValueType == RawValue {
+			s.ValueType = BoolValue
+		}
+		if s.ValueType != BoolValue {
+			return fmt.Errorf("illegal tag %s, valueType:%d", s.Name, s.ValueType)
+		}
+	case HoldingRegisterTable, InputRegisterTable:
+		if s.ValueType < Int16Value || s.ValueType > Float64Value {
+			return fmt.Errorf("illegal tag %s, valueType:%d", s.Name, s.ValueType)
+		}
+	default:
+		return fmt.Errorf("illegal tag %s, table:%s", s.Name, s.Table)
+	}
+
+	if s.Writable() || s.Access == ReadOnlyAccess {
+		return nil
+	}
+
+	return fmt.Errorf("illegal tag %s, %s is read only", s.Name, s.Table)
+}
+
+// Writable 是否允许写入
+func (s *Tag) Writable() bool {
+	return s.Access == ReadWriteAccess && (s.Table == CoilTable || s.Table == HoldingRegisterTable)
+}
+
+// Size Tag占用的寄存器数量,线圈与离散输入为1
+func (s *Tag) Size() uint16 {
+	switch s.ValueType {
+	case Int32Value, UInt32Value, Float32Value:
+		return 2
+	case Int64Value, UInt64Value, Float64Value:
+		return 4
+	default:
+		return 1
+	}
+}
+
+func (s *Tag) scale() float64 {
+	if s.Scale == 0 {
+		return 1
+	}
+
+	return s.Scale
+}
+
+// ToEngineering 原始值转换成工程值
+func (s *Tag) ToEngineering(rawVal float64) float64 {
+	return rawVal*s.scale() + s.Offset
+}
+
+// ToRaw 工程值转换成原始值,整数类型四舍五入并检查取值范围
+func (s *Tag) ToRaw(value float64) (ret float64, err error) {
+	rawVal := (value - s.Offset) / s.scale()
+	if math.IsNaN(rawVal) || math.IsInf(rawVal, 0) {
+		err = fmt.Errorf("illegal value %v for tag %s", value, s.Name)
+		return
+	}
+
+	var minVal, maxVal float64
+	switch s.ValueType {
+	case Int16Value:
+		minVal, maxVal = math.MinInt16, math.MaxInt16
+	case UInt16Value:
+		minVal, maxVal = 0, math.MaxUint16
+	case Int32Value:
+		minVal, maxVal = math.MinInt32, math.MaxInt32
+	case UInt32Value:
+		minVal, maxVal = 0, math.MaxUint32
+	case Int64Value:
+		minVal, maxVal = math.MinInt64, math.Nextafter(math.MaxInt64, 0)
+	case UInt64Value:
+		minVal, maxVal = 0, math.Nextafter(math.MaxUint64, 0)
+	case Float32Value:
+		if math.Abs(rawVal) > math.MaxFloat32 {
+			err = fmt.Errorf("value %v out of range for tag %s", value, s.Name)
+			return
+		}
+		ret = rawVal
+		return
+	default:
+		ret = rawVal
+		return
+	}
+
+	rawVal = math.Round(rawVal)
+	if rawVal < minVal || rawVal > maxVal {
+		err = fmt.Errorf("value %v out of range for tag %s", value, s.Name)
+		return
+	}
+
+	ret = rawVal
+	return
+}
+
+/*
+GoodQuality 最近一次读取成功
+BadQuality 最近一次读取失败,Value为此前读取成功的值
+*/
+const (
+	GoodQuality = "good"
+	BadQuality  = "bad"
+)
+
+/*
+TagValue Tag的当前值,布尔类型Tag的Value为bool,其余为工程值float64
+Timestamp为读取时间,读取失败时Quality为BadQuality,失败原因记录在Error中
+批量读取时单个Tag失败不影响其它Tag
+*/
+type TagValue struct {
+	Name          string      `json:"name"`
+	Value         interface{} `json:"value"`
+	Unit          string      `json:"unit,omitempty"`
+	Quality       string      `json:"quality"`
+	Timestamp     time.Time   `json:"timestamp"`
+	ExceptionCode byte        `json:"exceptionCode,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// TagChangeEvent Tag取值变化的事件ID前缀,事件ID为前缀加上Tag名称,事件数据为*TagValue
+const TagChangeEvent = MasterModule + "/tag/change"
+
+// TagChangeEventID Tag取值变化的事件ID,订阅全部Tag时使用TagChangeEvent + "/+"
+func TagChangeEventID(name string) string {
+	return TagChangeEvent + "/" + name
+}
+
+/*
+ValueChanged 与上次的取值相比是否发生变化,质量或者错误信息变化时总是视为变化
+布尔值的任何变化都视为变化,模拟量的变化量需要同时达到deadband绝对死区
+与相对上次取值的percent百分比死区,死区为0时不限制
+*/
+func ValueChanged(preVal, curVal *TagValue, deadband, percent float64) bool {
+	if preVal == nil || preVal.Quality != curVal.Quality || preVal.Error != curVal.Error {
+		return true
+	}
+
+	preFloat, preOK := preVal.Value.(float64)
+	curFloat, curOK := curVal.Value.(float64)
+	if !preOK || !curOK {
+		return preVal.Value != curVal.Value
+	}
+
+	diff := math.Abs(curFloat - preFloat)
+	if diff == 0 || diff < deadband {
+		return false
+	}
+	if percent > 0 && diff < math.Abs(preFloat)*percent/100 {
+		return false
+	}
+
+	return true
+}
+
+type ListTagResponse struct {
+	cd.Result
+	Tags []*Tag `json:"tags"`
+}
+
+type QueryTagResponse struct {
+	cd.Result
+	Tag *Tag `json:"tag"`
+}
+
+type SaveTagResponse struct {
+	cd.Result
+	Tag *Tag `json:"tag"`
+}
+
+type ReadTagResponse struct {
+	cd.Result
+	ExceptionCode byte      `json:"exceptionCode"`
+	Value         *TagValue `json:"value"`
+}
+
+type WriteTagRequest struct {
+	RequestPolicy
+
+	Value interface{} `json:"value"`
+}
+
+type WriteTagResponse struct {
+	cd.Result
+	ExceptionCode byte `json:"exceptionCode"`
+}
+
+type ReadTagsRequest struct {
+	RequestPolicy
+
+	Names []string `json:"names"`
+}
+
+type ReadTagsResponse struct {
+	cd.Result
+	Values []*TagValue `json:"values"`
+}
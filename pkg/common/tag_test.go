@@ -0,0 +1,170 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestTagValidate(t *testing.T) {
+	tagVal := &Tag{Name: "pump.run", SlaveID: "mb001", Table: CoilTable, Address: 1}
+	err := tagVal.Validate()
+	if err != nil {
+		t.Errorf("Validate failed, error:%s", err.Error())
+		return
+	}
+	if tagVal.ValueType != BoolValue || tagVal.Access != ReadOnlyAccess || tagVal.Writable() {
+		t.Errorf("Validate default failed, tag:%+v", tagVal)
+	}
+
+	illegalTags := []*Tag{
+		{Name: "", SlaveID: "mb001", Table: CoilTable},
+		{Name: "a/b", SlaveID: "mb001", Table: CoilTable},
+		{Name: "temp", Table: HoldingRegisterTable, ValueType: Int16Value},
+		{Name: "temp", SlaveID: "mb001", Table: "register", ValueType: Int16Value},
+		{Name: "temp", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: BoolValue},
+		{Name: "temp", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: Int16Value, EndianType: 7},
+		{Name: "temp", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: Int16Value, Access: "w"},
+		{Name: "temp", SlaveID: "mb001", Table: InputRegisterTable, ValueType: Int16Value, Access: ReadWriteAccess},
+		{Name: "alarm", SlaveID: "mb001", Table: DiscreteInputTable, ValueType: Int16Value},
+		{Name: "temp", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: Int16Value, Deadband: -1},
+	}
+	for _, val := range illegalTags {
+		if val.Validate() == nil {
+			t.Errorf("Validate should fail, tag:%+v", val)
+		}
+	}
+}
+
+func TestTagScale(t *testing.T) {
+	tagVal := &Tag{Name: "temp", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: Int16Value, Scale: 0.1, Offset: -40, Access: ReadWriteAccess}
+	if engVal := tagVal.ToEngineering(652); engVal < 25.19 || engVal > 25.21 {
+		t.Errorf("ToEngineering failed, value:%v", engVal)
+	}
+
+	rawVal, err := tagVal.ToRaw(25.2)
+	if err != nil || rawVal != 652 {
+		t.Errorf("ToRaw failed, value:%v, err:%v", rawVal, err)
+	}
+
+	_, err = tagVal.ToRaw(4000)
+	if err == nil {
+		t.Error("ToRaw should fail for out of range value")
+	}
+
+	tagVal = &Tag{Name: "flow", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: Float32Value}
+	rawVal, err = tagVal.ToRaw(1.25)
+	if err != nil || rawVal != 1.25 || tagVal.ToEngineering(1.25) != 1.25 {
+		t.Errorf("ToRaw failed, value:%v, err:%v", rawVal, err)
+	}
+
+	tagVal = &Tag{Name: "count", SlaveID: "mb001", Table: HoldingRegisterTable, ValueType: UInt16Value}
+	_, err = tagVal.ToRaw(-1)
+	if err == nil {
+		t.Error("ToRaw should fail for negative unsigned value")
+	}
+}
+
+func TestScanGroupValidate(t *testing.T) {
+	groupVal := &ScanGroup{Name: "fast", Interval: MinScanInterval}
+	if err := groupVal.Validate(); err != nil {
+		t.Errorf("Validate failed, error:%s", err.Error())
+	}
+
+	illegalGroups := []*ScanGroup{
+		{Name: "", Interval: 1000},
+		{Name: "a/b", Interval: 1000},
+		{Name: "fast", Interval: 10},
+	}
+	for _, val := range illegalGroups {
+		if val.Validate() == nil {
+			t.Errorf("Validate should fail, group:%+v", val)
+		}
+	}
+}
+
+func TestPlanReadBlocks(t *testing.T) {
+	tags := []*Tag{
+		{Name: "t3", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 10, ValueType: Float32Value},
+		{Name: "t1", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 0, ValueType: UInt16Value},
+		{Name: "t2", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 1, ValueType: Int32Value},
+		{Name: "t4", SlaveID: "mb001", Table: HoldingRegisterTable, Address: 200, ValueType: UInt16Value},
+		{Name: "c1", SlaveID: "mb001", Table: CoilTable, Address: 0, ValueType: BoolValue},
+		{Name: "c2", SlaveID: "mb001", Table: CoilTable, Address: 1999, ValueType: BoolValue},
+		{Name: "r1", SlaveID: "mb002", Table: HoldingRegisterTable, Address: 0, ValueType: Float64Value},
+		{Name: "r2", SlaveID: "mb002", Table: HoldingRegisterTable, Address: 4, ValueType: Float64Value},
+	}
+	coalesceMap := map[string]ReadCoalesce{
+		"mb001": {ReadGap: 10},
+		"mb002": {MaxReadRegisters: 6},
+	}
+	blocks := PlanReadBlocks(tags, func(slaveID string) ReadCoalesce {
+		return coalesceMap[slaveID]
+	})
+
+	expects := []struct {
+		slaveID string
+		table   string
+		address uint16
+		count   uint16
+		names   []string
+	}{
+		{"mb001", CoilTable, 0, 1, []string{"c1"}},
+		{"mb001", CoilTable, 1999, 1, []string{"c2"}},
+		{"mb001", HoldingRegisterTable, 0, 12, []string{"t1", "t2", "t3"}},
+		{"mb001", HoldingRegisterTable, 200, 1, []string{"t4"}},
+		{"mb002", HoldingRegisterTable, 0, 4, []string{"r1"}},
+		{"mb002", HoldingRegisterTable, 4, 4, []string{"r2"}},
+	}
+	if len(blocks) != len(expects) {
+		t.Fatalf("PlanReadBlocks failed, blocks:%d", len(blocks))
+	}
+	for idx, val := range expects {
+		blockPtr := blocks[idx]
+		if blockPtr.SlaveID != val.slaveID || blockPtr.Table != val.table || blockPtr.Address != val.address || blockPtr.Count != val.count || len(blockPtr.Tags) != len(val.names) {
+			t.Errorf("PlanReadBlocks failed, block:%+v", blockPtr)
+			continue
+		}
+		for tIdx, name := range val.names {
+			if blockPtr.Tags[tIdx].Name != name {
+				t.Errorf("PlanReadBlocks failed, block:%d, tag:%s", idx, blockPtr.Tags[tIdx].Name)
+			}
+		}
+	}
+	if blocks[2].Offset(blocks[2].Tags[2]) != 10 {
+		t.Errorf("Offset failed, offset:%d", blocks[2].Offset(blocks[2].Tags[2]))
+	}
+
+	blocks = PlanReadBlocks(tags[4:6], func(string) ReadCoalesce {
+		return ReadCoalesce{ReadGap: 2000}
+	})
+	if len(blocks) != 1 || blocks[0].Count != BitReadLimit {
+		t.Errorf("PlanReadBlocks bit limit failed, blocks:%d", len(blocks))
+	}
+}
+
+func TestValueChanged(t *testing.T) {
+	preVal := &TagValue{Name: "a", Value: 10.0, Quality: GoodQuality}
+	if ValueChanged(preVal, &TagValue{Name: "a", Value: 10.4, Quality: GoodQuality}, 0.5, 0) {
+		t.Error("ValueChanged should ignore change within deadband")
+	}
+	if !ValueChanged(preVal, &TagValue{Name: "a", Value: 10.5, Quality: GoodQuality}, 0.5, 0) {
+		t.Error("ValueChanged failed, change reach deadband")
+	}
+	if ValueChanged(preVal, &TagValue{Name: "a", Value: 10.5, Quality: GoodQuality}, 0.5, 10) {
+		t.Error("ValueChanged should ignore change within percent deadband")
+	}
+	if !ValueChanged(preVal, &TagValue{Name: "a", Value: 8.9, Quality: GoodQuality}, 0.5, 10) {
+		t.Error("ValueChanged failed, change reach percent deadband")
+	}
+	if !ValueChanged(preVal, &TagValue{Name: "a", Value: 10.0, Quality: BadQuality}, 0.5, 0) {
+		t.Error("ValueChanged failed, quality changed")
+	}
+	if ValueChanged(preVal, &TagValue{Name: "a", Value: 10.0, Quality: GoodQuality}, 0, 0) {
+		t.Error("ValueChanged should ignore same value")
+	}
+	if !ValueChanged(&TagValue{Value: false, Quality: GoodQuality}, &TagValue{Value: true, Quality: GoodQuality}, 100, 100) {
+		t.Error("ValueChanged failed, bool changed")
+	}
+	if !ValueChanged(nil, preVal, 100, 100) {
+		t.Error("ValueChanged failed, first value")
+	}
+}
@@ -0,0 +1,239 @@
+package common
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const ImportTemplate = "/slave/import"
+
+const (
+	JSONTemplate = "json"
+	CSVTemplate  = "csv"
+)
+
+/*
+DeviceTemplate 设备模板,包含从站的连接参数及该从站的全部Tag
+导入时Tag的SlaveID为新建或者已存在的从站,Tag名称加上NamePrefix前缀
+ScanGroups随模板一同保存,CSV模板中的Tag只能引用已定义的扫描组
+
+CSV模板便于从表格导出,首先是"参数名,取值"形式的连接参数行,
+随后是以name开头的Tag表头行及Tag数据行,列名与Tag的json字段一致,空白行被忽略
+valueType与endianType可以填写常量取值,也可以填写float32、CDAB等名称
+
+	slaveAddr,192.168.1.10:502
+	deviceID,1
+	name,table,address,valueType,scale,unit,access
+	voltage,inputRegister,0,float32,,V,r
+*/
+type DeviceTemplate struct {
+	ConnectSlaveRequest
+
+	NamePrefix string       `json:"namePrefix,omitempty"`
+	ScanGroups []*ScanGroup `json:"scanGroups,omitempty"`
+	Tags       []*Tag       `json:"tags"`
+}
+
+type ImportTemplateResponse struct {
+	cd.Result
+	SlaveID string `json:"slaveID"`
+	Tags    []*Tag `json:"tags"`
+}
+
+// TemplateFormat 根据文件扩展名或者Content-Type判断模板格式,默认为JSON
+func TemplateFormat(nameOrType string) string {
+	nameOrType = strings.ToLower(nameOrType)
+	if filepath.Ext(nameOrType) == ".csv" || strings.HasPrefix(nameOrType, "text/csv") {
+		return CSVTemplate
+	}
+
+	return JSONTemplate
+}
+
+func ParseDeviceTemplate(data []byte, format string) (ret *DeviceTemplate, err error) {
+	if format == CSVTemplate {
+		data, err = csvTemplateToJSON(data)
+		if err != nil {
+			return
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	templatePtr := &DeviceTemplate{}
+	err = decoder.Decode(templatePtr)
+	if err != nil {
+		err = fmt.Errorf("illegal device template, %s", err.Error())
+		return
+	}
+	if len(templatePtr.Tags) == 0 {
+		err = fmt.Errorf("illegal device template, no tags")
+		return
+	}
+
+	ret = templatePtr
+	return
+}
+
+var csvStringFields = map[string]bool{
+	"slaveAddr":   true,
+	"namePrefix":  true,
+	"parity":      true,
+	"name":        true,
+	"table":       true,
+	"unit":        true,
+	"access":      true,
+	"scanGroup":   true,
+	"description": true,
+}
+
+var csvSerialFields = map[string]bool{
+	"baudRate":     true,
+	"dataBits":     true,
+	"parity":       true,
+	"stopBits":     true,
+	"frameSilence": true,
+}
+
+var valueTypeNames = map[string]int{
+	"raw":     RawValue,
+	"bool":    BoolValue,
+	"int16":   Int16Value,
+	"uint16":  UInt16Value,
+	"int32":   Int32Value,
+	"uint32":  UInt32Value,
+	"int64":   Int64Value,
+	"uint64":  UInt64Value,
+	"float32": Float32Value,
+	"float64": Float64Value,
+}
+
+var endianTypeNames = map[string]int{
+	"default": DefaultEndian,
+	"abcd":    ABCDEndian,
+	"badc":    BADCEndian,
+	"cdab":    CDABEndian,
+	"dcba":    DCBAEndian,
+	"ab":      ABEndian,
+	"ba":      BAEndian,
+}
+
+// csvTemplateToJSON CSV模板按照字段名转换成JSON,由json解码完成类型校验
+func csvTemplateToJSON(data []byte) (ret []byte, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	templateVal := map[string]interface{}{}
+	serialVal := map[string]interface{}{}
+	tags := []interface{}{}
+	var header []string
+	for {
+		record, recordErr := reader.Read()
+		if recordErr == io.EOF {
+			break
+		}
+		if recordErr != nil {
+			err = recordErr
+			return
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		if header == nil && strings.TrimSpace(record[0]) == "name" {
+			header = record
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if header == nil {
+			key := strings.TrimSpace(record[0])
+			if len(record) < 2 || strings.TrimSpace(record[1]) == "" {
+				err = fmt.Errorf("illegal device template, line %d: missing value of %s", line, key)
+				return
+			}
+
+			itemVal, itemErr := csvFieldValue(key, record[1])
+			if itemErr != nil {
+				err = fmt.Errorf("illegal device template, line %d: %s", line, itemErr.Error())
+				return
+			}
+			if csvSerialFields[key] {
+				serialVal[key] = itemVal
+			} else {
+				templateVal[key] = itemVal
+			}
+			continue
+		}
+
+		tagVal := map[string]interface{}{}
+		for idx, cell := range record {
+			if idx >= len(header) || strings.TrimSpace(cell) == "" {
+				continue
+			}
+
+			key := strings.TrimSpace(header[idx])
+			itemVal, itemErr := csvFieldValue(key, cell)
+			if itemErr != nil {
+				err = fmt.Errorf("illegal device template, line %d: %s", line, itemErr.Error())
+				return
+			}
+			tagVal[key] = itemVal
+		}
+		tags = append(tags, tagVal)
+	}
+
+	if len(serialVal) > 0 {
+		templateVal["serialConfig"] = serialVal
+	}
+	templateVal["tags"] = tags
+	ret, err = json.Marshal(templateVal)
+	return
+}
+
+func isBlankRecord(record []string) bool {
+	for _, val := range record {
+		if strings.TrimSpace(val) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func csvFieldValue(key, value string) (ret interface{}, err error) {
+	value = strings.TrimSpace(value)
+	if csvStringFields[key] {
+		ret = value
+		return
+	}
+
+	if key == "valueType" || key == "endianType" {
+		names := valueTypeNames
+		if key == "endianType" {
+			names = endianTypeNames
+		}
+		if typeVal, ok := names[strings.ToLower(value)]; ok {
+			ret = typeVal
+			return
+		}
+	}
+
+	fVal, fErr := strconv.ParseFloat(value, 64)
+	if fErr != nil {
+		err = fmt.Errorf("illegal %s value '%s'", key, value)
+		return
+	}
+
+	ret = fVal
+	return
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParseCSVTemplate(t *testing.T) {
+	data := `slaveAddr,/dev/ttyUSB0
+deviceID,3
+deviceType,3
+endianType,CDAB
+baudRate,19200
+parity,N
+retries,0
+namePrefix,meter3.
+
+name,table,address,valueType,scale,offset,unit,access,scanGroup,description
+voltage,inputRegister,0,float32,,,V,r,fast,"Phase A, voltage"
+setpoint,holdingRegister,100,3,0.1,-40,C,rw,,
+relay,coil,5,,,,,rw,,
+`
+	templatePtr, err := ParseDeviceTemplate([]byte(data), TemplateFormat("meter.CSV"))
+	if err != nil {
+		t.Errorf("ParseDeviceTemplate failed, error:%s", err.Error())
+		return
+	}
+	if templatePtr.SlaveAddr != "/dev/ttyUSB0" || templatePtr.DeviceID != 3 || templatePtr.DeviceType != ModbusRTU || templatePtr.EndianType != CDABEndian {
+		t.Errorf("illegal connect param, template:%+v", templatePtr.ConnectSlaveRequest)
+	}
+	if templatePtr.SerialConfig == nil || templatePtr.SerialConfig.BaudRate != 19200 || templatePtr.SerialConfig.Parity != "N" {
+		t.Errorf("illegal serial config, config:%+v", templatePtr.SerialConfig)
+	}
+	if templatePtr.Retries == nil || *templatePtr.Retries != 0 || templatePtr.NamePrefix != "meter3." {
+		t.Errorf("illegal template, template:%+v", templatePtr)
+	}
+	if len(templatePtr.Tags) != 3 {
+		t.Errorf("illegal tag count:%d", len(templatePtr.Tags))
+		return
+	}
+
+	expectVal := Tag{Name: "setpoint", Table: HoldingRegisterTable, Address: 100, ValueType: UInt16Value, Scale: 0.1, Offset: -40, Unit: "C", Access: ReadWriteAccess}
+	if *templatePtr.Tags[1] != expectVal {
+		t.Errorf("illegal tag, expect:%+v, really:%+v", expectVal, *templatePtr.Tags[1])
+	}
+	if templatePtr.Tags[0].ValueType != Float32Value || templatePtr.Tags[0].ScanGroup != "fast" || templatePtr.Tags[0].Description != "Phase A, voltage" {
+		t.Errorf("illegal tag, really:%+v", *templatePtr.Tags[0])
+	}
+}
+
+func TestParseTemplateFailed(t *testing.T) {
+	items := []struct {
+		data   string
+		format string
+	}{
+		{"slaveAddr,127.0.0.1:502\nname,table,address\n", CSVTemplate},
+		{"slaveAddr,127.0.0.1:502\ncolor,red\nname,table,address\ntemp,coil,1\n", CSVTemplate},
+		{"slaveAddr,127.0.0.1:502\nname,table,address\ntemp,coil,abc\n", CSVTemplate},
+		{"slaveAddr,127.0.0.1:502\nname,table,address,valueType\ntemp,coil,1,double\n", CSVTemplate},
+		{`{"slaveAddr":"127.0.0.1:502","tags":[{"name":"temp","table":"coil","color":"red"}]}`, JSONTemplate},
+		{`{"slaveAddr":"127.0.0.1:502","tags":[]}`, JSONTemplate},
+	}
+	for _, val := range items {
+		_, err := ParseDeviceTemplate([]byte(val.data), val.format)
+		if err == nil {
+			t.Errorf("ParseDeviceTemplate should fail, data:%s", val.data)
+		}
+	}
+
+	if TemplateFormat("text/csv; charset=utf-8") != CSVTemplate || TemplateFormat("application/json") != JSONTemplate {
+		t.Error("TemplateFormat failed")
+	}
+}
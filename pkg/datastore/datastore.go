@@ -0,0 +1,60 @@
+package datastore
+
+const (
+	maxTableAddress = 0xFFFF
+	maxFIFOCount    = 31
+	maxFileNumber   = 0xFFFF
+	maxRecordNumber = 0x270F
+)
+
+// DataStore 从站数据存储,按UnitID划分,所有操作返回model中定义的异常码
+type DataStore interface {
+	ReadCoils(unitID byte, address, count uint16) ([]bool, byte)
+	WriteCoils(unitID byte, address uint16, values []bool) byte
+	ReadDiscreteInputs(unitID byte, address, count uint16) ([]bool, byte)
+	WriteDiscreteInputs(unitID byte, address uint16, values []bool) byte
+	ReadHoldingRegisters(unitID byte, address, count uint16) ([]uint16, byte)
+	WriteHoldingRegisters(unitID byte, address uint16, values []uint16) byte
+	MaskWriteHoldingRegister(unitID byte, address, andMask, orMask uint16) byte
+	ReadInputRegisters(unitID byte, address, count uint16) ([]uint16, byte)
+	WriteInputRegisters(unitID byte, address uint16, values []uint16) byte
+	ReadFileRecord(unitID byte, fileNumber, recordNumber, recordLength uint16) ([]uint16, byte)
+	WriteFileRecord(unitID byte, fileNumber, recordNumber uint16, values []uint16) byte
+	ReadFIFOQueue(unitID byte, address uint16) ([]uint16, byte)
+	WriteFIFOQueue(unitID byte, address uint16, values []uint16) byte
+}
+
+// Range 数据表的有效地址区间,Start和End均包含在内
+type Range struct {
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+}
+
+func (s Range) size() int {
+	if s.End < s.Start {
+		return 0
+	}
+
+	return int(s.End) - int(s.Start) + 1
+}
+
+// UnitConfig 单个UnitID对应的四张主数据表地址区间
+type UnitConfig struct {
+	UnitID           byte  `json:"unitID"`
+	Coils            Range `json:"coils"`
+	DiscreteInputs   Range `json:"discreteInputs"`
+	HoldingRegisters Range `json:"holdingRegisters"`
+	InputRegisters   Range `json:"inputRegisters"`
+}
+
+// DefaultUnitConfig 四张数据表均覆盖全部地址空间
+func DefaultUnitConfig(unitID byte) UnitConfig {
+	fullRange := Range{Start: 0, End: maxTableAddress}
+	return UnitConfig{
+		UnitID:           unitID,
+		Coils:            fullRange,
+		DiscreteInputs:   fullRange,
+		HoldingRegisters: fullRange,
+		InputRegisters:   fullRange,
+	}
+}
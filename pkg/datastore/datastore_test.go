@@ -0,0 +1,318 @@
+package datastore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+func testUnits() []UnitConfig {
+	unit2 := DefaultUnitConfig(2)
+	unit2.HoldingRegisters = Range{Start: 100, End: 199}
+	unit2.Coils = Range{Start: 0, End: 15}
+	return []UnitConfig{DefaultUnitConfig(1), unit2}
+}
+
+func TestMemoryStoreRange(t *testing.T) {
+	store := NewMemoryStore(testUnits())
+
+	exCode := store.WriteHoldingRegisters(2, 100, []uint16{1, 2, 3})
+	if exCode != model.SuccessCode {
+		t.Errorf("WriteHoldingRegisters failed, error code:%v", exCode)
+		return
+	}
+
+	values, exCode := store.ReadHoldingRegisters(2, 101, 2)
+	if exCode != model.SuccessCode || values[0] != 2 || values[1] != 3 {
+		t.Errorf("ReadHoldingRegisters failed, error code:%v, values:%v", exCode, values)
+		return
+	}
+
+	_, exCode = store.ReadHoldingRegisters(2, 99, 2)
+	if exCode != model.IllegalAddress {
+		t.Errorf("ReadHoldingRegisters below range, unexpected error code:%v", exCode)
+		return
+	}
+
+	_, exCode = store.ReadHoldingRegisters(2, 198, 3)
+	if exCode != model.IllegalAddress {
+		t.Errorf("ReadHoldingRegisters beyond range, unexpected error code:%v", exCode)
+		return
+	}
+
+	_, exCode = store.ReadHoldingRegisters(2, 100, 0)
+	if exCode != model.IllegalCount {
+		t.Errorf("ReadHoldingRegisters zero count, unexpected error code:%v", exCode)
+		return
+	}
+
+	exCode = store.WriteCoils(2, 15, []bool{true, true})
+	if exCode != model.IllegalAddress {
+		t.Errorf("WriteCoils beyond range, unexpected error code:%v", exCode)
+		return
+	}
+
+	_, exCode = store.ReadCoils(3, 0, 1)
+	if exCode != model.IllegalAddress {
+		t.Errorf("ReadCoils unknown unit, unexpected error code:%v", exCode)
+		return
+	}
+
+	// 不同UnitID的数据互不影响
+	values, exCode = store.ReadHoldingRegisters(1, 100, 1)
+	if exCode != model.SuccessCode || values[0] != 0 {
+		t.Errorf("ReadHoldingRegisters unit 1 failed, error code:%v, values:%v", exCode, values)
+		return
+	}
+
+	values, exCode = store.ReadHoldingRegisters(1, 0xFFFF, 1)
+	if exCode != model.SuccessCode || len(values) != 1 {
+		t.Errorf("ReadHoldingRegisters last address failed, error code:%v", exCode)
+		return
+	}
+}
+
+func TestMemoryStoreMaskWrite(t *testing.T) {
+	store := NewMemoryStore(testUnits())
+	store.WriteHoldingRegisters(1, 4, []uint16{0x0012})
+
+	exCode := store.MaskWriteHoldingRegister(1, 4, 0x00F2, 0x0025)
+	if exCode != model.SuccessCode {
+		t.Errorf("MaskWriteHoldingRegister failed, error code:%v", exCode)
+		return
+	}
+
+	values, _ := store.ReadHoldingRegisters(1, 4, 1)
+	if values[0] != 0x0017 {
+		t.Errorf("MaskWriteHoldingRegister failed, value:%04x", values[0])
+		return
+	}
+
+	exCode = store.MaskWriteHoldingRegister(2, 4, 0x00F2, 0x0025)
+	if exCode != model.IllegalAddress {
+		t.Errorf("MaskWriteHoldingRegister beyond range, unexpected error code:%v", exCode)
+		return
+	}
+}
+
+func TestMemoryStoreFileRecordAndFIFO(t *testing.T) {
+	store := NewMemoryStore(testUnits())
+
+	exCode := store.WriteFileRecord(1, 4, 7, []uint16{0x06AF, 0x04BE})
+	if exCode != model.SuccessCode {
+		t.Errorf("WriteFileRecord failed, error code:%v", exCode)
+		return
+	}
+
+	values, exCode := store.ReadFileRecord(1, 4, 8, 2)
+	if exCode != model.SuccessCode || values[0] != 0x04BE || values[1] != 0 {
+		t.Errorf("ReadFileRecord failed, error code:%v, values:%v", exCode, values)
+		return
+	}
+
+	_, exCode = store.ReadFileRecord(1, 0, 0, 1)
+	if exCode != model.IllegalAddress {
+		t.Errorf("ReadFileRecord file 0, unexpected error code:%v", exCode)
+		return
+	}
+
+	_, exCode = store.ReadFileRecord(1, 1, 0x270F, 2)
+	if exCode != model.IllegalAddress {
+		t.Errorf("ReadFileRecord beyond record range, unexpected error code:%v", exCode)
+		return
+	}
+
+	exCode = store.WriteFIFOQueue(1, 0x04DE, make([]uint16, 32))
+	if exCode != model.IllegalCount {
+		t.Errorf("WriteFIFOQueue overflow, unexpected error code:%v", exCode)
+		return
+	}
+
+	store.WriteFIFOQueue(1, 0x04DE, []uint16{0x01B8, 0x1284})
+	values, exCode = store.ReadFIFOQueue(1, 0x04DE)
+	if exCode != model.SuccessCode || len(values) != 2 || values[1] != 0x1284 {
+		t.Errorf("ReadFIFOQueue failed, error code:%v, values:%v", exCode, values)
+		return
+	}
+}
+
+func TestFileStorePersist(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "data", "slave.json")
+	store, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore failed, error:%s", storeErr.Error())
+		return
+	}
+
+	store.WriteCoils(1, 10, []bool{true, false, true})
+	store.WriteHoldingRegisters(2, 150, []uint16{0x1234})
+	store.WriteInputRegisters(1, 0xFFFF, []uint16{0xABCD})
+	store.WriteFileRecord(1, 4, 7, []uint16{0x06AF})
+	store.WriteFIFOQueue(2, 3, []uint16{1, 2})
+	if err := store.(*fileStore).Close(); err != nil {
+		t.Errorf("Close failed, error:%s", err.Error())
+		return
+	}
+
+	store, storeErr = NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore reload failed, error:%s", storeErr.Error())
+		return
+	}
+
+	coils, _ := store.ReadCoils(1, 10, 3)
+	if !coils[0] || coils[1] || !coils[2] {
+		t.Errorf("reload coils failed, values:%v", coils)
+		return
+	}
+
+	registers, _ := store.ReadHoldingRegisters(2, 150, 1)
+	if registers[0] != 0x1234 {
+		t.Errorf("reload holding registers failed, values:%v", registers)
+		return
+	}
+
+	registers, _ = store.ReadInputRegisters(1, 0xFFFF, 1)
+	if registers[0] != 0xABCD {
+		t.Errorf("reload input registers failed, values:%v", registers)
+		return
+	}
+
+	registers, _ = store.ReadFileRecord(1, 4, 7, 1)
+	if registers[0] != 0x06AF {
+		t.Errorf("reload file record failed, values:%v", registers)
+		return
+	}
+
+	registers, _ = store.ReadFIFOQueue(2, 3)
+	if len(registers) != 2 {
+		t.Errorf("reload fifo queue failed, values:%v", registers)
+		return
+	}
+}
+
+func TestFileStoreDelaySave(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "slave.json")
+	store, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore failed, error:%s", storeErr.Error())
+		return
+	}
+
+	// 写操作不等待落盘,saveDelay内的多次写操作合并为一次落盘
+	for idx := uint16(0); idx < 10; idx++ {
+		store.WriteHoldingRegisters(1, idx, []uint16{idx + 1})
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("write should not save synchronously, error:%v", err)
+		return
+	}
+
+	time.Sleep(saveDelay + 100*time.Millisecond)
+	reloadStore, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore reload failed, error:%s", storeErr.Error())
+		return
+	}
+	registers, _ := reloadStore.ReadHoldingRegisters(1, 0, 10)
+	if registers[0] != 1 || registers[9] != 10 {
+		t.Errorf("reload holding registers failed, values:%v", registers)
+		return
+	}
+}
+
+func TestFileStoreSaveFailed(t *testing.T) {
+	blockPath := filepath.Join(t.TempDir(), "block")
+	filePath := filepath.Join(blockPath, "slave.json")
+	store, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore failed, error:%s", storeErr.Error())
+		return
+	}
+
+	// 父路径是普通文件,落盘失败
+	if err := os.WriteFile(blockPath, nil, 0644); err != nil {
+		t.Errorf("create block file failed, error:%s", err.Error())
+		return
+	}
+
+	exCode := store.WriteHoldingRegisters(1, 0, []uint16{0x1234})
+	if exCode != model.SuccessCode {
+		t.Errorf("write should not fail on save error, error code:%v", exCode)
+		return
+	}
+	if err := store.(*fileStore).Close(); err == nil {
+		t.Errorf("Close should return the save error")
+		return
+	}
+
+	// 落盘失败后保留修改标记,恢复后重试成功
+	if err := os.Remove(blockPath); err != nil {
+		t.Errorf("remove block file failed, error:%s", err.Error())
+		return
+	}
+	if err := store.(*fileStore).Close(); err != nil {
+		t.Errorf("retry save failed, error:%s", err.Error())
+		return
+	}
+	reloadStore, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore reload failed, error:%s", storeErr.Error())
+		return
+	}
+	registers, _ := reloadStore.ReadHoldingRegisters(1, 0, 1)
+	if registers[0] != 0x1234 {
+		t.Errorf("reload holding registers failed, values:%v", registers)
+		return
+	}
+}
+
+func TestFileStoreSaveRetry(t *testing.T) {
+	blockPath := filepath.Join(t.TempDir(), "block")
+	filePath := filepath.Join(blockPath, "slave.json")
+	store, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore failed, error:%s", storeErr.Error())
+		return
+	}
+	defer store.(*fileStore).Close()
+
+	// 第一次落盘失败
+	if err := os.WriteFile(blockPath, nil, 0644); err != nil {
+		t.Errorf("create block file failed, error:%s", err.Error())
+		return
+	}
+	store.WriteHoldingRegisters(1, 0, []uint16{0x1234})
+	time.Sleep(saveDelay + 100*time.Millisecond)
+	if err := os.Remove(blockPath); err != nil {
+		t.Errorf("remove block file failed, error:%s", err.Error())
+		return
+	}
+
+	// 没有新的写操作,定时重试后落盘成功
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(filePath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("failed save should be retried without new writes")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reloadStore, storeErr := NewFileStore(filePath, testUnits())
+	if storeErr != nil {
+		t.Errorf("NewFileStore reload failed, error:%s", storeErr.Error())
+		return
+	}
+	registers, _ := reloadStore.ReadHoldingRegisters(1, 0, 1)
+	if registers[0] != 0x1234 {
+		t.Errorf("reload holding registers failed, values:%v", registers)
+		return
+	}
+}
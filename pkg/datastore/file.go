@@ -0,0 +1,257 @@
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/muidea/magicCommon/foundation/log"
+)
+
+// saveDelay 第一次写操作之后等待的时间,期间的写操作合并为一次落盘
+const saveDelay = 200 * time.Millisecond
+
+// maxRetryDelay 落盘失败后重试间隔逐次加倍,最长不超过该值
+const maxRetryDelay = 30 * time.Second
+
+// unitSnapshot 持久化时只保存非零值
+type unitSnapshot struct {
+	UnitID           byte                         `json:"unitID"`
+	Coils            map[uint16]bool              `json:"coils,omitempty"`
+	DiscreteInputs   map[uint16]bool              `json:"discreteInputs,omitempty"`
+	HoldingRegisters map[uint16]uint16            `json:"holdingRegisters,omitempty"`
+	InputRegisters   map[uint16]uint16            `json:"inputRegisters,omitempty"`
+	FileRecords      map[uint16]map[uint16]uint16 `json:"fileRecords,omitempty"`
+	FIFOQueues       map[uint16][]uint16          `json:"fifoQueues,omitempty"`
+}
+
+/*
+fileStore 写操作只修改内存中的数据并标记对应的Unit,saveDelay之后统一落盘,
+落盘时只重新生成有修改的Unit的快照,落盘失败时保留标记并按照retryDelay定时重试
+dirtyUnits、saveTimer、retryDelay与closed由storeLock保护,saveLock保证同一时刻只有一次落盘
+*/
+type fileStore struct {
+	*memoryStore
+	filePath string
+
+	snapshots  map[byte]*unitSnapshot
+	dirtyUnits map[byte]bool
+	saveTimer  *time.Timer
+	retryDelay time.Duration
+	closed     bool
+	saveLock   sync.Mutex
+}
+
+// NewFileStore 新建基于JSON文件的数据存储,写操作合并后异步落盘,返回值实现了Close,关闭时同步落盘剩余的修改
+func NewFileStore(filePath string, units []UnitConfig) (ret DataStore, err error) {
+	storePtr := &fileStore{
+		memoryStore: newMemoryStore(units),
+		filePath:    filePath,
+		snapshots:   map[byte]*unitSnapshot{},
+		dirtyUnits:  map[byte]bool{},
+	}
+
+	err = storePtr.load()
+	if err != nil {
+		return
+	}
+
+	for key, val := range storePtr.units {
+		storePtr.snapshots[key] = val.snapshot()
+	}
+	storePtr.afterWrite = storePtr.markDirty
+	ret = storePtr
+	return
+}
+
+func (s *fileStore) load() (err error) {
+	byteVal, byteErr := os.ReadFile(s.filePath)
+	if byteErr != nil {
+		if errors.Is(byteErr, os.ErrNotExist) {
+			return
+		}
+
+		err = byteErr
+		return
+	}
+
+	snapshots := []*unitSnapshot{}
+	err = json.Unmarshal(byteVal, &snapshots)
+	if err != nil {
+		return
+	}
+
+	for _, val := range snapshots {
+		unitPtr, unitOK := s.units[val.UnitID]
+		if !unitOK {
+			log.Warnf("ignore unconfigured unit data, unitID:%d, file:%s", val.UnitID, s.filePath)
+			continue
+		}
+
+		unitPtr.restore(val)
+	}
+	return
+}
+
+func (s *fileStore) markDirty(unitID byte) {
+	s.dirtyUnits[unitID] = true
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(saveDelay, s.onSaveTimer)
+	}
+}
+
+func (s *fileStore) onSaveTimer() {
+	s.storeLock.Lock()
+	s.saveTimer = nil
+	s.storeLock.Unlock()
+
+	_ = s.flush()
+}
+
+// Close 停止定时落盘并同步保存尚未落盘的修改
+func (s *fileStore) Close() error {
+	s.storeLock.Lock()
+	s.closed = true
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	s.storeLock.Unlock()
+
+	return s.flush()
+}
+
+func (s *fileStore) flush() (err error) {
+	s.saveLock.Lock()
+	defer s.saveLock.Unlock()
+
+	s.storeLock.Lock()
+	dirtyUnits := s.dirtyUnits
+	s.dirtyUnits = map[byte]bool{}
+	for key := range dirtyUnits {
+		s.snapshots[key] = s.units[key].snapshot()
+	}
+	s.storeLock.Unlock()
+	if len(dirtyUnits) == 0 {
+		return
+	}
+
+	err = s.save()
+	s.storeLock.Lock()
+	defer s.storeLock.Unlock()
+	if err == nil {
+		s.retryDelay = 0
+		return
+	}
+
+	for key := range dirtyUnits {
+		s.dirtyUnits[key] = true
+	}
+	s.retryDelay = min(max(s.retryDelay*2, saveDelay), maxRetryDelay)
+	log.Errorf("save datastore failed, retry after %v, file:%s, error:%s", s.retryDelay, s.filePath, err.Error())
+	// 没有新的写操作时也需要重试,关闭后由调用方处理错误
+	if !s.closed && s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(s.retryDelay, s.onSaveTimer)
+	}
+	return
+}
+
+// save 只在flush中调用,snapshots由saveLock保护
+func (s *fileStore) save() (err error) {
+	snapshots := []*unitSnapshot{}
+	for _, val := range s.snapshots {
+		snapshots = append(snapshots, val)
+	}
+
+	byteVal, byteErr := json.Marshal(snapshots)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	// 先写临时文件再改名,避免异常退出时破坏原有数据
+	tmpFile := s.filePath + ".tmp"
+	err = os.MkdirAll(filepath.Dir(s.filePath), os.ModePerm)
+	if err == nil {
+		err = os.WriteFile(tmpFile, byteVal, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, s.filePath)
+	}
+	return
+}
+
+func bitsSnapshot(table *bitTable) map[uint16]bool {
+	ret := map[uint16]bool{}
+	for idx, val := range table.values {
+		if val {
+			ret[table.addrRange.Start+uint16(idx)] = val
+		}
+	}
+
+	return ret
+}
+
+func wordsSnapshot(table *wordTable) map[uint16]uint16 {
+	ret := map[uint16]uint16{}
+	for idx, val := range table.values {
+		if val != 0 {
+			ret[table.addrRange.Start+uint16(idx)] = val
+		}
+	}
+
+	return ret
+}
+
+// snapshot 复制当前数据,快照在释放锁之后序列化,不能引用unitTable中的map
+func (s *unitTable) snapshot() *unitSnapshot {
+	fileRecords := map[uint16]map[uint16]uint16{}
+	for fileNumber, records := range s.fileRecords {
+		fileVal := map[uint16]uint16{}
+		for recordNumber, val := range records {
+			fileVal[recordNumber] = val
+		}
+		fileRecords[fileNumber] = fileVal
+	}
+	fifoQueues := map[uint16][]uint16{}
+	for addr, val := range s.fifoQueues {
+		fifoQueues[addr] = append([]uint16{}, val...)
+	}
+
+	return &unitSnapshot{
+		UnitID:           s.unitID,
+		Coils:            bitsSnapshot(s.coils),
+		DiscreteInputs:   bitsSnapshot(s.discreteInputs),
+		HoldingRegisters: wordsSnapshot(s.holdingRegisters),
+		InputRegisters:   wordsSnapshot(s.inputRegisters),
+		FileRecords:      fileRecords,
+		FIFOQueues:       fifoQueues,
+	}
+}
+
+// restore 超出当前配置地址区间的数据直接丢弃
+func (s *unitTable) restore(snapshot *unitSnapshot) {
+	for addr, val := range snapshot.Coils {
+		s.coils.write(addr, []bool{val})
+	}
+	for addr, val := range snapshot.DiscreteInputs {
+		s.discreteInputs.write(addr, []bool{val})
+	}
+	for addr, val := range snapshot.HoldingRegisters {
+		s.holdingRegisters.write(addr, []uint16{val})
+	}
+	for addr, val := range snapshot.InputRegisters {
+		s.inputRegisters.write(addr, []uint16{val})
+	}
+	for fileNumber, records := range snapshot.FileRecords {
+		for recordNumber, val := range records {
+			s.writeFileRecord(fileNumber, recordNumber, []uint16{val})
+		}
+	}
+	for addr, val := range snapshot.FIFOQueues {
+		s.writeFIFOQueue(addr, val)
+	}
+}
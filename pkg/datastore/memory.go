@@ -0,0 +1,156 @@
+package datastore
+
+import (
+	"sync"
+
+	"github.com/muidea/quickModbus/pkg/model"
+)
+
+type memoryStore struct {
+	units     map[byte]*unitTable
+	storeLock sync.RWMutex
+
+	// afterWrite 写操作成功后在持有写锁的情况下回调,用于标记需要持久化的Unit
+	afterWrite func(unitID byte)
+}
+
+// NewMemoryStore 新建内存数据存储,未配置的UnitID访问时返回IllegalAddress
+func NewMemoryStore(units []UnitConfig) DataStore {
+	return newMemoryStore(units)
+}
+
+func newMemoryStore(units []UnitConfig) *memoryStore {
+	ptr := &memoryStore{
+		units: map[byte]*unitTable{},
+	}
+	for _, val := range units {
+		ptr.units[val.UnitID] = newUnitTable(val)
+	}
+
+	return ptr
+}
+
+func (s *memoryStore) readUnit(unitID byte, readFunc func(unit *unitTable) byte) byte {
+	s.storeLock.RLock()
+	defer s.storeLock.RUnlock()
+
+	unitPtr, unitOK := s.units[unitID]
+	if !unitOK {
+		return model.IllegalAddress
+	}
+
+	return readFunc(unitPtr)
+}
+
+func (s *memoryStore) writeUnit(unitID byte, writeFunc func(unit *unitTable) byte) byte {
+	s.storeLock.Lock()
+	defer s.storeLock.Unlock()
+
+	unitPtr, unitOK := s.units[unitID]
+	if !unitOK {
+		return model.IllegalAddress
+	}
+
+	exCode := writeFunc(unitPtr)
+	if exCode == model.SuccessCode && s.afterWrite != nil {
+		s.afterWrite(unitID)
+	}
+
+	return exCode
+}
+
+func (s *memoryStore) ReadCoils(unitID byte, address, count uint16) (ret []bool, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.coils.read(address, count)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteCoils(unitID byte, address uint16, values []bool) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.coils.write(address, values)
+	})
+}
+
+func (s *memoryStore) ReadDiscreteInputs(unitID byte, address, count uint16) (ret []bool, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.discreteInputs.read(address, count)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteDiscreteInputs(unitID byte, address uint16, values []bool) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.discreteInputs.write(address, values)
+	})
+}
+
+func (s *memoryStore) ReadHoldingRegisters(unitID byte, address, count uint16) (ret []uint16, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.holdingRegisters.read(address, count)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteHoldingRegisters(unitID byte, address uint16, values []uint16) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.holdingRegisters.write(address, values)
+	})
+}
+
+// MaskWriteHoldingRegister Result = (Current Contents AND And_Mask) OR (Or_Mask AND (NOT And_Mask))
+func (s *memoryStore) MaskWriteHoldingRegister(unitID byte, address, andMask, orMask uint16) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		curVal, exCode := unit.holdingRegisters.read(address, 1)
+		if exCode != model.SuccessCode {
+			return exCode
+		}
+
+		return unit.holdingRegisters.write(address, []uint16{(curVal[0] & andMask) | (orMask & ^andMask)})
+	})
+}
+
+func (s *memoryStore) ReadInputRegisters(unitID byte, address, count uint16) (ret []uint16, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.inputRegisters.read(address, count)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteInputRegisters(unitID byte, address uint16, values []uint16) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.inputRegisters.write(address, values)
+	})
+}
+
+func (s *memoryStore) ReadFileRecord(unitID byte, fileNumber, recordNumber, recordLength uint16) (ret []uint16, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.readFileRecord(fileNumber, recordNumber, recordLength)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteFileRecord(unitID byte, fileNumber, recordNumber uint16, values []uint16) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.writeFileRecord(fileNumber, recordNumber, values)
+	})
+}
+
+func (s *memoryStore) ReadFIFOQueue(unitID byte, address uint16) (ret []uint16, exCode byte) {
+	exCode = s.readUnit(unitID, func(unit *unitTable) (err byte) {
+		ret, err = unit.readFIFOQueue(address)
+		return
+	})
+	return
+}
+
+func (s *memoryStore) WriteFIFOQueue(unitID byte, address uint16, values []uint16) byte {
+	return s.writeUnit(unitID, func(unit *unitTable) byte {
+		return unit.writeFIFOQueue(address, values)
+	})
+}
@@ -0,0 +1,172 @@
+package datastore
+
+import "github.com/muidea/quickModbus/pkg/model"
+
+type bitTable struct {
+	addrRange Range
+	values    []bool
+}
+
+func newBitTable(addrRange Range) *bitTable {
+	return &bitTable{
+		addrRange: addrRange,
+		values:    make([]bool, addrRange.size()),
+	}
+}
+
+func (s *bitTable) read(address, count uint16) (ret []bool, exCode byte) {
+	exCode = checkRange(s.addrRange, address, int(count))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	offset := int(address - s.addrRange.Start)
+	ret = make([]bool, count)
+	copy(ret, s.values[offset:offset+int(count)])
+	return
+}
+
+func (s *bitTable) write(address uint16, values []bool) (exCode byte) {
+	exCode = checkRange(s.addrRange, address, len(values))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	copy(s.values[address-s.addrRange.Start:], values)
+	return
+}
+
+type wordTable struct {
+	addrRange Range
+	values    []uint16
+}
+
+func newWordTable(addrRange Range) *wordTable {
+	return &wordTable{
+		addrRange: addrRange,
+		values:    make([]uint16, addrRange.size()),
+	}
+}
+
+func (s *wordTable) read(address, count uint16) (ret []uint16, exCode byte) {
+	exCode = checkRange(s.addrRange, address, int(count))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	offset := int(address - s.addrRange.Start)
+	ret = make([]uint16, count)
+	copy(ret, s.values[offset:offset+int(count)])
+	return
+}
+
+func (s *wordTable) write(address uint16, values []uint16) (exCode byte) {
+	exCode = checkRange(s.addrRange, address, len(values))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	copy(s.values[address-s.addrRange.Start:], values)
+	return
+}
+
+func checkRange(addrRange Range, address uint16, count int) byte {
+	if count < 1 {
+		return model.IllegalCount
+	}
+	if address < addrRange.Start || int(address)+count-1 > int(addrRange.End) || addrRange.size() == 0 {
+		return model.IllegalAddress
+	}
+
+	return model.SuccessCode
+}
+
+func checkFileRecord(fileNumber, recordNumber uint16, recordLength int) byte {
+	if recordLength < 1 {
+		return model.IllegalCount
+	}
+	if fileNumber == 0 || fileNumber > maxFileNumber {
+		return model.IllegalAddress
+	}
+	if int(recordNumber)+recordLength > maxRecordNumber+1 {
+		return model.IllegalAddress
+	}
+
+	return model.SuccessCode
+}
+
+// unitTable 单个UnitID下的全部数据
+type unitTable struct {
+	unitID           byte
+	coils            *bitTable
+	discreteInputs   *bitTable
+	holdingRegisters *wordTable
+	inputRegisters   *wordTable
+	fileRecords      map[uint16]map[uint16]uint16
+	fifoQueues       map[uint16][]uint16
+}
+
+func newUnitTable(cfg UnitConfig) *unitTable {
+	return &unitTable{
+		unitID:           cfg.UnitID,
+		coils:            newBitTable(cfg.Coils),
+		discreteInputs:   newBitTable(cfg.DiscreteInputs),
+		holdingRegisters: newWordTable(cfg.HoldingRegisters),
+		inputRegisters:   newWordTable(cfg.InputRegisters),
+		fileRecords:      map[uint16]map[uint16]uint16{},
+		fifoQueues:       map[uint16][]uint16{},
+	}
+}
+
+func (s *unitTable) readFileRecord(fileNumber, recordNumber, recordLength uint16) (ret []uint16, exCode byte) {
+	exCode = checkFileRecord(fileNumber, recordNumber, int(recordLength))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	ret = make([]uint16, recordLength)
+	fileVal := s.fileRecords[fileNumber]
+	for idx := uint16(0); idx < recordLength; idx++ {
+		ret[idx] = fileVal[recordNumber+idx]
+	}
+	return
+}
+
+func (s *unitTable) writeFileRecord(fileNumber, recordNumber uint16, values []uint16) (exCode byte) {
+	exCode = checkFileRecord(fileNumber, recordNumber, len(values))
+	if exCode != model.SuccessCode {
+		return
+	}
+
+	fileVal, fileOK := s.fileRecords[fileNumber]
+	if !fileOK {
+		fileVal = map[uint16]uint16{}
+		s.fileRecords[fileNumber] = fileVal
+	}
+	for idx, val := range values {
+		fileVal[recordNumber+uint16(idx)] = val
+	}
+	return
+}
+
+func (s *unitTable) readFIFOQueue(address uint16) (ret []uint16, exCode byte) {
+	queueVal := s.fifoQueues[address]
+	if len(queueVal) > maxFIFOCount {
+		exCode = model.IllegalCount
+		return
+	}
+
+	ret = make([]uint16, len(queueVal))
+	copy(ret, queueVal)
+	return
+}
+
+func (s *unitTable) writeFIFOQueue(address uint16, values []uint16) (exCode byte) {
+	if len(values) > maxFIFOCount {
+		exCode = model.IllegalCount
+		return
+	}
+
+	s.fifoQueues[address] = append([]uint16{}, values...)
+	return
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+降采样的聚合方式
+*/
+const (
+	AggMin = "min"
+	AggMax = "max"
+	AggAvg = "avg"
+)
+
+func ValidAgg(agg string) bool {
+	return agg == AggMin || agg == AggMax || agg == AggAvg
+}
+
+// Bucket 降采样后的单个时间段,Time为时间段的起始时间,Count为参与聚合的取值数
+type Bucket struct {
+	Time  time.Time
+	Value float64
+	Count int
+}
+
+/*
+Downsample 从from开始按照step划分时间段并聚合每个时间段内的取值
+只聚合读取成功且有取值的Sample,没有可聚合取值的时间段不返回,samples需要按照时间排序
+*/
+func Downsample(samples []Sample, from time.Time, step time.Duration, agg string) (ret []Bucket, err error) {
+	if !ValidAgg(agg) {
+		err = fmt.Errorf("illegal agg '%s'", agg)
+		return
+	}
+	if step <= 0 {
+		err = fmt.Errorf("illegal step %s", step)
+		return
+	}
+
+	ret = []Bucket{}
+	var bucketPtr *Bucket
+	for _, val := range samples {
+		if !val.Good || math.IsNaN(val.Value) || val.Time.Before(from) {
+			continue
+		}
+
+		bucketTime := from.Add(val.Time.Sub(from) / step * step)
+		if bucketPtr == nil || !bucketPtr.Time.Equal(bucketTime) {
+			if bucketPtr != nil {
+				ret = append(ret, finishBucket(*bucketPtr, agg))
+			}
+			bucketPtr = &Bucket{Time: bucketTime, Value: val.Value, Count: 1}
+			continue
+		}
+
+		bucketPtr.Count++
+		switch agg {
+		case AggMin:
+			bucketPtr.Value = math.Min(bucketPtr.Value, val.Value)
+		case AggMax:
+			bucketPtr.Value = math.Max(bucketPtr.Value, val.Value)
+		case AggAvg:
+			bucketPtr.Value += val.Value
+		}
+	}
+	if bucketPtr != nil {
+		ret = append(ret, finishBucket(*bucketPtr, agg))
+	}
+	return
+}
+
+func finishBucket(bucket Bucket, agg string) Bucket {
+	if agg == AggAvg {
+		bucket.Value /= float64(bucket.Count)
+	}
+
+	return bucket
+}
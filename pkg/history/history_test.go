@@ -0,0 +1,163 @@
+package history
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(Options{Dir: dir, SegmentDuration: time.Minute, Retention: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("Open failed, error:%s", err.Error())
+	}
+
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	for idx := 0; idx < 180; idx++ {
+		timeVal := base.Add(time.Duration(idx) * time.Second)
+		_ = store.Append("temp", Sample{Time: timeVal, Value: float64(idx), Good: true})
+		_ = store.Append("speed", Sample{Time: timeVal, Value: 1450, Good: idx%2 == 0})
+	}
+
+	samples, err := store.Query("temp", base.Add(30*time.Second), base.Add(90*time.Second))
+	if err != nil {
+		t.Fatalf("Query failed, error:%s", err.Error())
+	}
+	if len(samples) != 61 || samples[0].Value != 30 || samples[60].Value != 90 || !samples[0].Time.Equal(base.Add(30*time.Second)) {
+		t.Errorf("Query failed, samples:%d", len(samples))
+	}
+
+	segments, _ := listSegments(dir)
+	if len(segments) != 3 {
+		t.Errorf("listSegments failed, segments:%d", len(segments))
+	}
+
+	// 异常退出时残留的不完整记录在重新打开时被截掉
+	_ = store.Close()
+	lastSegment := segments[len(segments)-1].path
+	fileHandle, _ := os.OpenFile(lastSegment, os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = fileHandle.Write(encodeRecord("temp", Sample{Time: base.Add(170 * time.Second)})[:10])
+	_ = fileHandle.Close()
+
+	store, err = Open(Options{Dir: dir, SegmentDuration: time.Minute, Retention: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("Open failed, error:%s", err.Error())
+	}
+	defer store.Close()
+	_ = store.Append("temp", Sample{Time: base.Add(179*time.Second + time.Millisecond), Value: math.NaN()})
+	samples, _ = store.Query("temp", base.Add(2*time.Minute), base.Add(3*time.Minute))
+	if len(samples) != 61 || samples[60].Good || !math.IsNaN(samples[60].Value) {
+		t.Errorf("Query after reopen failed, samples:%d", len(samples))
+	}
+
+	removed, err := store.Purge(base.Add(12 * time.Minute))
+	if err != nil || removed != 2 {
+		t.Errorf("Purge failed, removed:%d, err:%v", removed, err)
+	}
+	samples, _ = store.Query("speed", base, base.Add(time.Hour))
+	if len(samples) != 60 {
+		t.Errorf("Query after purge failed, samples:%d", len(samples))
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, segmentName(base, base.Add(time.Minute)))); !os.IsNotExist(err) {
+		t.Error("Purge should remove expired segment")
+	}
+}
+
+func TestSegmentFor(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(Options{Dir: dir, SegmentDuration: time.Hour})
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// 调整SegmentDuration后不产生与已有数据段重叠的数据段
+	_ = os.WriteFile(filepath.Join(dir, segmentName(base.Add(20*time.Minute), base.Add(30*time.Minute))), nil, 0644)
+	items := []struct {
+		timeVal    time.Time
+		start, end time.Time
+	}{
+		{base.Add(25 * time.Minute), base.Add(20 * time.Minute), base.Add(30 * time.Minute)},
+		{base.Add(10 * time.Minute), base, base.Add(20 * time.Minute)},
+		{base.Add(40 * time.Minute), base.Add(30 * time.Minute), base.Add(time.Hour)},
+	}
+	for _, val := range items {
+		infoPtr := store.segmentFor(val.timeVal)
+		if !infoPtr.start.Equal(val.start) || !infoPtr.end.Equal(val.end) {
+			t.Errorf("segmentFor failed, time:%s, start:%s, end:%s", val.timeVal, infoPtr.start, infoPtr.end)
+		}
+	}
+}
+
+func TestStoreBoundary(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(Options{Dir: dir, SegmentDuration: time.Minute})
+	defer store.Close()
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// 数据段边界附近乱序写入时复用已经打开的数据段
+	_ = store.Append("temp", Sample{Time: base.Add(59 * time.Second), Value: 0, Good: true})
+	_ = store.Append("temp", Sample{Time: base.Add(60 * time.Second), Value: 1, Good: true})
+	writers := append([]*segmentWriter(nil), store.writers...)
+	for idx := 2; idx < 10; idx++ {
+		timeVal := base.Add(59 * time.Second)
+		if idx%2 == 1 {
+			timeVal = base.Add(60 * time.Second)
+		}
+		_ = store.Append("temp", Sample{Time: timeVal.Add(time.Duration(idx) * time.Millisecond), Value: float64(idx), Good: true})
+	}
+	if len(store.writers) != maxOpenWriters || store.writers[0] != writers[0] || store.writers[1] != writers[1] {
+		t.Errorf("Append should reuse open segments, writers:%d", len(store.writers))
+	}
+
+	_ = store.Append("temp", Sample{Time: base.Add(150 * time.Second), Value: 10, Good: true})
+	if len(store.writers) != maxOpenWriters || store.writers[1] != writers[0] {
+		t.Errorf("Append should close the least recently written segment, writers:%d", len(store.writers))
+	}
+
+	samples, err := store.Query("temp", base, base.Add(3*time.Minute))
+	if err != nil || len(samples) != 11 || samples[10].Value != 10 {
+		t.Errorf("Query failed, samples:%d, err:%v", len(samples), err)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Time: base, Value: 1, Good: true},
+		{Time: base.Add(10 * time.Second), Value: 5, Good: true},
+		{Time: base.Add(20 * time.Second), Value: 100, Good: false},
+		{Time: base.Add(70 * time.Second), Value: 3, Good: true},
+		{Time: base.Add(80 * time.Second), Value: math.NaN(), Good: true},
+		{Time: base.Add(190 * time.Second), Value: 7, Good: true},
+	}
+
+	items := []struct {
+		agg    string
+		values []float64
+	}{
+		{AggMin, []float64{1, 3, 7}},
+		{AggMax, []float64{5, 3, 7}},
+		{AggAvg, []float64{3, 3, 7}},
+	}
+	for _, val := range items {
+		buckets, err := Downsample(samples, base, time.Minute, val.agg)
+		if err != nil || len(buckets) != len(val.values) {
+			t.Errorf("Downsample failed, agg:%s, buckets:%v, err:%v", val.agg, buckets, err)
+			continue
+		}
+		for idx, bucket := range buckets {
+			if bucket.Value != val.values[idx] {
+				t.Errorf("Downsample failed, agg:%s, idx:%d, value:%v", val.agg, idx, bucket.Value)
+			}
+		}
+		if !buckets[2].Time.Equal(base.Add(3*time.Minute)) || buckets[0].Count != 2 {
+			t.Errorf("Downsample failed, agg:%s, buckets:%v", val.agg, buckets)
+		}
+	}
+
+	if _, err := Downsample(samples, base, time.Minute, "sum"); err == nil {
+		t.Error("Downsample should fail for illegal agg")
+	}
+}
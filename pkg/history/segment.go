@@ -0,0 +1,212 @@
+package history
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const segmentExt = ".seg"
+
+// maxNameLength 记录中Tag名称的最大长度
+const maxNameLength = 0xFFFF
+
+/*
+记录格式: crc32(4) + 名称长度(2) + 名称 + UnixNano(8) + Value(8) + Good(1),均为大端序
+crc32校验crc之后的全部内容,读取到校验失败或者不完整的记录时视为文件结束
+*/
+const recordHeadSize = 4 + 2
+
+const recordBodySize = 8 + 8 + 1
+
+var errTornRecord = errors.New("torn history record")
+
+// segmentInfo 数据段文件覆盖[start, end)时间区间内的记录,文件名为start-end的Unix秒
+type segmentInfo struct {
+	path  string
+	start time.Time
+	end   time.Time
+}
+
+func segmentName(start, end time.Time) string {
+	return fmt.Sprintf("%d-%d%s", start.Unix(), end.Unix(), segmentExt)
+}
+
+func parseSegmentName(dir, name string) (ret *segmentInfo, ok bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return
+	}
+
+	items := strings.Split(strings.TrimSuffix(name, segmentExt), "-")
+	if len(items) != 2 {
+		return
+	}
+	startVal, startErr := strconv.ParseInt(items[0], 10, 64)
+	endVal, endErr := strconv.ParseInt(items[1], 10, 64)
+	if startErr != nil || endErr != nil || endVal <= startVal {
+		return
+	}
+
+	ret = &segmentInfo{path: filepath.Join(dir, name), start: time.Unix(startVal, 0), end: time.Unix(endVal, 0)}
+	ok = true
+	return
+}
+
+// listSegments 按照起始时间排序
+func listSegments(dir string) (ret []*segmentInfo, err error) {
+	entries, entriesErr := os.ReadDir(dir)
+	if entriesErr != nil {
+		err = entriesErr
+		return
+	}
+
+	for _, val := range entries {
+		if val.IsDir() {
+			continue
+		}
+		if infoPtr, ok := parseSegmentName(dir, val.Name()); ok {
+			ret = append(ret, infoPtr)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].start.Before(ret[j].start)
+	})
+	return
+}
+
+func encodeRecord(name string, sample Sample) []byte {
+	data := make([]byte, recordHeadSize, recordHeadSize+len(name)+recordBodySize)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(name)))
+	data = append(data, name...)
+	data = binary.BigEndian.AppendUint64(data, uint64(sample.Time.UnixNano()))
+	data = binary.BigEndian.AppendUint64(data, math.Float64bits(sample.Value))
+	if sample.Good {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+
+	binary.BigEndian.PutUint32(data, crc32.ChecksumIEEE(data[4:]))
+	return data
+}
+
+// readRecord 返回记录及其占用的字节数,文件结束时返回io.EOF,记录不完整或者校验失败时返回errTornRecord
+func readRecord(reader *bufio.Reader) (name string, sample Sample, size int, err error) {
+	head := make([]byte, recordHeadSize)
+	_, err = io.ReadFull(reader, head)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errTornRecord
+		}
+		return
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(head[4:]))
+	body := make([]byte, 2+nameLen+recordBodySize)
+	copy(body, head[4:])
+	_, err = io.ReadFull(reader, body[2:])
+	if err != nil {
+		err = errTornRecord
+		return
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(head) {
+		err = errTornRecord
+		return
+	}
+
+	name = string(body[2 : 2+nameLen])
+	offset := 2 + nameLen
+	sample.Time = time.Unix(0, int64(binary.BigEndian.Uint64(body[offset:])))
+	sample.Value = math.Float64frombits(binary.BigEndian.Uint64(body[offset+8:]))
+	sample.Good = body[offset+16] == 1
+	size = recordHeadSize + nameLen + recordBodySize
+	return
+}
+
+// scanSegment 依次读取数据段中的记录,返回完整记录的总长度,handler返回false时停止读取
+func scanSegment(filePath string, handler func(name string, sample Sample) bool) (validSize int64, err error) {
+	fileHandle, fileErr := os.Open(filePath)
+	if fileErr != nil {
+		err = fileErr
+		return
+	}
+	defer fileHandle.Close()
+
+	reader := bufio.NewReader(fileHandle)
+	for {
+		name, sample, size, recordErr := readRecord(reader)
+		if recordErr != nil {
+			if !errors.Is(recordErr, io.EOF) && !errors.Is(recordErr, errTornRecord) {
+				err = recordErr
+			}
+			return
+		}
+
+		validSize += int64(size)
+		if !handler(name, sample) {
+			return
+		}
+	}
+}
+
+// segmentWriter 当前写入的数据段,打开时截掉异常退出时残留的不完整记录
+type segmentWriter struct {
+	info   *segmentInfo
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func openSegmentWriter(infoPtr *segmentInfo) (ret *segmentWriter, err error) {
+	validSize, scanErr := scanSegment(infoPtr.path, func(string, Sample) bool { return true })
+	if scanErr != nil && !errors.Is(scanErr, os.ErrNotExist) {
+		err = scanErr
+		return
+	}
+
+	fileHandle, fileErr := os.OpenFile(infoPtr.path, os.O_CREATE|os.O_WRONLY, 0644)
+	if fileErr != nil {
+		err = fileErr
+		return
+	}
+
+	err = fileHandle.Truncate(validSize)
+	if err == nil {
+		_, err = fileHandle.Seek(validSize, io.SeekStart)
+	}
+	if err != nil {
+		_ = fileHandle.Close()
+		return
+	}
+
+	ret = &segmentWriter{info: infoPtr, file: fileHandle, writer: bufio.NewWriter(fileHandle)}
+	return
+}
+
+func (s *segmentWriter) write(data []byte) (err error) {
+	_, err = s.writer.Write(data)
+	return
+}
+
+func (s *segmentWriter) flush() (err error) {
+	return s.writer.Flush()
+}
+
+func (s *segmentWriter) close() (err error) {
+	err = s.writer.Flush()
+	closeErr := s.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return
+}
@@ -0,0 +1,295 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultSegmentDuration = time.Hour
+
+/*
+Options 历史数据存储参数
+Dir为数据段文件所在目录,SegmentDuration为单个数据段覆盖的时长,为0时为1小时
+Retention为数据保留时长,为0时不清理,清理以数据段为单位,数据段的结束时间早于保留时长才被删除
+*/
+type Options struct {
+	Dir             string
+	SegmentDuration time.Duration
+	Retention       time.Duration
+}
+
+// Sample 单个历史取值,Good为false时表示读取失败,Value为NaN时表示没有取值
+type Sample struct {
+	Time  time.Time
+	Value float64
+	Good  bool
+}
+
+// maxOpenWriters 同时打开的数据段数,并行的扫描组在数据段边界附近写入的取值可能乱序
+const maxOpenWriters = 2
+
+/*
+Store 基于数据段文件的历史数据存储,记录按照取值时间写入对应的数据段,
+写入先进入缓冲区,由Flush或者查询时落盘
+*/
+type Store struct {
+	options Options
+
+	lock sync.Mutex
+	// writers 打开的数据段,按照最近写入排序
+	writers []*segmentWriter
+	closed  bool
+}
+
+func Open(options Options) (ret *Store, err error) {
+	if options.Dir == "" {
+		err = fmt.Errorf("illegal history dir, dir is empty")
+		return
+	}
+	if options.SegmentDuration <= 0 {
+		options.SegmentDuration = defaultSegmentDuration
+	}
+	if options.SegmentDuration < time.Second || options.Retention < 0 {
+		err = fmt.Errorf("illegal history options, segmentDuration:%s, retention:%s", options.SegmentDuration, options.Retention)
+		return
+	}
+
+	err = os.MkdirAll(options.Dir, os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	ret = &Store{options: options}
+	return
+}
+
+// segmentFor 取值时间所在的数据段,按照SegmentDuration对齐
+func (s *Store) segmentFor(timeVal time.Time) *segmentInfo {
+	start := timeVal.Truncate(s.options.SegmentDuration)
+	end := start.Add(s.options.SegmentDuration)
+
+	// 已存在覆盖该时间的数据段时继续写入,避免SegmentDuration调整后产生重叠的数据段
+	segments, _ := listSegments(s.options.Dir)
+	for _, val := range segments {
+		if !timeVal.Before(val.start) && timeVal.Before(val.end) {
+			return val
+		}
+		if val.start.Before(end) && val.end.After(start) {
+			if val.end.After(timeVal) {
+				end = val.start
+			} else {
+				start = val.end
+			}
+		}
+	}
+
+	infoPtr, _ := parseSegmentName(s.options.Dir, segmentName(start, end))
+	return infoPtr
+}
+
+func (s *Store) Append(name string, sample Sample) (err error) {
+	if name == "" || len(name) > maxNameLength {
+		err = fmt.Errorf("illegal history name '%s'", name)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		err = os.ErrClosed
+		return
+	}
+
+	writer, writerErr := s.fetchWriter(sample.Time)
+	if writerErr != nil {
+		err = writerErr
+		return
+	}
+
+	err = writer.write(encodeRecord(name, sample))
+	return
+}
+
+// fetchWriter 优先使用已经打开的数据段,超过maxOpenWriters时关闭最久未写入的数据段
+func (s *Store) fetchWriter(timeVal time.Time) (ret *segmentWriter, err error) {
+	for idx, val := range s.writers {
+		if !timeVal.Before(val.info.start) && timeVal.Before(val.info.end) {
+			copy(s.writers[1:idx+1], s.writers[:idx])
+			s.writers[0] = val
+			ret = val
+			return
+		}
+	}
+
+	infoPtr := s.segmentFor(timeVal)
+	if infoPtr == nil {
+		err = fmt.Errorf("illegal history time %s", timeVal)
+		return
+	}
+
+	writer, writerErr := openSegmentWriter(infoPtr)
+	if writerErr != nil {
+		err = writerErr
+		return
+	}
+
+	s.writers = append([]*segmentWriter{writer}, s.writers...)
+	if len(s.writers) > maxOpenWriters {
+		lastWriter := s.writers[len(s.writers)-1]
+		s.writers = s.writers[:len(s.writers)-1]
+		err = lastWriter.close()
+		if err != nil {
+			return
+		}
+	}
+
+	ret = writer
+	return
+}
+
+func (s *Store) Flush() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err = s.flushWriters()
+	return
+}
+
+func (s *Store) flushWriters() (err error) {
+	for _, val := range s.writers {
+		err = val.flush()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Query 查询[from, to]区间内name的历史取值,按照时间排序,读取数据段时不阻塞写入
+func (s *Store) Query(name string, from, to time.Time) (ret []Sample, err error) {
+	segments, segmentsErr := s.querySegments(from, to)
+	if segmentsErr != nil {
+		err = segmentsErr
+		return
+	}
+
+	ret = []Sample{}
+	for _, val := range segments {
+		_, err = scanSegment(val.path, func(itemName string, sample Sample) bool {
+			if itemName == name && !sample.Time.Before(from) && !sample.Time.After(to) {
+				ret = append(ret, sample)
+			}
+			return true
+		})
+		// 读取期间数据段可能被Purge删除
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		err = nil
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Time.Before(ret[j].Time)
+	})
+	return
+}
+
+// querySegments 缓冲区落盘后返回与[from, to]重叠的数据段
+func (s *Store) querySegments(from, to time.Time) (ret []*segmentInfo, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		err = os.ErrClosed
+		return
+	}
+
+	err = s.flushWriters()
+	if err != nil {
+		return
+	}
+
+	segments, segmentsErr := listSegments(s.options.Dir)
+	if segmentsErr != nil {
+		err = segmentsErr
+		return
+	}
+
+	for _, val := range segments {
+		if val.end.Before(from) || val.start.After(to) {
+			continue
+		}
+		ret = append(ret, val)
+	}
+	return
+}
+
+// Purge 删除结束时间早于now减去Retention的数据段,返回删除的数据段数
+func (s *Store) Purge(now time.Time) (ret int, err error) {
+	if s.options.Retention == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		err = os.ErrClosed
+		return
+	}
+
+	segments, segmentsErr := listSegments(s.options.Dir)
+	if segmentsErr != nil {
+		err = segmentsErr
+		return
+	}
+
+	deadline := now.Add(-s.options.Retention)
+	for _, val := range segments {
+		if val.end.After(deadline) {
+			continue
+		}
+		err = s.closeWriter(val.path)
+		if err != nil {
+			return
+		}
+
+		err = os.Remove(val.path)
+		if err != nil {
+			return
+		}
+		ret++
+	}
+	return
+}
+
+func (s *Store) closeWriter(path string) (err error) {
+	for idx, val := range s.writers {
+		if val.info.path == path {
+			s.writers = append(s.writers[:idx], s.writers[idx+1:]...)
+			err = val.close()
+			return
+		}
+	}
+	return
+}
+
+func (s *Store) Close() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	for _, val := range s.writers {
+		closeErr := val.close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	s.writers = nil
+	return
+}
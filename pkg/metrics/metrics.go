@@ -0,0 +1,404 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets 默认的直方图分组,单位为秒
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 进程内共享的指标集合,/metrics输出该集合
+var Default = NewRegistry()
+
+var nameReg = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+var labelReg = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type collector interface {
+	name() string
+	write(writer *bufio.Writer)
+}
+
+// Registry 指标集合,指标名称不能重复,按照名称排序输出
+type Registry struct {
+	lock       sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]collector{}}
+}
+
+// register 名称或者标签不合法、名称重复时panic,与在init中定义指标的用法保持一致
+func (s *Registry) register(val collector, labelNames []string) {
+	if !nameReg.MatchString(val.name()) {
+		panic(fmt.Sprintf("illegal metric name '%s'", val.name()))
+	}
+	for _, label := range labelNames {
+		if !labelReg.MatchString(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("illegal metric label '%s', metric:%s", label, val.name()))
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.collectors[val.name()]; ok {
+		panic(fmt.Sprintf("duplicate metric '%s'", val.name()))
+	}
+	s.collectors[val.name()] = val
+}
+
+func (s *Registry) WriteText(w io.Writer) error {
+	s.lock.RLock()
+	names := make([]string, 0, len(s.collectors))
+	for key := range s.collectors {
+		names = append(names, key)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, val := range names {
+		collectors = append(collectors, s.collectors[val])
+	}
+	s.lock.RUnlock()
+
+	writer := bufio.NewWriter(w)
+	for _, val := range collectors {
+		val.write(writer)
+	}
+	return writer.Flush()
+}
+
+func (s *Registry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", ContentType)
+	_ = s.WriteText(res)
+}
+
+// desc 指标的名称、说明、类型及标签名
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+	labelNames []string
+}
+
+func (s *desc) name() string {
+	return s.metricName
+}
+
+func (s *desc) writeHead(writer *bufio.Writer) {
+	fmt.Fprintf(writer, "# HELP %s %s\n", s.metricName, escapeHelp(s.help))
+	fmt.Fprintf(writer, "# TYPE %s %s\n", s.metricName, s.kind)
+}
+
+// labelKey 标签值数量与标签名不一致时panic,属于调用方的编码错误
+func (s *desc) labelKey(labelValues []string) string {
+	if len(labelValues) != len(s.labelNames) {
+		panic(fmt.Sprintf("illegal label values %v, metric:%s, labels:%v", labelValues, s.metricName, s.labelNames))
+	}
+
+	return strings.Join(labelValues, "\xff")
+}
+
+func (s *desc) formatLabels(labelValues []string, extraName, extraValue string) string {
+	if len(labelValues) == 0 && extraName == "" {
+		return ""
+	}
+
+	items := make([]string, 0, len(labelValues)+1)
+	for idx, val := range labelValues {
+		items = append(items, fmt.Sprintf("%s=\"%s\"", s.labelNames[idx], escapeLabel(val)))
+	}
+	if extraName != "" {
+		items = append(items, fmt.Sprintf("%s=\"%s\"", extraName, extraValue))
+	}
+	return "{" + strings.Join(items, ",") + "}"
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(val string) string {
+	return helpReplacer.Replace(val)
+}
+
+func escapeLabel(val string) string {
+	return labelReplacer.Replace(val)
+}
+
+func formatValue(val float64) string {
+	switch {
+	case math.IsInf(val, 1):
+		return "+Inf"
+	case math.IsInf(val, -1):
+		return "-Inf"
+	case math.IsNaN(val):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// series 单个标签组合的取值
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// valueVec Counter与Gauge共用的按标签组合存储的取值
+type valueVec struct {
+	desc
+
+	lock      sync.Mutex
+	seriesMap map[string]*series
+}
+
+func (s *valueVec) add(delta float64, labelValues []string) {
+	key := s.labelKey(labelValues)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	seriesPtr, ok := s.seriesMap[key]
+	if !ok {
+		seriesPtr = &series{labelValues: append([]string(nil), labelValues...)}
+		s.seriesMap[key] = seriesPtr
+	}
+	seriesPtr.value += delta
+}
+
+func (s *valueVec) write(writer *bufio.Writer) {
+	s.lock.Lock()
+	items := make([]series, 0, len(s.seriesMap))
+	for _, val := range s.seriesMap {
+		items = append(items, *val)
+	}
+	s.lock.Unlock()
+
+	sortSeries(items)
+	s.writeHead(writer)
+	for _, val := range items {
+		fmt.Fprintf(writer, "%s%s %s\n", s.metricName, s.formatLabels(val.labelValues, "", ""), formatValue(val.value))
+	}
+}
+
+// DeleteMatch 删除前若干个标签取值与labelValues相同的全部标签组合
+func (s *valueVec) DeleteMatch(labelValues ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, val := range s.seriesMap {
+		if matchLabels(val.labelValues, labelValues) {
+			delete(s.seriesMap, key)
+		}
+	}
+}
+
+func matchLabels(labelValues, prefix []string) bool {
+	if len(prefix) > len(labelValues) {
+		return false
+	}
+	for idx, val := range prefix {
+		if labelValues[idx] != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortSeries(items []series) {
+	sort.Slice(items, func(i, j int) bool {
+		return strings.Join(items[i].labelValues, "\xff") < strings.Join(items[j].labelValues, "\xff")
+	})
+}
+
+// Counter 只增不减的计数,按照标签组合分别计数
+type Counter struct {
+	valueVec
+}
+
+func (s *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	ret := &Counter{valueVec: valueVec{desc: desc{metricName: name, help: help, kind: "counter", labelNames: labelNames}, seriesMap: map[string]*series{}}}
+	s.register(ret, labelNames)
+	return ret
+}
+
+func (s *Counter) Inc(labelValues ...string) {
+	s.add(1, labelValues)
+}
+
+// Add delta为负数时忽略
+func (s *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+
+	s.add(delta, labelValues)
+}
+
+// Gauge 可增可减的取值
+type Gauge struct {
+	valueVec
+}
+
+func (s *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	ret := &Gauge{valueVec: valueVec{desc: desc{metricName: name, help: help, kind: "gauge", labelNames: labelNames}, seriesMap: map[string]*series{}}}
+	s.register(ret, labelNames)
+	return ret
+}
+
+func (s *Gauge) Set(val float64, labelValues ...string) {
+	key := s.labelKey(labelValues)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.seriesMap[key] = &series{labelValues: append([]string(nil), labelValues...), value: val}
+}
+
+func (s *Gauge) Add(delta float64, labelValues ...string) {
+	s.add(delta, labelValues)
+}
+
+func (s *Gauge) Inc(labelValues ...string) {
+	s.add(1, labelValues)
+}
+
+func (s *Gauge) Dec(labelValues ...string) {
+	s.add(-1, labelValues)
+}
+
+// Delete 删除标签组合,对象销毁后不再输出其取值
+func (s *Gauge) Delete(labelValues ...string) {
+	key := s.labelKey(labelValues)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.seriesMap, key)
+}
+
+// GaugeFunc 输出时通过collect获取各标签组合的取值
+type GaugeFunc struct {
+	desc
+	collect func(emit func(val float64, labelValues ...string))
+}
+
+// NewGaugeFunc 适用于从已有状态计算的取值,collect在输出时调用,不能阻塞
+func (s *Registry) NewGaugeFunc(name, help string, labelNames []string, collect func(emit func(val float64, labelValues ...string))) *GaugeFunc {
+	ret := &GaugeFunc{desc: desc{metricName: name, help: help, kind: "gauge", labelNames: labelNames}, collect: collect}
+	s.register(ret, labelNames)
+	return ret
+}
+
+func (s *GaugeFunc) write(writer *bufio.Writer) {
+	items := []series{}
+	s.collect(func(val float64, labelValues ...string) {
+		s.labelKey(labelValues)
+		items = append(items, series{labelValues: labelValues, value: val})
+	})
+
+	sortSeries(items)
+	s.writeHead(writer)
+	for _, val := range items {
+		fmt.Fprintf(writer, "%s%s %s\n", s.metricName, s.formatLabels(val.labelValues, "", ""), formatValue(val.value))
+	}
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// Histogram 按照分组上限统计观测值的分布,分组计数在输出时累加
+type Histogram struct {
+	desc
+	buckets []float64
+
+	lock      sync.Mutex
+	seriesMap map[string]*histogramSeries
+}
+
+// NewHistogram buckets为空时使用DefBuckets,需要递增
+func (s *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("illegal histogram buckets %v, metric:%s", buckets, name))
+	}
+
+	ret := &Histogram{
+		desc:      desc{metricName: name, help: help, kind: "histogram", labelNames: labelNames},
+		buckets:   append([]float64(nil), buckets...),
+		seriesMap: map[string]*histogramSeries{},
+	}
+	s.register(ret, labelNames)
+	return ret
+}
+
+func (s *Histogram) Observe(val float64, labelValues ...string) {
+	key := s.labelKey(labelValues)
+	idx := sort.SearchFloat64s(s.buckets, val)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	seriesPtr, ok := s.seriesMap[key]
+	if !ok {
+		seriesPtr = &histogramSeries{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(s.buckets))}
+		s.seriesMap[key] = seriesPtr
+	}
+	if idx < len(s.buckets) {
+		seriesPtr.counts[idx]++
+	}
+	seriesPtr.count++
+	seriesPtr.sum += val
+}
+
+// DeleteMatch 删除前若干个标签取值与labelValues相同的全部标签组合
+func (s *Histogram) DeleteMatch(labelValues ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, val := range s.seriesMap {
+		if matchLabels(val.labelValues, labelValues) {
+			delete(s.seriesMap, key)
+		}
+	}
+}
+
+func (s *Histogram) write(writer *bufio.Writer) {
+	s.lock.Lock()
+	items := make([]histogramSeries, 0, len(s.seriesMap))
+	for _, val := range s.seriesMap {
+		item := *val
+		item.counts = append([]uint64(nil), val.counts...)
+		items = append(items, item)
+	}
+	s.lock.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return strings.Join(items[i].labelValues, "\xff") < strings.Join(items[j].labelValues, "\xff")
+	})
+	s.writeHead(writer)
+	for _, val := range items {
+		cumulative := uint64(0)
+		for idx, upper := range s.buckets {
+			cumulative += val.counts[idx]
+			fmt.Fprintf(writer, "%s_bucket%s %d\n", s.metricName, s.formatLabels(val.labelValues, "le", formatValue(upper)), cumulative)
+		}
+		fmt.Fprintf(writer, "%s_bucket%s %d\n", s.metricName, s.formatLabels(val.labelValues, "le", "+Inf"), val.count)
+		fmt.Fprintf(writer, "%s_sum%s %s\n", s.metricName, s.formatLabels(val.labelValues, "", ""), formatValue(val.sum))
+		fmt.Fprintf(writer, "%s_count%s %d\n", s.metricName, s.formatLabels(val.labelValues, "", ""), val.count)
+	}
+}
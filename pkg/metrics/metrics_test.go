@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("modbus_requests_total", "Requests sent.", "slave", "function")
+	connected := registry.NewGauge("modbus_connected", "Connected slaves.")
+	latency := registry.NewHistogram("modbus_latency_seconds", "Request latency.", []float64{0.1, 1}, "slave")
+	registry.NewGaugeFunc("modbus_slave_up", "Slave status.", []string{"slave"}, func(emit func(float64, ...string)) {
+		emit(1, "mb002")
+		emit(0, "mb001")
+	})
+
+	requests.Inc("mb001", "0x03")
+	requests.Add(2, "mb001", "0x03")
+	requests.Add(-1, "mb001", "0x03")
+	requests.Inc("mb\"1\n", "0x01")
+	connected.Inc()
+	connected.Inc()
+	connected.Dec()
+	latency.Observe(0.05, "mb001")
+	latency.Observe(0.1, "mb001")
+	latency.Observe(0.5, "mb001")
+	latency.Observe(3, "mb001")
+
+	buffer := bytes.NewBuffer(nil)
+	if err := registry.WriteText(buffer); err != nil {
+		t.Fatalf("WriteText failed, error:%s", err.Error())
+	}
+
+	expect := `# HELP modbus_connected Connected slaves.
+# TYPE modbus_connected gauge
+modbus_connected 1
+# HELP modbus_latency_seconds Request latency.
+# TYPE modbus_latency_seconds histogram
+modbus_latency_seconds_bucket{slave="mb001",le="0.1"} 2
+modbus_latency_seconds_bucket{slave="mb001",le="1"} 3
+modbus_latency_seconds_bucket{slave="mb001",le="+Inf"} 4
+modbus_latency_seconds_sum{slave="mb001"} 3.65
+modbus_latency_seconds_count{slave="mb001"} 4
+# HELP modbus_requests_total Requests sent.
+# TYPE modbus_requests_total counter
+modbus_requests_total{slave="mb\"1\n",function="0x01"} 1
+modbus_requests_total{slave="mb001",function="0x03"} 3
+# HELP modbus_slave_up Slave status.
+# TYPE modbus_slave_up gauge
+modbus_slave_up{slave="mb001"} 0
+modbus_slave_up{slave="mb002"} 1
+`
+	if buffer.String() != expect {
+		t.Errorf("WriteText failed, text:\n%s", buffer.String())
+	}
+
+	connected.Delete()
+	requests.DeleteMatch("mb001")
+	latency.DeleteMatch("mb001")
+	res := httptest.NewRecorder()
+	registry.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	if res.Header().Get("Content-Type") != ContentType || strings.Contains(res.Body.String(), "modbus_connected 1") {
+		t.Errorf("ServeHTTP failed, body:\n%s", res.Body.String())
+	}
+	if strings.Contains(res.Body.String(), `requests_total{slave="mb001"`) || strings.Contains(res.Body.String(), `latency_seconds_count{slave="mb001"}`) ||
+		!strings.Contains(res.Body.String(), `requests_total{slave="mb\"1\n"`) {
+		t.Errorf("DeleteMatch failed, body:\n%s", res.Body.String())
+	}
+}
+
+func TestRegistryPanic(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("modbus_requests_total", "Requests sent.", "slave")
+
+	items := []func(){
+		func() { registry.NewCounter("modbus_requests_total", "Duplicate.") },
+		func() { registry.NewGauge("modbus-connected", "Illegal name.") },
+		func() { registry.NewGauge("modbus_connected", "Illegal label.", "le") },
+		func() { registry.NewHistogram("modbus_latency_seconds", "Illegal buckets.", []float64{1, 0.1}) },
+	}
+	for idx, val := range items {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("register should panic, idx:%d", idx)
+				}
+			}()
+			val()
+		}()
+	}
+}
@@ -89,8 +89,10 @@ func (s *MBReadExceptionStatusReq) CalcPayloadLen() uint16 {
 	return 0
 }
 
-func NewReadExceptionStatusRsp() *MBReadExceptionStatusRsp {
-	return &MBReadExceptionStatusRsp{}
+func NewReadExceptionStatusRsp(status byte) *MBReadExceptionStatusRsp {
+	return &MBReadExceptionStatusRsp{
+		statusVal: status,
+	}
 }
 
 func EmptyReadExceptionStatusRsp(exceptionCode byte) *MBReadExceptionStatusRsp {
@@ -300,6 +302,14 @@ func (s *MBDiagnosticsReq) CalcPayloadLen() uint16 {
 	return 4
 }
 
+func (s *MBDiagnosticsReq) SubFunctionCode() uint16 {
+	return s.subFuncCode
+}
+
+func (s *MBDiagnosticsReq) Data() []byte {
+	return s.dataVal
+}
+
 func NewDiagnosticsRsp(subFuncCode uint16, data []byte) *MBDiagnosticsRsp {
 	return &MBDiagnosticsRsp{
 		subFuncCode: subFuncCode,
@@ -494,8 +504,11 @@ func (s *MBGetCommEventCounterReq) CalcPayloadLen() uint16 {
 	return 0
 }
 
-func NewGetCommEventCounterRsp() *MBGetCommEventCounterRsp {
-	return &MBGetCommEventCounterRsp{}
+func NewGetCommEventCounterRsp(commStatus, eventCount uint16) *MBGetCommEventCounterRsp {
+	return &MBGetCommEventCounterRsp{
+		commStatus: commStatus,
+		eventCount: eventCount,
+	}
 }
 
 func EmptyGetCommEventCounterRsp(exceptionCode byte) *MBGetCommEventCounterRsp {
@@ -678,8 +691,13 @@ func (s *MBGetCommEventLogReq) CalcPayloadLen() uint16 {
 	return 0
 }
 
-func NewGetCommEventLogRsp() *MBGetCommEventLogRsp {
-	return &MBGetCommEventLogRsp{}
+func NewGetCommEventLogRsp(commStatus, eventCount, messageCount uint16, events []byte) *MBGetCommEventLogRsp {
+	return &MBGetCommEventLogRsp{
+		commStatus:   commStatus,
+		eventCount:   eventCount,
+		messageCount: messageCount,
+		commonEvents: events,
+	}
 }
 
 func EmptyGetCommEventLogRsp(exceptionCode byte) *MBGetCommEventLogRsp {
@@ -1119,7 +1137,7 @@ func (s *ReadRequestItem) encode(writer io.Writer) (err byte) {
 		}
 	}()
 
-	buffVal := make([]byte, 7)
+	buffVal := make([]byte, 0)
 	buffVal = append(buffVal, s.referenceType)
 	buffVal = binary.BigEndian.AppendUint16(buffVal, s.fileNumber)
 	buffVal = binary.BigEndian.AppendUint16(buffVal, s.recordNumber)
@@ -1333,7 +1351,7 @@ func (s *ReadResponseItem) encode(writer io.Writer) (err byte) {
 	}()
 
 	buffSize := s.calcDataSize() + 1
-	buffVal := make([]byte, buffSize)
+	buffVal := make([]byte, 0)
 	buffVal = append(buffVal, s.calcDataSize())
 	buffVal = append(buffVal, s.referenceType)
 	buffVal = append(buffVal, s.recordData...)
@@ -1368,7 +1386,7 @@ func (s *ReadResponseItem) decode(reader io.Reader) (err byte) {
 
 	dataVal = make([]byte, dataSize-1)
 	rSize, rErr = reader.Read(dataVal)
-	if rErr != nil || rSize != 2 {
+	if rErr != nil || rSize != int(dataSize-1) {
 		err = IllegalAddress
 		return
 	}
@@ -1447,7 +1465,7 @@ func (s *MBReadFileRecordRsp) Decode(reader io.Reader) (err byte) {
 		}
 
 		s.items = append(s.items, item)
-		offset += item.calcDataSize()
+		offset += item.calcDataSize() + 1
 	}
 
 	return
@@ -1463,7 +1481,7 @@ func (s *MBReadFileRecordRsp) EncodePayload(writer io.Writer) (err byte) {
 			err = IllegalData
 		}
 	}()
-	wSize, wErr := writer.Write([]byte{s.FuncCode(), s.calcDataSize()})
+	wSize, wErr := writer.Write([]byte{s.calcDataSize()})
 	if wErr != nil || wSize != 1 {
 		err = IllegalAddress
 		return
@@ -1506,7 +1524,7 @@ func (s *MBReadFileRecordRsp) DecodePayload(reader io.Reader) (err byte) {
 		}
 
 		s.items = append(s.items, item)
-		offset += item.calcDataSize()
+		offset += item.calcDataSize() + 1
 	}
 
 	return
@@ -1530,7 +1548,7 @@ func (s *MBReadFileRecordRsp) Items() []*ReadResponseItem {
 func (s *MBReadFileRecordRsp) calcDataSize() byte {
 	dataSize := byte(0)
 	for _, val := range s.items {
-		dataSize += val.calcDataSize()
+		dataSize += val.calcDataSize() + 1
 	}
 
 	return dataSize
@@ -1585,7 +1603,7 @@ func (s *WriteItem) encode(writer io.Writer) (err byte) {
 		}
 	}()
 
-	buffVal := make([]byte, 7)
+	buffVal := make([]byte, 0)
 	recordLength := uint16(len(s.recordData) / 2)
 	buffVal = append(buffVal, s.referenceType)
 	buffVal = binary.BigEndian.AppendUint16(buffVal, s.fileNumber)
@@ -1674,7 +1692,7 @@ func (s *MBWriteFileRecordReq) Decode(reader io.Reader) (err byte) {
 	}()
 	dataVal := make([]byte, 2)
 	rSize, rErr := reader.Read(dataVal)
-	if rErr != nil || rSize != 1 {
+	if rErr != nil || rSize != 2 {
 		err = IllegalAddress
 		return
 	}
@@ -1878,7 +1896,7 @@ func (s *MBWriteFileRecordRsp) Decode(reader io.Reader) (err byte) {
 }
 
 func (s *MBWriteFileRecordRsp) CalcLen() uint16 {
-	return 0
+	return uint16(s.calcDataSize()) + 2
 }
 
 func (s *MBWriteFileRecordRsp) EncodePayload(writer io.Writer) (err byte) {
@@ -1939,7 +1957,7 @@ func (s *MBWriteFileRecordRsp) DecodePayload(reader io.Reader) (err byte) {
 }
 
 func (s *MBWriteFileRecordRsp) CalcPayloadLen() uint16 {
-	return 0
+	return uint16(s.calcDataSize()) + 1
 }
 
 func (s *MBWriteFileRecordRsp) AppendItem(fileNumber, recordNumber uint16, recordData []byte) {
@@ -2095,8 +2113,12 @@ func (s *MBMaskWriteRegisterReq) OrMask() []byte {
 	return s.orMask
 }
 
-func NewMaskWriteRegisterRsp() *MBMaskWriteRegisterRsp {
-	return &MBMaskWriteRegisterRsp{}
+func NewMaskWriteRegisterRsp(address uint16, andBytes []byte, orBytes []byte) *MBMaskWriteRegisterRsp {
+	return &MBMaskWriteRegisterRsp{
+		address: address,
+		andMask: andBytes,
+		orMask:  orBytes,
+	}
 }
 
 func EmptyMaskWriteRegisterRsp(exceptionCode byte) *MBMaskWriteRegisterRsp {
@@ -2398,8 +2420,10 @@ func (s *MBReadWriteMultipleRegistersReq) WriteData() []byte {
 	return s.writeData
 }
 
-func NewReadWriteMultipleRegistersRsp() *MBReadWriteMultipleRegistersRsp {
-	return &MBReadWriteMultipleRegistersRsp{}
+func NewReadWriteMultipleRegistersRsp(data []byte) *MBReadWriteMultipleRegistersRsp {
+	return &MBReadWriteMultipleRegistersRsp{
+		dataVal: data,
+	}
 }
 
 func EmptyReadWriteMultipleRegistersRsp(exceptionCode byte) *MBReadWriteMultipleRegistersRsp {
@@ -2645,8 +2669,11 @@ func (s *MBReadFIFOQueueReq) Address() uint16 {
 	return s.address
 }
 
-func NewReadFIFOQueueRsp() *MBReadFIFOQueueRsp {
-	return &MBReadFIFOQueueRsp{}
+func NewReadFIFOQueueRsp(count uint16, data []byte) *MBReadFIFOQueueRsp {
+	return &MBReadFIFOQueueRsp{
+		dataCount: count,
+		dataVal:   data,
+	}
 }
 
 func EmptyReadFIFOQueueRsp(exceptionCode byte) *MBReadFIFOQueueRsp {
@@ -2809,7 +2836,7 @@ type MBExceptionRsp struct {
 }
 
 func (s *MBExceptionRsp) FuncCode() byte {
-	return s.funcCode
+	return s.funcCode | 0x80
 }
 
 func (s *MBExceptionRsp) Encode(writer io.Writer) (err byte) {
@@ -2819,8 +2846,8 @@ func (s *MBExceptionRsp) Encode(writer io.Writer) (err byte) {
 		}
 	}()
 
-	buffVal := make([]byte, 2)
-	buffVal = append(buffVal, s.funcCode)
+	buffVal := make([]byte, 0)
+	buffVal = append(buffVal, s.FuncCode())
 	buffVal = append(buffVal, s.exceptionCode)
 	wSize, wErr := writer.Write(buffVal)
 	if wErr != nil || wSize != 2 {
@@ -2843,11 +2870,15 @@ func (s *MBExceptionRsp) Decode(reader io.Reader) (err byte) {
 		err = IllegalAddress
 		return
 	}
-	s.funcCode = dataVal[0]
+	s.funcCode = dataVal[0] & 0x7F
 	s.exceptionCode = dataVal[1]
 	return
 }
 
+func (s *MBExceptionRsp) CalcLen() uint16 {
+	return 2
+}
+
 func (s *MBExceptionRsp) EncodePayload(writer io.Writer) (err byte) {
 	defer func() {
 		if errInfo := recover(); errInfo != nil {
@@ -2855,7 +2886,7 @@ func (s *MBExceptionRsp) EncodePayload(writer io.Writer) (err byte) {
 		}
 	}()
 
-	buffVal := make([]byte, 1)
+	buffVal := make([]byte, 0)
 	buffVal = append(buffVal, s.exceptionCode)
 	wSize, wErr := writer.Write(buffVal)
 	if wErr != nil || wSize != 1 {
@@ -2885,3 +2916,7 @@ func (s *MBExceptionRsp) DecodePayload(reader io.Reader) (err byte) {
 func (s *MBExceptionRsp) ExceptionCode() byte {
 	return s.exceptionCode
 }
+
+func (s *MBExceptionRsp) CalcPayloadLen() uint16 {
+	return 1
+}
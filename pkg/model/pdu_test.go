@@ -1 +1,148 @@
 package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Exception
+// funcCode: ReadHoldingRegisters
+// exceptionCode: IllegalAddress
+func TestDecodeMB012(t *testing.T) {
+	rspPtr := NewExceptionRsp(ReadHoldingRegisters, IllegalAddress)
+	byteBuff := bytes.NewBuffer(nil)
+	errCode := EncodeMBTcpProtocol(NewTcpHeader(0x1040, rspPtr.CalcLen(), 1), rspPtr, byteBuff)
+	if errCode != SuccessCode {
+		t.Errorf("EncodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	strVal := "104000000003018302"
+	if strings.ToUpper(hex.EncodeToString(byteBuff.Bytes())) != strVal {
+		t.Errorf("encode Exception response failed, %s", hex.EncodeToString(byteBuff.Bytes()))
+		return
+	}
+
+	_, protocol, errCode := DecodeMBTcpProtocol(byteBuff, ResponseAction)
+	if errCode != SuccessCode {
+		t.Errorf("DecodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	holdingPtr, holdingOK := protocol.(*MBReadHoldingRegistersRsp)
+	if !holdingOK {
+		t.Errorf("decode Exception response failed")
+		return
+	}
+	if holdingPtr.ExceptionCode() != IllegalAddress {
+		t.Errorf("decode Exception response exception code failed")
+		return
+	}
+}
+
+// ReadFileRecord
+// fileNumber: 4, recordNumber: 1, recordLength: 2
+// fileNumber: 3, recordNumber: 9, recordLength: 2
+func TestDecodeMB013(t *testing.T) {
+	strVal := "00010000001001140E0600040001000206000300090002"
+	byteVal, byteErr := hex.DecodeString(strVal)
+	if byteErr != nil {
+		t.Errorf("hex.DecodeString, error:%v", byteErr.Error())
+		return
+	}
+
+	_, protocol, errCode := DecodeMBTcpProtocol(bytes.NewBuffer(byteVal), RequestAction)
+	if errCode != SuccessCode {
+		t.Errorf("DecodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	reqPtr, reqOK := protocol.(*MBReadFileRecordReq)
+	if !reqOK {
+		t.Errorf("decode ReadFileRecord request failed")
+		return
+	}
+	if len(reqPtr.Items()) != 2 {
+		t.Errorf("decode ReadFileRecord request items failed")
+		return
+	}
+	if reqPtr.Items()[1].FileNumber() != 3 || reqPtr.Items()[1].RecordNumber() != 9 || reqPtr.Items()[1].RecordLength() != 2 {
+		t.Errorf("decode ReadFileRecord request item failed")
+		return
+	}
+
+	rspPtr := NewReadFileRecordRsp()
+	rspPtr.AppendItem([]byte{0x0D, 0xFE, 0x00, 0x20})
+	rspPtr.AppendItem([]byte{0x33, 0xCD, 0x00, 0x40})
+	byteBuff := bytes.NewBuffer(nil)
+	errCode = EncodeMBTcpProtocol(NewTcpHeader(0x0001, rspPtr.CalcLen(), 1), rspPtr, byteBuff)
+	if errCode != SuccessCode {
+		t.Errorf("EncodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	strVal = "00010000000F01140C05060DFE0020050633CD0040"
+	if strings.ToUpper(hex.EncodeToString(byteBuff.Bytes())) != strVal {
+		t.Errorf("encode ReadFileRecord response failed, %s", hex.EncodeToString(byteBuff.Bytes()))
+		return
+	}
+
+	_, protocol, errCode = DecodeMBTcpProtocol(byteBuff, ResponseAction)
+	if errCode != SuccessCode {
+		t.Errorf("DecodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	filePtr, fileOK := protocol.(*MBReadFileRecordRsp)
+	if !fileOK {
+		t.Errorf("decode ReadFileRecord response failed")
+		return
+	}
+	if len(filePtr.Items()) != 2 || !bytes.Equal(filePtr.Items()[1].Data(), []byte{0x33, 0xCD, 0x00, 0x40}) {
+		t.Errorf("decode ReadFileRecord response items failed")
+		return
+	}
+}
+
+// WriteFileRecord
+// fileNumber: 4, recordNumber: 7, recordData: 06AF04BE100D
+func TestDecodeMB014(t *testing.T) {
+	strVal := "00010000001001150D0600040007000306AF04BE100D"
+	byteVal, byteErr := hex.DecodeString(strVal)
+	if byteErr != nil {
+		t.Errorf("hex.DecodeString, error:%v", byteErr.Error())
+		return
+	}
+
+	_, protocol, errCode := DecodeMBTcpProtocol(bytes.NewBuffer(byteVal), RequestAction)
+	if errCode != SuccessCode {
+		t.Errorf("DecodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+
+	reqPtr, reqOK := protocol.(*MBWriteFileRecordReq)
+	if !reqOK {
+		t.Errorf("decode WriteFileRecord request failed")
+		return
+	}
+	if len(reqPtr.Items()) != 1 {
+		t.Errorf("decode WriteFileRecord request items failed")
+		return
+	}
+
+	itemPtr := reqPtr.Items()[0]
+	rspPtr := NewWriteFileRecordRsp()
+	rspPtr.AppendItem(itemPtr.FileNumber(), itemPtr.RecordNumber(), itemPtr.RecordData())
+	byteBuff := bytes.NewBuffer(nil)
+	errCode = EncodeMBTcpProtocol(NewTcpHeader(0x0001, rspPtr.CalcLen(), 1), rspPtr, byteBuff)
+	if errCode != SuccessCode {
+		t.Errorf("EncodeMBTcpProtocol failed, error code :%v", errCode)
+		return
+	}
+	if strings.ToUpper(hex.EncodeToString(byteBuff.Bytes())) != strVal {
+		t.Errorf("encode WriteFileRecord response failed, %s", hex.EncodeToString(byteBuff.Bytes()))
+		return
+	}
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	asciiStartChar = ":"
+	asciiEndChars  = "\r\n"
+)
+
+// ErrCRCCheck RTU帧CRC校验失败,ErrLRCCheck ASCII帧LRC校验失败
+var (
+	ErrCRCCheck = errors.New("check crc failed")
+	ErrLRCCheck = errors.New("check lrc failed")
+)
+
+// CRCCheck Modbus RTU CRC16,低字节在前
+func CRCCheck(byteVal []byte) []byte {
+	var crc uint16 = 0xFFFF
+
+	for _, b := range byteVal {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if (crc & 0x0001) != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc = crc >> 1
+			}
+		}
+	}
+
+	return []byte{byte(crc), byte(crc >> 8)}
+}
+
+// LRCCheck Modbus ASCII LRC,对地址到数据的所有字节求和后取补码
+func LRCCheck(byteVal []byte) byte {
+	var lrc byte = 0
+
+	for _, b := range byteVal {
+		lrc += b
+	}
+
+	lrc = 0xFF - lrc + 1
+
+	return lrc
+}
+
+func EncodeToRTUStream(byteVal []byte) []byte {
+	crcVal := CRCCheck(byteVal)
+	byteVal = append(byteVal, crcVal...)
+	return byteVal
+}
+
+func DecodeFromRTUStream(dataVal []byte) ([]byte, error) {
+	if len(dataVal) < 4 {
+		err := fmt.Errorf("illegal rtu frame length:%d", len(dataVal))
+		return nil, err
+	}
+
+	rawData := dataVal[0 : len(dataVal)-2]
+	crcVal := CRCCheck(rawData)
+	dataCRC := dataVal[len(dataVal)-2:]
+	if !bytes.Equal(crcVal, dataCRC) {
+		return nil, ErrCRCCheck
+	}
+
+	return rawData, nil
+}
+
+func EncodeToASCIIStream(byteVal []byte) []byte {
+	lrcVal := LRCCheck(byteVal)
+	byteVal = append(byteVal, lrcVal)
+	strVal := asciiStartChar + strings.ToUpper(hex.EncodeToString(byteVal)) + asciiEndChars
+	return []byte(strVal)
+}
+
+func DecodeFromASCIIStream(dataVal []byte) ([]byte, error) {
+	strVal := string(dataVal)
+	if !strings.HasPrefix(strVal, asciiStartChar) || !strings.HasSuffix(strVal, asciiEndChars) {
+		err := fmt.Errorf("illegal ascii frame")
+		return nil, err
+	}
+
+	strVal = strVal[len(asciiStartChar) : len(strVal)-len(asciiEndChars)]
+	byteVal, byteErr := hex.DecodeString(strVal)
+	if byteErr != nil {
+		return nil, byteErr
+	}
+	if len(byteVal) < 3 {
+		err := fmt.Errorf("illegal ascii frame length:%d", len(byteVal))
+		return nil, err
+	}
+
+	rawData := byteVal[:len(byteVal)-1]
+	lrcVal := LRCCheck(rawData)
+	if lrcVal != byteVal[len(byteVal)-1] {
+		return nil, ErrLRCCheck
+	}
+
+	return rawData, nil
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// ReadHoldingRegisters over RTU
+// address: 1, register: 0, count: 10
+func TestRTUStream(t *testing.T) {
+	byteVal := EncodeToRTUStream([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A})
+	if hex.EncodeToString(byteVal) != "01030000000ac5cd" {
+		t.Errorf("EncodeToRTUStream failed, %s", hex.EncodeToString(byteVal))
+		return
+	}
+
+	dataVal, dataErr := DecodeFromRTUStream(byteVal)
+	if dataErr != nil || !bytes.Equal(dataVal, []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A}) {
+		t.Errorf("DecodeFromRTUStream failed, error:%v", dataErr)
+		return
+	}
+
+	byteVal[2] = 0x01
+	_, dataErr = DecodeFromRTUStream(byteVal)
+	if !errors.Is(dataErr, ErrCRCCheck) {
+		t.Errorf("DecodeFromRTUStream with bad crc should fail")
+		return
+	}
+
+	_, dataErr = DecodeFromRTUStream([]byte{0x01, 0x03})
+	if dataErr == nil {
+		t.Errorf("DecodeFromRTUStream with short frame should fail")
+		return
+	}
+}
+
+// ReadHoldingRegisters over ASCII
+// address: 17, register: 0x006B, count: 3
+func TestASCIIStream(t *testing.T) {
+	byteVal := EncodeToASCIIStream([]byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03})
+	if string(byteVal) != ":1103006B00037E\r\n" {
+		t.Errorf("EncodeToASCIIStream failed, %q", string(byteVal))
+		return
+	}
+
+	dataVal, dataErr := DecodeFromASCIIStream(byteVal)
+	if dataErr != nil || !bytes.Equal(dataVal, []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03}) {
+		t.Errorf("DecodeFromASCIIStream failed, error:%v", dataErr)
+		return
+	}
+
+	_, dataErr = DecodeFromASCIIStream([]byte(":1103006B00037F\r\n"))
+	if !errors.Is(dataErr, ErrLRCCheck) {
+		t.Errorf("DecodeFromASCIIStream with bad lrc should fail")
+		return
+	}
+
+	_, dataErr = DecodeFromASCIIStream([]byte("1103006B00037E\r\n"))
+	if dataErr == nil {
+		t.Errorf("DecodeFromASCIIStream without start char should fail")
+		return
+	}
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// 各类ADU的最大长度
+const (
+	maxTCPFrameLength   = 260
+	maxRTUFrameLength   = 256
+	maxASCIIFrameLength = 513
+)
+
+const rtuCRCLength = 2
+
+// TCPFrameLen 根据MBAP头中的长度字段计算首帧长度,数据不足时返回0
+func TCPFrameLen(dataVal []byte) (int, error) {
+	if len(dataVal) < aduTcpHeadLength {
+		return 0, nil
+	}
+
+	protocol := binary.BigEndian.Uint16(dataVal[2:4])
+	if protocol != ModbusProtocol {
+		return 0, fmt.Errorf("illegal mbap protocol:%d", protocol)
+	}
+
+	// 长度字段包含unitID
+	dataLen := int(binary.BigEndian.Uint16(dataVal[4:6]))
+	frameLen := aduTcpHeadLength - 1 + dataLen
+	if dataLen < 2 || frameLen > maxTCPFrameLength {
+		return 0, fmt.Errorf("illegal mbap length:%d", dataLen)
+	}
+	if len(dataVal) < frameLen {
+		return 0, nil
+	}
+
+	return frameLen, nil
+}
+
+// RTUResponseFrameLen 根据功能码规则计算首个RTU应答帧长度,数据不足时返回0
+func RTUResponseFrameLen(dataVal []byte) (int, error) {
+	if len(dataVal) < aduSerialHeadLength+1 {
+		return 0, nil
+	}
+
+	funcCode := dataVal[aduSerialHeadLength]
+	pduLen := 0
+	if funcCode&0x80 != 0 {
+		// 功能码 + 异常码
+		pduLen = 2
+	} else {
+		switch funcCode {
+		case ReadCoils, ReadDiscreteInputs, ReadHoldingRegisters, ReadInputRegisters,
+			GetCommEventLog, ReportSlaveID, ReadFileRecord, WriteFileRecord, ReadWriteMultipleRegisters:
+			// 功能码 + 字节数 + 数据
+			if len(dataVal) < aduSerialHeadLength+2 {
+				return 0, nil
+			}
+			pduLen = 2 + int(dataVal[aduSerialHeadLength+1])
+		case WriteSingleCoil, WriteSingleRegister, Diagnostics, GetCommEventCounter,
+			WriteMultipleCoils, WriteMultipleRegisters:
+			pduLen = 5
+		case ReadExceptionStatus:
+			pduLen = 2
+		case MaskWriteRegister:
+			pduLen = 7
+		case ReadFIFOQueue:
+			// 功能码 + 两字节的字节数 + 数据
+			if len(dataVal) < aduSerialHeadLength+3 {
+				return 0, nil
+			}
+			pduLen = 3 + int(binary.BigEndian.Uint16(dataVal[aduSerialHeadLength+1:aduSerialHeadLength+3]))
+		default:
+			return 0, fmt.Errorf("illegal rtu function code:%d", funcCode)
+		}
+	}
+
+	return rtuFrameLen(dataVal, pduLen)
+}
+
+// RTURequestFrameLen 根据功能码规则计算首个RTU请求帧长度,数据不足时返回0
+func RTURequestFrameLen(dataVal []byte) (int, error) {
+	if len(dataVal) < aduSerialHeadLength+1 {
+		return 0, nil
+	}
+
+	// byteCountLen 字节数字段之前的PDU长度,字节数字段之后为数据
+	byteCountLen := 0
+	pduLen := 0
+	funcCode := dataVal[aduSerialHeadLength]
+	switch funcCode {
+	case ReadCoils, ReadDiscreteInputs, ReadHoldingRegisters, ReadInputRegisters,
+		WriteSingleCoil, WriteSingleRegister, Diagnostics:
+		pduLen = 5
+	case ReadExceptionStatus, GetCommEventCounter, GetCommEventLog, ReportSlaveID:
+		pduLen = 1
+	case ReadFIFOQueue:
+		pduLen = 3
+	case MaskWriteRegister:
+		pduLen = 7
+	case ReadFileRecord, WriteFileRecord:
+		// 功能码 + 字节数 + 数据
+		byteCountLen = 1
+	case WriteMultipleCoils, WriteMultipleRegisters:
+		// 功能码 + 地址 + 数量 + 字节数 + 数据
+		byteCountLen = 5
+	case ReadWriteMultipleRegisters:
+		// 功能码 + 读地址 + 读数量 + 写地址 + 写数量 + 字节数 + 数据
+		byteCountLen = 9
+	default:
+		return 0, fmt.Errorf("illegal rtu function code:%d", funcCode)
+	}
+	if byteCountLen > 0 {
+		if len(dataVal) < aduSerialHeadLength+byteCountLen+1 {
+			return 0, nil
+		}
+		pduLen = byteCountLen + 1 + int(dataVal[aduSerialHeadLength+byteCountLen])
+	}
+
+	return rtuFrameLen(dataVal, pduLen)
+}
+
+func rtuFrameLen(dataVal []byte, pduLen int) (int, error) {
+	frameLen := aduSerialHeadLength + pduLen + rtuCRCLength
+	if frameLen > maxRTUFrameLength {
+		return 0, fmt.Errorf("illegal rtu frame length:%d", frameLen)
+	}
+	if len(dataVal) < frameLen {
+		return 0, nil
+	}
+
+	return frameLen, nil
+}
+
+// ASCIIFrameLen 以CRLF为结束符计算首个ASCII帧长度,数据不足时返回0
+func ASCIIFrameLen(dataVal []byte) (int, error) {
+	if len(dataVal) == 0 {
+		return 0, nil
+	}
+	if dataVal[0] != asciiStartChar[0] {
+		return 0, fmt.Errorf("illegal ascii frame start:0x%02X", dataVal[0])
+	}
+
+	endIdx := bytes.Index(dataVal, []byte(asciiEndChars))
+	if endIdx < 0 {
+		if len(dataVal) >= maxASCIIFrameLength {
+			return 0, fmt.Errorf("illegal ascii frame length:%d", len(dataVal))
+		}
+		return 0, nil
+	}
+
+	return endIdx + len(asciiEndChars), nil
+}
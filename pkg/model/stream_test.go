@@ -0,0 +1,123 @@
+package model
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// two ReadHoldingRegisters responses back to back, transaction 1 and 2
+func TestTCPFrameLen(t *testing.T) {
+	byteVal, _ := hex.DecodeString("000100000007010304000A000B" + "000200000003018302")
+
+	frameLen, frameErr := TCPFrameLen(byteVal)
+	if frameErr != nil || frameLen != 13 {
+		t.Errorf("TCPFrameLen failed, frameLen:%d, error:%v", frameLen, frameErr)
+		return
+	}
+
+	frameLen, frameErr = TCPFrameLen(byteVal[13:])
+	if frameErr != nil || frameLen != 9 {
+		t.Errorf("TCPFrameLen failed, frameLen:%d, error:%v", frameLen, frameErr)
+		return
+	}
+
+	frameLen, frameErr = TCPFrameLen(byteVal[:10])
+	if frameErr != nil || frameLen != 0 {
+		t.Errorf("TCPFrameLen with partial frame failed, frameLen:%d, error:%v", frameLen, frameErr)
+		return
+	}
+
+	byteVal[3] = 0x01
+	_, frameErr = TCPFrameLen(byteVal)
+	if frameErr == nil {
+		t.Errorf("TCPFrameLen with illegal protocol should fail")
+		return
+	}
+}
+
+func TestRTUResponseFrameLen(t *testing.T) {
+	testCases := []struct {
+		frame    string
+		frameLen int
+	}{
+		{"010304000A000BFFFF", 9},
+		{"01830200FFFF", 5},
+		{"010600010003FFFF", 8},
+		{"010700FFFF", 5},
+		{"011600040012002500FFFF", 10},
+		{"0118000600020001000AFFFF", 12},
+		{"010304000A", 0},
+		{"01", 0},
+	}
+
+	for _, val := range testCases {
+		byteVal, _ := hex.DecodeString(val.frame)
+		frameLen, frameErr := RTUResponseFrameLen(byteVal)
+		if frameErr != nil || frameLen != val.frameLen {
+			t.Errorf("RTUResponseFrameLen %s failed, frameLen:%d, error:%v", val.frame, frameLen, frameErr)
+			return
+		}
+	}
+
+	_, frameErr := RTUResponseFrameLen([]byte{0x01, 0x42})
+	if frameErr == nil {
+		t.Errorf("RTUResponseFrameLen with illegal function code should fail")
+		return
+	}
+}
+
+func TestRTURequestFrameLen(t *testing.T) {
+	testCases := []struct {
+		frame    string
+		frameLen int
+	}{
+		{"010300000002FFFF", 8},
+		{"0107FFFF", 4},
+		{"011800040000FFFF", 6},
+		{"0116000400F20025FFFF", 10},
+		{"011000010002040001000AFFFF", 13},
+		{"010F0013000A02CD01FFFF", 11},
+		{"011700030006000E00030600FF00FF00FFFFFF", 19},
+		{"01140706000400010002FFFF", 12},
+		{"01100001000204", 0},
+		{"0103000000", 0},
+		{"01", 0},
+	}
+
+	for _, val := range testCases {
+		byteVal, _ := hex.DecodeString(val.frame)
+		frameLen, frameErr := RTURequestFrameLen(byteVal)
+		if frameErr != nil || frameLen != val.frameLen {
+			t.Errorf("RTURequestFrameLen %s failed, frameLen:%d, error:%v", val.frame, frameLen, frameErr)
+			return
+		}
+	}
+
+	_, frameErr := RTURequestFrameLen([]byte{0x01, 0x42})
+	if frameErr == nil {
+		t.Errorf("RTURequestFrameLen with illegal function code should fail")
+		return
+	}
+}
+
+func TestASCIIFrameLen(t *testing.T) {
+	byteVal := []byte(":1103006B00037E\r\n:1103006B")
+
+	frameLen, frameErr := ASCIIFrameLen(byteVal)
+	if frameErr != nil || frameLen != 17 {
+		t.Errorf("ASCIIFrameLen failed, frameLen:%d, error:%v", frameLen, frameErr)
+		return
+	}
+
+	frameLen, frameErr = ASCIIFrameLen(byteVal[17:])
+	if frameErr != nil || frameLen != 0 {
+		t.Errorf("ASCIIFrameLen with partial frame failed, frameLen:%d, error:%v", frameLen, frameErr)
+		return
+	}
+
+	_, frameErr = ASCIIFrameLen([]byte("1103006B00037E\r\n"))
+	if frameErr == nil {
+		t.Errorf("ASCIIFrameLen without start char should fail")
+		return
+	}
+}
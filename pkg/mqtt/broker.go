@@ -0,0 +1,378 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// connectTimeout 建立连接后等待CONNECT报文的时间
+const connectTimeout = 10 * time.Second
+
+/*
+Broker 内嵌的MQTT 3.1.1 Broker,用于本地部署及测试
+支持QoS 0与QoS 1、保留消息及通配符订阅,不支持持久会话与遗嘱消息,不做认证
+*/
+type Broker struct {
+	lock       sync.Mutex
+	listener   net.Listener
+	sessionMap map[string]*brokerSession
+	retainMap  map[string]*Message
+	autoID     int
+	closed     bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{sessionMap: map[string]*brokerSession{}, retainMap: map[string]*Message{}}
+}
+
+// ListenAndServe 在addr上监听并阻塞处理连接,直到Close
+func (s *Broker) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+func (s *Broker) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = listener.Close()
+		return net.ErrClosed
+	}
+	s.listener = listener
+	s.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// Close 停止监听并断开全部连接
+func (s *Broker) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for _, val := range s.sessionMap {
+		_ = val.conn.Close()
+	}
+}
+
+type brokerSession struct {
+	clientID  string
+	conn      net.Conn
+	keepAlive time.Duration
+
+	lock      sync.Mutex
+	filterMap map[string]byte
+	packetID  uint16
+
+	writeLock sync.Mutex
+}
+
+func (s *brokerSession) write(data []byte) (err error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(defaultTimeout))
+	_, err = s.conn.Write(data)
+	return
+}
+
+// deliver 按照订阅时授予的QoS转发消息,消息与多个过滤器匹配时取最大的QoS
+func (s *brokerSession) deliver(msg *Message, retain bool) {
+	s.lock.Lock()
+	matched := false
+	qos := byte(0)
+	for filter, val := range s.filterMap {
+		if MatchTopic(filter, msg.Topic) {
+			matched = true
+			qos = max(qos, val)
+		}
+	}
+	if !matched {
+		s.lock.Unlock()
+		return
+	}
+
+	s.packetID++
+	if s.packetID == 0 {
+		s.packetID = 1
+	}
+	packetID := s.packetID
+	s.lock.Unlock()
+
+	data, err := encodePublish(&Message{Topic: msg.Topic, Payload: msg.Payload, QoS: min(qos, msg.QoS), Retain: retain}, packetID)
+	if err == nil {
+		err = s.write(data)
+	}
+	if err != nil {
+		_ = s.conn.Close()
+	}
+}
+
+func (s *Broker) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	session, returnCode, err := s.readConnect(conn, reader)
+	if err != nil {
+		return
+	}
+
+	connack, _ := encodePacket(connackPacket, 0, []byte{0, returnCode})
+	_, err = conn.Write(connack)
+	if err != nil || returnCode != 0 {
+		return
+	}
+
+	// 相同ClientID的新连接替换已有的连接
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	if preSession, ok := s.sessionMap[session.clientID]; ok {
+		_ = preSession.conn.Close()
+	}
+	s.sessionMap[session.clientID] = session
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		if s.sessionMap[session.clientID] == session {
+			delete(s.sessionMap, session.clientID)
+		}
+		s.lock.Unlock()
+	}()
+
+	for {
+		if session.keepAlive > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(session.keepAlive * 3 / 2))
+		} else {
+			_ = conn.SetReadDeadline(time.Time{})
+		}
+
+		pkt, pktErr := readPacket(reader)
+		if pktErr != nil {
+			return
+		}
+
+		switch pkt.kind {
+		case publishPacket:
+			err = s.handlePublish(session, pkt)
+		case subscribePacket:
+			err = s.handleSubscribe(session, pkt)
+		case unsubscribePacket:
+			err = s.handleUnsubscribe(session, pkt)
+		case pingreqPacket:
+			pingresp, _ := encodePacket(pingrespPacket, 0, nil)
+			err = session.write(pingresp)
+		case pubackPacket:
+		case disconnectPacket:
+			return
+		default:
+			err = fmt.Errorf("unexpect mqtt packet %d", pkt.kind)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readConnect returnCode非0时拒绝连接
+func (s *Broker) readConnect(conn net.Conn, reader *bufio.Reader) (ret *brokerSession, returnCode byte, err error) {
+	pkt, pktErr := readPacket(reader)
+	if pktErr != nil {
+		err = pktErr
+		return
+	}
+	if pkt.kind != connectPacket {
+		err = errMalformedPacket
+		return
+	}
+
+	protocolName, remain, nameErr := readString(pkt.body)
+	if nameErr != nil || protocolName != "MQTT" || len(remain) < 4 {
+		err = errMalformedPacket
+		return
+	}
+	level, flags := remain[0], remain[1]
+	keepAlive, remain, _ := readUint16(remain[2:])
+	clientID, remain, idErr := readString(remain)
+	if idErr != nil {
+		err = idErr
+		return
+	}
+
+	ret = &brokerSession{clientID: clientID, conn: conn, keepAlive: time.Duration(keepAlive) * time.Second, filterMap: map[string]byte{}}
+	if level != protocolLevel {
+		returnCode = 1
+		return
+	}
+	if clientID == "" {
+		if flags&0x02 == 0 {
+			returnCode = 2
+			return
+		}
+
+		s.lock.Lock()
+		s.autoID++
+		ret.clientID = fmt.Sprintf("auto-%d", s.autoID)
+		s.lock.Unlock()
+	}
+
+	// 遗嘱消息、用户名与密码只做格式校验
+	if flags&0x04 != 0 {
+		_, remain, err = readString(remain)
+		if err == nil {
+			_, remain, err = readString(remain)
+		}
+	}
+	if err == nil && flags&0x80 != 0 {
+		_, remain, err = readString(remain)
+	}
+	if err == nil && flags&0x40 != 0 {
+		_, _, err = readString(remain)
+	}
+	return
+}
+
+func (s *Broker) handlePublish(session *brokerSession, pkt *packet) (err error) {
+	msg, packetID, msgErr := decodePublish(pkt)
+	if msgErr != nil {
+		err = msgErr
+		return
+	}
+	if !ValidTopic(msg.Topic) {
+		err = fmt.Errorf("illegal mqtt topic '%s'", msg.Topic)
+		return
+	}
+
+	if msg.QoS > 0 {
+		puback, _ := encodePacket(pubackPacket, 0, binary.BigEndian.AppendUint16(nil, packetID))
+		err = session.write(puback)
+		if err != nil {
+			return
+		}
+	}
+
+	s.lock.Lock()
+	if msg.Retain {
+		if len(msg.Payload) == 0 {
+			delete(s.retainMap, msg.Topic)
+		} else {
+			s.retainMap[msg.Topic] = msg
+		}
+	}
+	sessions := make([]*brokerSession, 0, len(s.sessionMap))
+	for _, val := range s.sessionMap {
+		sessions = append(sessions, val)
+	}
+	s.lock.Unlock()
+
+	for _, val := range sessions {
+		val.deliver(msg, false)
+	}
+	return
+}
+
+func (s *Broker) handleSubscribe(session *brokerSession, pkt *packet) (err error) {
+	packetID, remain, idErr := readUint16(pkt.body)
+	if idErr != nil || pkt.flags != 0x02 || len(remain) == 0 {
+		err = errMalformedPacket
+		return
+	}
+
+	filters := []string{}
+	codes := binary.BigEndian.AppendUint16(nil, packetID)
+	for len(remain) > 0 {
+		var filter string
+		filter, remain, err = readString(remain)
+		if err != nil || len(remain) == 0 {
+			err = errMalformedPacket
+			return
+		}
+		qos := remain[0]
+		remain = remain[1:]
+
+		if !ValidFilter(filter) || qos > 2 {
+			codes = append(codes, subackFailure)
+			continue
+		}
+
+		qos = min(qos, 1)
+		session.lock.Lock()
+		session.filterMap[filter] = qos
+		session.lock.Unlock()
+		filters = append(filters, filter)
+		codes = append(codes, qos)
+	}
+
+	suback, _ := encodePacket(subackPacket, 0, codes)
+	err = session.write(suback)
+	if err != nil {
+		return
+	}
+
+	retained := []*Message{}
+	s.lock.Lock()
+	for topic, msg := range s.retainMap {
+		for _, filter := range filters {
+			if MatchTopic(filter, topic) {
+				retained = append(retained, msg)
+				break
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	for _, val := range retained {
+		session.deliver(val, true)
+	}
+	return
+}
+
+func (s *Broker) handleUnsubscribe(session *brokerSession, pkt *packet) (err error) {
+	packetID, remain, idErr := readUint16(pkt.body)
+	if idErr != nil || pkt.flags != 0x02 {
+		err = errMalformedPacket
+		return
+	}
+
+	for len(remain) > 0 {
+		var filter string
+		filter, remain, err = readString(remain)
+		if err != nil {
+			return
+		}
+
+		session.lock.Lock()
+		delete(session.filterMap, filter)
+		session.lock.Unlock()
+	}
+
+	unsuback, _ := encodePacket(unsubackPacket, 0, binary.BigEndian.AppendUint16(nil, packetID))
+	err = session.write(unsuback)
+	return
+}
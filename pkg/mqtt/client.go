@@ -0,0 +1,416 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeepAlive = 30 * time.Second
+	defaultTimeout   = 5 * time.Second
+)
+
+// ErrNotConnected 客户端尚未连接或者连接已断开
+var ErrNotConnected = errors.New("mqtt client not connected")
+
+/*
+Options 客户端参数,KeepAlive为0时为30秒,Timeout为建立连接及等待应答的超时,为0时为5秒
+客户端总是使用CleanSession,断线后由调用方重新Connect,已有的订阅在连接后自动重新发送
+*/
+type Options struct {
+	BrokerAddr string
+	ClientID   string
+	Username   string
+	Password   string
+	KeepAlive  time.Duration
+	Timeout    time.Duration
+}
+
+// Handler 收到订阅的消息时调用,在客户端的读取routine中执行,不能阻塞,也不能在其中同步发布QoS 1消息或者订阅
+type Handler func(msg *Message)
+
+type subscription struct {
+	filter  string
+	qos     byte
+	handler Handler
+}
+
+// Client MQTT 3.1.1客户端,支持QoS 0与QoS 1
+type Client struct {
+	options Options
+
+	lock          sync.Mutex
+	conn          net.Conn
+	doneCh        chan struct{}
+	packetID      uint16
+	pendingMap    map[uint16]chan *packet
+	subscriptions []*subscription
+
+	writeLock sync.Mutex
+}
+
+func NewClient(options Options) *Client {
+	if options.KeepAlive <= 0 {
+		options.KeepAlive = defaultKeepAlive
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	return &Client{options: options, pendingMap: map[uint16]chan *packet{}}
+}
+
+func (s *Client) IsConnected() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.conn != nil
+}
+
+// Connect 连接Broker并重新发送已有的订阅,已连接时直接返回
+func (s *Client) Connect() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn != nil {
+		return
+	}
+
+	conn, connErr := net.DialTimeout("tcp", s.options.BrokerAddr, s.options.Timeout)
+	if connErr != nil {
+		err = connErr
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(s.options.Timeout))
+	err = s.handshake(conn, reader)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	doneCh := make(chan struct{})
+	s.conn = conn
+	s.doneCh = doneCh
+	go s.readLoop(conn, reader, doneCh)
+	go s.keepAlive(conn, doneCh)
+
+	for _, val := range s.subscriptions {
+		err = s.sendSubscribe(conn, val)
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		s.closeConnLocked(conn)
+	}
+	return
+}
+
+func (s *Client) handshake(conn net.Conn, reader *bufio.Reader) (err error) {
+	flags := byte(0x02)
+	if s.options.Username != "" {
+		flags |= 0x80
+	}
+	if s.options.Password != "" {
+		flags |= 0x40
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, protocolLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(s.options.KeepAlive/time.Second))
+	body = appendString(body, s.options.ClientID)
+	if s.options.Username != "" {
+		body = appendString(body, s.options.Username)
+	}
+	if s.options.Password != "" {
+		body = appendString(body, s.options.Password)
+	}
+
+	data, dataErr := encodePacket(connectPacket, 0, body)
+	if dataErr != nil {
+		err = dataErr
+		return
+	}
+	_, err = conn.Write(data)
+	if err != nil {
+		return
+	}
+
+	pkt, pktErr := readPacket(reader)
+	if pktErr != nil {
+		err = pktErr
+		return
+	}
+	if pkt.kind != connackPacket || len(pkt.body) != 2 {
+		err = errMalformedPacket
+		return
+	}
+	if pkt.body[1] != 0 {
+		err = fmt.Errorf("mqtt connect refused, returnCode:%d", pkt.body[1])
+	}
+	return
+}
+
+// Close 发送DISCONNECT后断开连接,已有的订阅保留到下次Connect
+func (s *Client) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return
+	}
+
+	data, _ := encodePacket(disconnectPacket, 0, nil)
+	_ = s.write(s.conn, data)
+	s.closeConnLocked(s.conn)
+}
+
+func (s *Client) closeConn(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closeConnLocked(conn)
+}
+
+// closeConnLocked 关闭连接并唤醒全部等待应答的请求,conn已被替换时只关闭conn
+func (s *Client) closeConnLocked(conn net.Conn) {
+	_ = conn.Close()
+	if s.conn != conn {
+		return
+	}
+
+	close(s.doneCh)
+	for key, val := range s.pendingMap {
+		close(val)
+		delete(s.pendingMap, key)
+	}
+	s.conn = nil
+}
+
+func (s *Client) write(conn net.Conn, data []byte) (err error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	_ = conn.SetWriteDeadline(time.Now().Add(s.options.Timeout))
+	_, err = conn.Write(data)
+	return
+}
+
+func (s *Client) readLoop(conn net.Conn, reader *bufio.Reader, doneCh chan struct{}) {
+	defer s.closeConn(conn)
+
+	for {
+		// Broker在1.5倍KeepAlive内没有任何报文时视为断开,这里多留出一个KeepAlive
+		_ = conn.SetReadDeadline(time.Now().Add(2 * s.options.KeepAlive))
+		pkt, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch pkt.kind {
+		case publishPacket:
+			msg, packetID, msgErr := decodePublish(pkt)
+			if msgErr != nil {
+				return
+			}
+			if msg.QoS > 0 {
+				ack, _ := encodePacket(pubackPacket, 0, binary.BigEndian.AppendUint16(nil, packetID))
+				if s.write(conn, ack) != nil {
+					return
+				}
+			}
+			s.dispatch(msg)
+		case pubackPacket, subackPacket, unsubackPacket:
+			packetID, _, idErr := readUint16(pkt.body)
+			if idErr != nil {
+				return
+			}
+			s.lock.Lock()
+			if ch, ok := s.pendingMap[packetID]; ok {
+				ch <- pkt
+				delete(s.pendingMap, packetID)
+			}
+			s.lock.Unlock()
+		case pingrespPacket:
+		default:
+			return
+		}
+	}
+}
+
+func (s *Client) dispatch(msg *Message) {
+	handlers := []Handler{}
+	s.lock.Lock()
+	for _, val := range s.subscriptions {
+		if MatchTopic(val.filter, msg.Topic) {
+			handlers = append(handlers, val.handler)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, val := range handlers {
+		val(msg)
+	}
+}
+
+func (s *Client) keepAlive(conn net.Conn, doneCh chan struct{}) {
+	ticker := time.NewTicker(s.options.KeepAlive)
+	defer ticker.Stop()
+
+	ping, _ := encodePacket(pingreqPacket, 0, nil)
+	for {
+		select {
+		case <-doneCh:
+			return
+		case <-ticker.C:
+			if s.write(conn, ping) != nil {
+				s.closeConn(conn)
+				return
+			}
+		}
+	}
+}
+
+// request 发送需要应答的报文并等待应答,调用时需要持有s.lock,等待期间释放
+func (s *Client) request(conn net.Conn, encode func(packetID uint16) ([]byte, error)) (ret *packet, err error) {
+	s.packetID++
+	if s.packetID == 0 {
+		s.packetID = 1
+	}
+	packetID := s.packetID
+	replyCh := make(chan *packet, 1)
+	s.pendingMap[packetID] = replyCh
+
+	data, dataErr := encode(packetID)
+	if dataErr == nil {
+		dataErr = s.write(conn, data)
+	}
+	if dataErr != nil {
+		delete(s.pendingMap, packetID)
+		err = dataErr
+		return
+	}
+
+	s.lock.Unlock()
+	defer s.lock.Lock()
+
+	timer := time.NewTimer(s.options.Timeout)
+	defer timer.Stop()
+	select {
+	case pkt, ok := <-replyCh:
+		if !ok {
+			err = ErrNotConnected
+			return
+		}
+		ret = pkt
+	case <-timer.C:
+		err = fmt.Errorf("mqtt request timeout, packetID:%d", packetID)
+		s.lock.Lock()
+		delete(s.pendingMap, packetID)
+		s.lock.Unlock()
+	}
+	return
+}
+
+// Publish QoS为1时等待Broker的PUBACK
+func (s *Client) Publish(msg *Message) (err error) {
+	if !ValidTopic(msg.Topic) || msg.QoS > 1 {
+		err = fmt.Errorf("illegal mqtt publish, topic:%s, qos:%d", msg.Topic, msg.QoS)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		err = ErrNotConnected
+		return
+	}
+
+	if msg.QoS == 0 {
+		data, dataErr := encodePublish(msg, 0)
+		if dataErr != nil {
+			err = dataErr
+			return
+		}
+		err = s.write(s.conn, data)
+		return
+	}
+
+	_, err = s.request(s.conn, func(packetID uint16) ([]byte, error) {
+		return encodePublish(msg, packetID)
+	})
+	return
+}
+
+// Subscribe 订阅filter,qos大于1时按1处理,同一filter重复订阅时替换handler,未连接时在Connect后发送
+func (s *Client) Subscribe(filter string, qos byte, handler Handler) (err error) {
+	if !ValidFilter(filter) {
+		err = fmt.Errorf("illegal mqtt topic filter '%s'", filter)
+		return
+	}
+
+	subPtr := &subscription{filter: filter, qos: min(qos, 1), handler: handler}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	subscriptions := []*subscription{subPtr}
+	for _, val := range s.subscriptions {
+		if val.filter != filter {
+			subscriptions = append(subscriptions, val)
+		}
+	}
+	s.subscriptions = subscriptions
+	if s.conn == nil {
+		return
+	}
+
+	err = s.sendSubscribe(s.conn, subPtr)
+	return
+}
+
+func (s *Client) sendSubscribe(conn net.Conn, subPtr *subscription) (err error) {
+	pkt, pktErr := s.request(conn, func(packetID uint16) ([]byte, error) {
+		body := binary.BigEndian.AppendUint16(nil, packetID)
+		body = appendString(body, subPtr.filter)
+		body = append(body, subPtr.qos)
+		return encodePacket(subscribePacket, 0x02, body)
+	})
+	if pktErr != nil {
+		err = pktErr
+		return
+	}
+	if len(pkt.body) != 3 || pkt.body[2] == subackFailure {
+		err = fmt.Errorf("mqtt subscribe %s refused", subPtr.filter)
+	}
+	return
+}
+
+// Unsubscribe 取消订阅,未连接时只删除本地的订阅
+func (s *Client) Unsubscribe(filter string) (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subscriptions := []*subscription{}
+	for _, val := range s.subscriptions {
+		if val.filter != filter {
+			subscriptions = append(subscriptions, val)
+		}
+	}
+	s.subscriptions = subscriptions
+	if s.conn == nil {
+		return
+	}
+
+	_, err = s.request(s.conn, func(packetID uint16) ([]byte, error) {
+		body := binary.BigEndian.AppendUint16(nil, packetID)
+		body = appendString(body, filter)
+		return encodePacket(unsubscribePacket, 0x02, body)
+	})
+	return
+}
@@ -0,0 +1,148 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMatchTopic(t *testing.T) {
+	items := []struct {
+		filter string
+		topic  string
+		match  bool
+	}{
+		{"a/b/c", "a/b/c", true},
+		{"a/+/c", "a/b/c", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"#", "a/b", true},
+		{"+/b", "a/b", true},
+		{"#", "$SYS/info", false},
+		{"a/b", "a/b/c", false},
+		{"a/b/c", "a/b", false},
+	}
+	for _, val := range items {
+		if MatchTopic(val.filter, val.topic) != val.match {
+			t.Errorf("MatchTopic failed, filter:%s, topic:%s", val.filter, val.topic)
+		}
+	}
+
+	if ValidFilter("a/#/b") || ValidFilter("a/b+") || !ValidFilter("a/+/#") {
+		t.Error("ValidFilter failed")
+	}
+	if ValidTopic("a/+") || ValidTopic("") || !ValidTopic("a/b") {
+		t.Error("ValidTopic failed")
+	}
+}
+
+func TestPacket(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x5A}, 300)
+	data, err := encodePublish(&Message{Topic: "a/b", Payload: payload, QoS: 1, Retain: true}, 7)
+	if err != nil {
+		t.Fatalf("encodePublish failed, error:%s", err.Error())
+	}
+
+	pkt, err := readPacket(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("readPacket failed, error:%s", err.Error())
+	}
+	msg, packetID, err := decodePublish(pkt)
+	if err != nil || packetID != 7 || msg.Topic != "a/b" || msg.QoS != 1 || !msg.Retain || !bytes.Equal(msg.Payload, payload) {
+		t.Errorf("decodePublish failed, msg:%+v, packetID:%d, err:%v", msg, packetID, err)
+	}
+
+	_, err = readPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})))
+	if err == nil {
+		t.Error("readPacket should fail for illegal remaining length")
+	}
+}
+
+func newTestBroker(t *testing.T) (*Broker, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, error:%s", err.Error())
+	}
+
+	broker := NewBroker()
+	go func() {
+		_ = broker.Serve(listener)
+	}()
+	return broker, listener.Addr().String()
+}
+
+func TestClientBroker(t *testing.T) {
+	broker, addr := newTestBroker(t)
+	defer broker.Close()
+
+	publisher := NewClient(Options{BrokerAddr: addr, ClientID: "publisher", Timeout: time.Second})
+	subscriber := NewClient(Options{BrokerAddr: addr, ClientID: "subscriber", Timeout: time.Second})
+	if err := publisher.Connect(); err != nil {
+		t.Fatalf("Connect failed, error:%s", err.Error())
+	}
+	defer publisher.Close()
+
+	err := publisher.Publish(&Message{Topic: "plant/status", Payload: []byte("online"), QoS: 1, Retain: true})
+	if err != nil {
+		t.Fatalf("Publish failed, error:%s", err.Error())
+	}
+
+	msgCh := make(chan *Message, 10)
+	err = subscriber.Subscribe("plant/#", 1, func(msg *Message) {
+		msgCh <- msg
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed, error:%s", err.Error())
+	}
+	if err = subscriber.Connect(); err != nil {
+		t.Fatalf("Connect failed, error:%s", err.Error())
+	}
+	defer subscriber.Close()
+
+	expectMessage := func(topic, payload string, qos byte, retain bool) {
+		select {
+		case msg := <-msgCh:
+			if msg.Topic != topic || string(msg.Payload) != payload || msg.QoS != qos || msg.Retain != retain {
+				t.Errorf("receive message failed, msg:%+v", msg)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("receive message timeout, topic:%s", topic)
+		}
+	}
+	expectMessage("plant/status", "online", 1, true)
+
+	_ = publisher.Publish(&Message{Topic: "plant/line1/temp", Payload: []byte("21.5")})
+	expectMessage("plant/line1/temp", "21.5", 0, false)
+	_ = publisher.Publish(&Message{Topic: "other/temp", Payload: []byte("0")})
+	_ = publisher.Publish(&Message{Topic: "plant/line1/speed", Payload: []byte("1450"), QoS: 1})
+	expectMessage("plant/line1/speed", "1450", 1, false)
+
+	// 断线重连后自动恢复订阅
+	subscriber.Close()
+	if subscriber.IsConnected() {
+		t.Error("IsConnected should be false after Close")
+	}
+	if err = subscriber.Connect(); err != nil {
+		t.Fatalf("reconnect failed, error:%s", err.Error())
+	}
+	expectMessage("plant/status", "online", 1, true)
+	_ = publisher.Publish(&Message{Topic: "plant/line2/temp", Payload: []byte("19")})
+	expectMessage("plant/line2/temp", "19", 0, false)
+
+	if err = subscriber.Unsubscribe("plant/#"); err != nil {
+		t.Errorf("Unsubscribe failed, error:%s", err.Error())
+	}
+	_ = publisher.Publish(&Message{Topic: "plant/line2/temp", Payload: []byte("20"), QoS: 1})
+	select {
+	case msg := <-msgCh:
+		t.Errorf("unsubscribe failed, msg:%+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err = publisher.Publish(&Message{Topic: "plant/+", Payload: []byte("x")}); err == nil {
+		t.Error("Publish should fail for wildcard topic")
+	}
+}
@@ -0,0 +1,228 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+MQTT 3.1.1控制报文类型
+*/
+const (
+	connectPacket     = 1
+	connackPacket     = 2
+	publishPacket     = 3
+	pubackPacket      = 4
+	subscribePacket   = 8
+	subackPacket      = 9
+	unsubscribePacket = 10
+	unsubackPacket    = 11
+	pingreqPacket     = 12
+	pingrespPacket    = 13
+	disconnectPacket  = 14
+)
+
+// protocolLevel MQTT 3.1.1的协议级别
+const protocolLevel = 4
+
+// maxRemainingLength 剩余长度最多4个字节
+const maxRemainingLength = 268435455
+
+// subackFailure SUBACK中订阅失败的返回码
+const subackFailure = 0x80
+
+var errMalformedPacket = errors.New("malformed mqtt packet")
+
+// Message 发布的消息,QoS仅支持0与1
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(reader *bufio.Reader) (ret *packet, err error) {
+	header, headerErr := reader.ReadByte()
+	if headerErr != nil {
+		err = headerErr
+		return
+	}
+
+	length := 0
+	for idx := 0; ; idx++ {
+		if idx == 4 {
+			err = errMalformedPacket
+			return
+		}
+
+		digit, digitErr := reader.ReadByte()
+		if digitErr != nil {
+			err = digitErr
+			return
+		}
+		length |= int(digit&0x7F) << (7 * idx)
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	if err != nil {
+		return
+	}
+
+	ret = &packet{kind: header >> 4, flags: header & 0x0F, body: body}
+	return
+}
+
+func encodePacket(kind, flags byte, body []byte) (ret []byte, err error) {
+	length := len(body)
+	if length > maxRemainingLength {
+		err = fmt.Errorf("mqtt packet too large, length:%d", length)
+		return
+	}
+
+	ret = []byte{kind<<4 | flags&0x0F}
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		ret = append(ret, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	ret = append(ret, body...)
+	return
+}
+
+func appendString(data []byte, val string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(val)))
+	return append(data, val...)
+}
+
+func readUint16(data []byte) (ret uint16, remain []byte, err error) {
+	if len(data) < 2 {
+		err = errMalformedPacket
+		return
+	}
+
+	ret = binary.BigEndian.Uint16(data)
+	remain = data[2:]
+	return
+}
+
+func readString(data []byte) (ret string, remain []byte, err error) {
+	length, remain, err := readUint16(data)
+	if err != nil {
+		return
+	}
+	if len(remain) < int(length) {
+		err = errMalformedPacket
+		return
+	}
+
+	ret = string(remain[:length])
+	remain = remain[length:]
+	return
+}
+
+// encodePublish QoS为0时packetID被忽略
+func encodePublish(msg *Message, packetID uint16) ([]byte, error) {
+	flags := msg.QoS << 1
+	if msg.Retain {
+		flags |= 0x01
+	}
+
+	body := appendString(nil, msg.Topic)
+	if msg.QoS > 0 {
+		body = binary.BigEndian.AppendUint16(body, packetID)
+	}
+	body = append(body, msg.Payload...)
+	return encodePacket(publishPacket, flags, body)
+}
+
+func decodePublish(pkt *packet) (ret *Message, packetID uint16, err error) {
+	msg := &Message{QoS: (pkt.flags >> 1) & 0x03, Retain: pkt.flags&0x01 != 0}
+	if msg.QoS > 1 {
+		err = fmt.Errorf("unsupported mqtt qos %d", msg.QoS)
+		return
+	}
+
+	var remain []byte
+	msg.Topic, remain, err = readString(pkt.body)
+	if err != nil {
+		return
+	}
+	if msg.QoS > 0 {
+		packetID, remain, err = readUint16(remain)
+		if err != nil {
+			return
+		}
+	}
+
+	msg.Payload = remain
+	ret = msg
+	return
+}
+
+// ValidTopic 发布的主题不能为空,也不能包含通配符
+func ValidTopic(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#\x00")
+}
+
+// ValidFilter 订阅的主题过滤器,#只能出现在最后一级,通配符必须独占一级
+func ValidFilter(filter string) bool {
+	if filter == "" || strings.Contains(filter, "\x00") {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for idx, val := range levels {
+		if strings.Contains(val, "#") && (val != "#" || idx != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(val, "+") && val != "+" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchTopic 主题是否匹配订阅的主题过滤器,以$开头的主题不匹配以通配符开头的过滤器
+func MatchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return false
+	}
+
+	for idx, val := range filterLevels {
+		if val == "#" {
+			return true
+		}
+		if idx >= len(topicLevels) {
+			return false
+		}
+		if val != "+" && val != topicLevels[idx] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
@@ -0,0 +1,47 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func (s *Client) ListScanGroup(ctx context.Context) (ret []*common.ScanGroup, err error) {
+	result := &common.ListScanGroupResponse{}
+	err = s.get(ctx, common.ListScanGroup, "", nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Groups
+	return
+}
+
+func (s *Client) SaveScanGroup(ctx context.Context, group *common.ScanGroup) (ret *common.ScanGroup, err error) {
+	result := &common.SaveScanGroupResponse{}
+	err = s.post(ctx, common.SaveScanGroup, "", group, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Group
+	return
+}
+
+func (s *Client) DeleteScanGroup(ctx context.Context, name string) (err error) {
+	result := &cd.Result{}
+	err = s.invoke(ctx, http.MethodDelete, s.routeURL(common.DeleteScanGroup, name, nil), nil, result)
+	if err == nil {
+		err = checkResult(*result, 0)
+	}
+	return
+}
@@ -0,0 +1,424 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// Error REST接口返回的错误,ExceptionCode非零时表示从站返回了异常应答
+type Error struct {
+	cd.Result
+	ExceptionCode byte
+}
+
+func (s *Error) Error() string {
+	if s.ExceptionCode != 0 && s.Reason == "" {
+		return fmt.Sprintf("%s, modbus exception code:%v", s.Result.Error(), s.ExceptionCode)
+	}
+
+	return s.Result.Error()
+}
+
+// ExceptionCode 从err中取出从站返回的异常码,不是异常应答时返回0
+func ExceptionCode(err error) byte {
+	var sdkErr *Error
+	if errors.As(err, &sdkErr) {
+		return sdkErr.ExceptionCode
+	}
+
+	return 0
+}
+
+// Client quickModbus REST接口客户端
+type Client struct {
+	serverURL  string
+	httpClient *http.Client
+}
+
+// NewClient serverURL形如http://127.0.0.1:8080,httpClient为空时使用http.DefaultClient
+func NewClient(serverURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		serverURL:  strings.TrimRight(serverURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+var pathParamReg = regexp.MustCompile(`:\w+`)
+
+// routeURL pathParam替换路由中的路径参数,如从站的:id或者Tag的:name
+func (s *Client) routeURL(route, pathParam string, queryVal url.Values) string {
+	if loc := pathParamReg.FindStringIndex(route); loc != nil {
+		route = route[:loc[0]] + url.PathEscape(pathParam) + route[loc[1]:]
+	}
+
+	urlVal := s.serverURL + route
+	if len(queryVal) > 0 {
+		urlVal += "?" + queryVal.Encode()
+	}
+
+	return urlVal
+}
+
+func (s *Client) invoke(ctx context.Context, method, urlVal string, param, result interface{}) (err error) {
+	if param == nil {
+		err = s.do(ctx, method, urlVal, "", nil, result)
+		return
+	}
+
+	data, dataErr := json.Marshal(param)
+	if dataErr != nil {
+		err = dataErr
+		return
+	}
+
+	err = s.do(ctx, method, urlVal, "application/json", bytes.NewBuffer(data), result)
+	return
+}
+
+func (s *Client) do(ctx context.Context, method, urlVal, contentType string, body io.Reader, result interface{}) (err error) {
+	request, requestErr := http.NewRequestWithContext(ctx, method, urlVal, body)
+	if requestErr != nil {
+		err = requestErr
+		return
+	}
+	if contentType != "" {
+		request.Header.Set("content-type", contentType)
+	}
+
+	response, responseErr := s.httpClient.Do(request)
+	if responseErr != nil {
+		err = responseErr
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpect statusCode, statusCode:%d", response.StatusCode)
+		return
+	}
+
+	err = json.NewDecoder(response.Body).Decode(result)
+	return
+}
+
+func (s *Client) post(ctx context.Context, route, pathParam string, param, result interface{}) error {
+	return s.invoke(ctx, http.MethodPost, s.routeURL(route, pathParam, nil), param, result)
+}
+
+func (s *Client) get(ctx context.Context, route, pathParam string, queryVal url.Values, result interface{}) error {
+	return s.invoke(ctx, http.MethodGet, s.routeURL(route, pathParam, queryVal), nil, result)
+}
+
+// policyQuery GET接口通过query参数传递超时与重试参数
+func policyQuery(policy *common.RequestPolicy) url.Values {
+	queryVal := url.Values{}
+	if policy == nil {
+		return queryVal
+	}
+
+	if policy.Timeout > 0 {
+		queryVal.Set("timeout", strconv.Itoa(policy.Timeout))
+	}
+	if policy.Retries != nil {
+		queryVal.Set("retries", strconv.Itoa(*policy.Retries))
+	}
+	if policy.RetryBackoff > 0 {
+		queryVal.Set("retryBackoff", strconv.Itoa(policy.RetryBackoff))
+	}
+	return queryVal
+}
+
+func checkResult(result cd.Result, exCode byte) error {
+	if result.ErrorCode == cd.Succeeded && exCode == 0 {
+		return nil
+	}
+
+	return &Error{Result: result, ExceptionCode: exCode}
+}
+
+func (s *Client) ListSlave(ctx context.Context) (ret []*common.SlaveView, err error) {
+	result := &common.ListSlaveResponse{}
+	err = s.get(ctx, common.ListSlave, "", nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Slaves
+	return
+}
+
+func (s *Client) QuerySlave(ctx context.Context, slaveID string) (ret *common.SlaveView, err error) {
+	result := &common.QuerySlaveResponse{}
+	err = s.get(ctx, common.QuerySlave, slaveID, nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Slave
+	return
+}
+
+func (s *Client) QuerySlaveStatus(ctx context.Context, slaveID string) (ret *common.SlaveStatus, err error) {
+	result := &common.QuerySlaveStatusResponse{}
+	err = s.get(ctx, common.QuerySlaveStatus, slaveID, nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = &result.SlaveStatus
+	return
+}
+
+// ConnectSlave 返回服务端分配的slaveID
+func (s *Client) ConnectSlave(ctx context.Context, param *common.ConnectSlaveRequest) (ret string, err error) {
+	result := &common.ConnectSlaveResponse{}
+	err = s.post(ctx, common.ConnectSlave, "", param, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.SlaveID
+	return
+}
+
+func (s *Client) DisConnectSlave(ctx context.Context, slaveID string) (err error) {
+	result := &cd.Result{}
+	err = s.invoke(ctx, http.MethodDelete, s.routeURL(common.DisConnectSlave, slaveID, nil), nil, result)
+	if err == nil {
+		err = checkResult(*result, 0)
+	}
+	return
+}
+
+func (s *Client) ReadCoils(ctx context.Context, slaveID string, param *common.ReadCoilsRequest) (ret []bool, err error) {
+	result := &common.ReadCoilsResponse{Values: &ret}
+	err = s.post(ctx, common.ReadCoils, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) ReadDiscreteInputs(ctx context.Context, slaveID string, param *common.ReadDiscreteInputsRequest) (ret []bool, err error) {
+	result := &common.ReadDiscreteInputsResponse{Values: &ret}
+	err = s.post(ctx, common.ReadDiscreteInputs, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+// ReadHoldingRegisters values为与param.ValueType对应的切片指针,如*[]float32
+func (s *Client) ReadHoldingRegisters(ctx context.Context, slaveID string, param *common.ReadHoldingRegistersRequest, values interface{}) (err error) {
+	result := &common.ReadHoldingRegistersResponse{Values: values}
+	err = s.post(ctx, common.ReadHoldingRegisters, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+// ReadInputRegisters values为与param.ValueType对应的切片指针,如*[]float32
+func (s *Client) ReadInputRegisters(ctx context.Context, slaveID string, param *common.ReadReadInputRegistersRequest, values interface{}) (err error) {
+	result := &common.ReadReadInputRegistersResponse{Values: values}
+	err = s.post(ctx, common.ReadInputRegisters, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) WriteSingleCoil(ctx context.Context, slaveID string, param *common.WriteSingleCoilRequest) (err error) {
+	result := &common.WriteSingleCoilResponse{}
+	err = s.post(ctx, common.WriteSingleCoil, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) WriteSingleRegister(ctx context.Context, slaveID string, param *common.WriteSingleRegisterRequest) (err error) {
+	result := &common.WriteSingleRegisterResponse{}
+	err = s.post(ctx, common.WriteSingleRegister, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) ReadExceptionStatus(ctx context.Context, slaveID string, policy *common.RequestPolicy) (ret byte, err error) {
+	result := &common.ReadExceptionStatusResponse{}
+	err = s.get(ctx, common.ReadExceptionStatus, slaveID, policyQuery(policy), result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Status
+	return
+}
+
+func (s *Client) Diagnostics(ctx context.Context, slaveID string, param *common.DiagnosticsRequest) (ret string, err error) {
+	result := &common.DiagnosticsResponse{}
+	err = s.post(ctx, common.Diagnostics, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Value
+	return
+}
+
+func (s *Client) GetCommEventCounter(ctx context.Context, slaveID string, policy *common.RequestPolicy) (status, eventCount uint16, err error) {
+	result := &common.GetCommEventCounterResponse{}
+	err = s.get(ctx, common.GetCommEventCounter, slaveID, policyQuery(policy), result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	status = result.CommStatus
+	eventCount = result.EventCount
+	return
+}
+
+func (s *Client) GetCommEventLog(ctx context.Context, slaveID string, policy *common.RequestPolicy) (status, eventCount, messageCount uint16, events string, err error) {
+	result := &common.GetCommEventLogResponse{}
+	err = s.get(ctx, common.GetCommEventLog, slaveID, policyQuery(policy), result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	status = result.CommStatus
+	eventCount = result.EventCount
+	messageCount = result.MessageCount
+	events = result.CommEvents
+	return
+}
+
+func (s *Client) WriteMultipleCoils(ctx context.Context, slaveID string, param *common.WriteMultipleCoilsRequest) (err error) {
+	result := &common.WriteMultipleCoilsResponse{}
+	err = s.post(ctx, common.WriteMultipleCoils, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) WriteMultipleRegisters(ctx context.Context, slaveID string, param *common.WriteMultipleRegistersRequest) (err error) {
+	result := &common.WriteMultipleRegistersResponse{}
+	err = s.post(ctx, common.WriteMultipleRegisters, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) ReportSlaveID(ctx context.Context, slaveID string, policy *common.RequestPolicy) (ret string, err error) {
+	result := &common.ReportSlaveIDResponse{}
+	err = s.get(ctx, common.ReportSlaveID, slaveID, policyQuery(policy), result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.SlaveID
+	return
+}
+
+func (s *Client) ReadFileRecord(ctx context.Context, slaveID string, param *common.ReadFileRecordRequest) (ret []string, err error) {
+	result := &common.ReadFileRecordResponse{}
+	err = s.post(ctx, common.ReadFileRecord, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.ItemData
+	return
+}
+
+func (s *Client) WriteFileRecord(ctx context.Context, slaveID string, param *common.WriteFileRecordRequest) (err error) {
+	result := &common.WriteFileRecordResponse{}
+	err = s.post(ctx, common.WriteFileRecord, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) MaskWriteRegister(ctx context.Context, slaveID string, param *common.MaskWriteRegisterRequest) (err error) {
+	result := &common.MaskWriteRegisterResponse{}
+	err = s.post(ctx, common.MaskWriteRegister, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+// ReadWriteMultipleRegisters values为与param.ReadValueType对应的切片指针
+func (s *Client) ReadWriteMultipleRegisters(ctx context.Context, slaveID string, param *common.ReadWriteMultipleRegistersRequest, values interface{}) (err error) {
+	result := &common.ReadWriteMultipleRegistersResponse{Values: values}
+	err = s.post(ctx, common.ReadWriteMultipleRegisters, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+func (s *Client) ReadFIFOQueue(ctx context.Context, slaveID string, param *common.ReadFIFOQueueRequest) (ret []string, err error) {
+	result := &common.ReadFIFOQueueResponse{}
+	err = s.post(ctx, common.ReadFIFOQueue, slaveID, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Data
+	return
+}
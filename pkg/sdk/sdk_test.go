@@ -0,0 +1,125 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slave/mb001/holding/register/read", func(res http.ResponseWriter, req *http.Request) {
+		param := &common.ReadHoldingRegistersRequest{}
+		_ = json.NewDecoder(req.Body).Decode(param)
+		result := &common.ReadHoldingRegistersResponse{}
+		if param.Address > 100 {
+			result.Result = *cd.NewError(cd.UnExpected, "modbus exception code:2")
+			result.ExceptionCode = 2
+		} else {
+			result.Values = []float32{1.5, 2.5}
+		}
+		_ = json.NewEncoder(res).Encode(result)
+	})
+	mux.HandleFunc("/slave/mb001/exception/status/read", func(res http.ResponseWriter, req *http.Request) {
+		result := &common.ReadExceptionStatusResponse{Status: 0x12}
+		if req.URL.Query().Get("timeout") != "300" || req.URL.Query().Get("retries") != "0" {
+			result.Result = *cd.NewError(cd.IllegalParam, "invalid param")
+		}
+		_ = json.NewEncoder(res).Encode(result)
+	})
+	mux.HandleFunc("/slave/connect", func(res http.ResponseWriter, req *http.Request) {
+		result := &common.ConnectSlaveResponse{}
+		result.Result = *cd.NewError(cd.Duplicated, "duplicate slave device")
+		_ = json.NewEncoder(res).Encode(result)
+	})
+	mux.HandleFunc("/tags/pump.speed/value", func(res http.ResponseWriter, req *http.Request) {
+		result := &common.ReadTagResponse{Value: &common.TagValue{Name: "pump.speed", Value: 1450.0, Unit: "rpm"}}
+		_ = json.NewEncoder(res).Encode(result)
+	})
+	mux.HandleFunc("/tags/pump.speed/history", func(res http.ResponseWriter, req *http.Request) {
+		result := &common.QueryTagHistoryResponse{Name: "pump.speed", Agg: req.URL.Query().Get("agg"), Step: req.URL.Query().Get("step")}
+		if result.Agg != "max" || result.Step != "1m0s" {
+			_ = json.NewEncoder(res).Encode(cd.NewError(cd.IllegalParam, "illegal agg"))
+			return
+		}
+		result.Points = []*common.HistoryPoint{{Value: 1460.0, Count: 12}}
+		_ = json.NewEncoder(res).Encode(result)
+	})
+	mux.HandleFunc("/stream", func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("range") != "mb001:coil:0-15" {
+			_ = json.NewEncoder(res).Encode(cd.NewError(cd.IllegalParam, "illegal address range"))
+			return
+		}
+		res.Header().Set("Content-Type", "text/event-stream")
+		_, _ = res.Write([]byte(": keepalive\n\nevent: value\ndata: {\"name\":\"run\",\"value\":true,\"quality\":\"good\"}\n\n"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	clnt := NewClient(server.URL+"/", nil)
+	values := []float32{}
+	err := clnt.ReadHoldingRegisters(context.Background(), "mb001", &common.ReadHoldingRegistersRequest{Address: 1, Count: 2, ValueType: common.Float32Value}, &values)
+	if err != nil || len(values) != 2 || values[1] != 2.5 {
+		t.Errorf("ReadHoldingRegisters failed, values:%v, err:%v", values, err)
+	}
+
+	err = clnt.ReadHoldingRegisters(context.Background(), "mb001", &common.ReadHoldingRegistersRequest{Address: 200, Count: 2, ValueType: common.Float32Value}, &values)
+	if err == nil || ExceptionCode(err) != 2 {
+		t.Errorf("ReadHoldingRegisters should return exception, err:%v", err)
+	}
+
+	retries := 0
+	status, statusErr := clnt.ReadExceptionStatus(context.Background(), "mb001", &common.RequestPolicy{Timeout: 300, Retries: &retries})
+	if statusErr != nil || status != 0x12 {
+		t.Errorf("ReadExceptionStatus failed, status:%v, err:%v", status, statusErr)
+	}
+
+	_, err = clnt.ConnectSlave(context.Background(), &common.ConnectSlaveRequest{SlaveAddr: "127.0.0.1:502"})
+	sdkErr, ok := err.(*Error)
+	if !ok || sdkErr.ErrorCode != cd.Duplicated || ExceptionCode(err) != 0 {
+		t.Errorf("ConnectSlave should return duplicated error, err:%v", err)
+	}
+
+	tagVal, tagErr := clnt.ReadTag(context.Background(), "pump.speed", nil)
+	if tagErr != nil || tagVal.Value != 1450.0 || tagVal.Unit != "rpm" {
+		t.Errorf("ReadTag failed, value:%+v, err:%v", tagVal, tagErr)
+	}
+
+	now := time.Now()
+	points, pointsErr := clnt.QueryTagHistory(context.Background(), "pump.speed", &common.HistoryRequest{From: now.Add(-time.Hour), To: now, Agg: "max", Step: time.Minute})
+	if pointsErr != nil || len(points) != 1 || points[0].Value != 1460.0 || points[0].Count != 12 {
+		t.Errorf("QueryTagHistory failed, points:%d, err:%v", len(points), pointsErr)
+	}
+
+	_, err = clnt.QuerySlave(context.Background(), "mb002")
+	if err == nil {
+		t.Error("QuerySlave should fail for unknown route")
+	}
+
+	streamVals := []*common.TagValue{}
+	err = clnt.StreamValue(context.Background(), &common.StreamRequest{Ranges: []*common.AddressRange{{SlaveID: "mb001", Table: common.CoilTable, From: 0, To: 15}}}, func(val *common.TagValue) error {
+		streamVals = append(streamVals, val)
+		return nil
+	})
+	if err != nil || len(streamVals) != 1 || streamVals[0].Name != "run" || streamVals[0].Value != true {
+		t.Errorf("StreamValue failed, values:%d, err:%v", len(streamVals), err)
+	}
+
+	err = clnt.StreamValue(context.Background(), &common.StreamRequest{}, func(val *common.TagValue) error {
+		return nil
+	})
+	if sdkErr, ok := err.(*Error); !ok || sdkErr.ErrorCode != cd.IllegalParam {
+		t.Errorf("StreamValue should return illegal param, err:%v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+/*
+StreamValue 订阅Tag的取值变化,每收到一次推送调用一次handler,handler返回错误时结束订阅
+服务端断开时返回nil,ctx取消时返回ctx.Err(),httpClient设置了Timeout时订阅会被超时中断
+*/
+func (s *Client) StreamValue(ctx context.Context, param *common.StreamRequest, handler func(*common.TagValue) error) (err error) {
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodGet, s.routeURL(common.StreamValue, "", param.Values()), nil)
+	if requestErr != nil {
+		err = requestErr
+		return
+	}
+	request.Header.Set("accept", "text/event-stream")
+
+	response, responseErr := s.httpClient.Do(request)
+	if responseErr != nil {
+		err = responseErr
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpect statusCode, statusCode:%d", response.StatusCode)
+		return
+	}
+	if !strings.HasPrefix(response.Header.Get("content-type"), "text/event-stream") {
+		result := &cd.Result{}
+		err = json.NewDecoder(response.Body).Decode(result)
+		if err == nil {
+			err = checkResult(*result, 0)
+		}
+		if err == nil {
+			err = fmt.Errorf("unexpect content type %s", response.Header.Get("content-type"))
+		}
+		return
+	}
+
+	eventName := ""
+	data := []string{}
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if (eventName == "" || eventName == common.ValueEvent) && len(data) > 0 {
+				tagVal := &common.TagValue{}
+				err = json.Unmarshal([]byte(strings.Join(data, "\n")), tagVal)
+				if err == nil {
+					err = handler(tagVal)
+				}
+				if err != nil {
+					return
+				}
+			}
+			eventName = ""
+			data = data[:0]
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			eventName = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if ctx.Err() != nil {
+		err = ctx.Err()
+		return
+	}
+
+	err = scanner.Err()
+	return
+}
@@ -0,0 +1,121 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+func (s *Client) ListTag(ctx context.Context) (ret []*common.Tag, err error) {
+	result := &common.ListTagResponse{}
+	err = s.get(ctx, common.ListTag, "", nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Tags
+	return
+}
+
+func (s *Client) QueryTag(ctx context.Context, name string) (ret *common.Tag, err error) {
+	result := &common.QueryTagResponse{}
+	err = s.get(ctx, common.QueryTag, name, nil, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Tag
+	return
+}
+
+// SaveTag 新增或者替换Tag,返回补齐缺省值后的Tag定义
+func (s *Client) SaveTag(ctx context.Context, tag *common.Tag) (ret *common.Tag, err error) {
+	result := &common.SaveTagResponse{}
+	err = s.post(ctx, common.SaveTag, "", tag, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Tag
+	return
+}
+
+func (s *Client) DeleteTag(ctx context.Context, name string) (err error) {
+	result := &cd.Result{}
+	err = s.invoke(ctx, http.MethodDelete, s.routeURL(common.DeleteTag, name, nil), nil, result)
+	if err == nil {
+		err = checkResult(*result, 0)
+	}
+	return
+}
+
+func (s *Client) ReadTag(ctx context.Context, name string, policy *common.RequestPolicy) (ret *common.TagValue, err error) {
+	result := &common.ReadTagResponse{}
+	err = s.get(ctx, common.ReadTag, name, policyQuery(policy), result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Value
+	return
+}
+
+// ReadTags 单个Tag读取失败时对应TagValue的Error非空
+func (s *Client) ReadTags(ctx context.Context, param *common.ReadTagsRequest) (ret []*common.TagValue, err error) {
+	result := &common.ReadTagsResponse{}
+	err = s.post(ctx, common.ReadTags, "", param, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Values
+	return
+}
+
+func (s *Client) WriteTag(ctx context.Context, name string, param *common.WriteTagRequest) (err error) {
+	result := &common.WriteTagResponse{}
+	err = s.post(ctx, common.WriteTag, name, param, result)
+	if err == nil {
+		err = checkResult(result.Result, result.ExceptionCode)
+	}
+	return
+}
+
+// QueryTagHistory param为空时查询最近1小时的原始取值
+func (s *Client) QueryTagHistory(ctx context.Context, name string, param *common.HistoryRequest) (ret []*common.HistoryPoint, err error) {
+	var queryVal url.Values
+	if param != nil {
+		queryVal = param.Values()
+	}
+
+	result := &common.QueryTagHistoryResponse{}
+	err = s.get(ctx, common.QueryTagHistory, name, queryVal, result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	ret = result.Points
+	return
+}
@@ -0,0 +1,30 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/muidea/quickModbus/pkg/common"
+)
+
+// ImportTemplate 导入设备模板,format为common.JSONTemplate或者common.CSVTemplate
+func (s *Client) ImportTemplate(ctx context.Context, data []byte, format string) (slaveID string, tags []*common.Tag, err error) {
+	contentType := "application/json"
+	if format == common.CSVTemplate {
+		contentType = "text/csv"
+	}
+
+	result := &common.ImportTemplateResponse{}
+	err = s.do(ctx, http.MethodPost, s.routeURL(common.ImportTemplate, "", nil), contentType, bytes.NewReader(data), result)
+	if err == nil {
+		err = checkResult(result.Result, 0)
+	}
+	if err != nil {
+		return
+	}
+
+	slaveID = result.SlaveID
+	tags = result.Tags
+	return
+}
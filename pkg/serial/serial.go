@@ -0,0 +1,138 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	NoneParity = "N"
+	EvenParity = "E"
+	OddParity  = "O"
+)
+
+const (
+	defaultBaudRate = 9600
+	defaultDataBits = 8
+	defaultStopBits = 1
+	defaultParity   = EvenParity
+)
+
+// 波特率大于19200时,规范建议帧间隔固定为1.75ms
+const (
+	fixedSilenceBaudRate = 19200
+	fixedFrameSilence    = 1750 * time.Microsecond
+)
+
+// Config 串口参数,FrameSilence单位为微秒,为0时按照3.5个字符时间计算
+type Config struct {
+	BaudRate     int    `json:"baudRate"`
+	DataBits     int    `json:"dataBits"`
+	Parity       string `json:"parity"`
+	StopBits     int    `json:"stopBits"`
+	FrameSilence int    `json:"frameSilence"`
+}
+
+// DefaultConfig Modbus串行链路默认参数 9600,8,E,1
+func DefaultConfig() Config {
+	return Config{
+		BaudRate: defaultBaudRate,
+		DataBits: defaultDataBits,
+		Parity:   defaultParity,
+		StopBits: defaultStopBits,
+	}
+}
+
+func (s Config) Verify() error {
+	if s.BaudRate <= 0 {
+		return fmt.Errorf("illegal baud rate:%d", s.BaudRate)
+	}
+	if s.DataBits < 5 || s.DataBits > 8 {
+		return fmt.Errorf("illegal data bits:%d", s.DataBits)
+	}
+	if s.StopBits != 1 && s.StopBits != 2 {
+		return fmt.Errorf("illegal stop bits:%d", s.StopBits)
+	}
+	switch s.Parity {
+	case NoneParity, EvenParity, OddParity:
+	default:
+		return fmt.Errorf("illegal parity:%s", s.Parity)
+	}
+
+	return nil
+}
+
+// CharTime 传输一个字符所需时间,包含起始位、数据位、校验位和停止位
+func (s Config) CharTime() time.Duration {
+	bits := 1 + s.DataBits + s.StopBits
+	if s.Parity != NoneParity {
+		bits++
+	}
+
+	return time.Duration(bits) * time.Second / time.Duration(s.BaudRate)
+}
+
+// Silence RTU帧间隔,即3.5个字符时间
+func (s Config) Silence() time.Duration {
+	if s.FrameSilence > 0 {
+		return time.Duration(s.FrameSilence) * time.Microsecond
+	}
+	if s.BaudRate > fixedSilenceBaudRate {
+		return fixedFrameSilence
+	}
+
+	return s.CharTime() * 7 / 2
+}
+
+// Port 已打开的串口,读操作支持超时
+type Port struct {
+	name   string
+	config Config
+	file   *os.File
+}
+
+// Open 以非阻塞方式打开串口并设置为raw模式
+func Open(name string, config Config) (ret *Port, err error) {
+	err = config.Verify()
+	if err != nil {
+		return
+	}
+
+	fileVal, fileErr := openPort(name, config)
+	if fileErr != nil {
+		err = fileErr
+		return
+	}
+
+	ret = &Port{
+		name:   name,
+		config: config,
+		file:   fileVal,
+	}
+	return
+}
+
+func (s *Port) Name() string {
+	return s.name
+}
+
+func (s *Port) Config() Config {
+	return s.config
+}
+
+func (s *Port) Read(byteVal []byte) (int, error) {
+	return s.file.Read(byteVal)
+}
+
+func (s *Port) Write(byteVal []byte) (int, error) {
+	return s.file.Write(byteVal)
+}
+
+func (s *Port) SetReadDeadline(t time.Time) error {
+	return s.file.SetReadDeadline(t)
+}
+
+func (s *Port) Close() error {
+	return s.file.Close()
+}
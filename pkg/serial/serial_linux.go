@@ -0,0 +1,95 @@
+//go:build linux
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var baudRates = map[int]uint32{
+	1200:    syscall.B1200,
+	2400:    syscall.B2400,
+	4800:    syscall.B4800,
+	9600:    syscall.B9600,
+	19200:   syscall.B19200,
+	38400:   syscall.B38400,
+	57600:   syscall.B57600,
+	115200:  syscall.B115200,
+	230400:  syscall.B230400,
+	460800:  syscall.B460800,
+	921600:  syscall.B921600,
+	1000000: syscall.B1000000,
+}
+
+var dataBits = map[int]uint32{
+	5: syscall.CS5,
+	6: syscall.CS6,
+	7: syscall.CS7,
+	8: syscall.CS8,
+}
+
+func ioctl(fd int, request uintptr, argp unsafe.Pointer) error {
+	_, _, errNo := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(argp))
+	if errNo != 0 {
+		return errNo
+	}
+
+	return nil
+}
+
+func makeTermios(config Config) (ret *syscall.Termios, err error) {
+	baudVal, baudOK := baudRates[config.BaudRate]
+	if !baudOK {
+		err = fmt.Errorf("unsupported baud rate:%d", config.BaudRate)
+		return
+	}
+
+	cflag := baudVal | dataBits[config.DataBits] | syscall.CREAD | syscall.CLOCAL
+	if config.StopBits == 2 {
+		cflag |= syscall.CSTOPB
+	}
+	switch config.Parity {
+	case EvenParity:
+		cflag |= syscall.PARENB
+	case OddParity:
+		cflag |= syscall.PARENB | syscall.PARODD
+	}
+
+	ret = &syscall.Termios{
+		Iflag:  syscall.IGNPAR,
+		Cflag:  cflag,
+		Ispeed: baudVal,
+		Ospeed: baudVal,
+	}
+	// 非阻塞读由runtime poller配合deadline实现
+	ret.Cc[syscall.VMIN] = 1
+	ret.Cc[syscall.VTIME] = 0
+	return
+}
+
+func openPort(name string, config Config) (ret *os.File, err error) {
+	termios, termiosErr := makeTermios(config)
+	if termiosErr != nil {
+		err = termiosErr
+		return
+	}
+
+	fd, fdErr := syscall.Open(name, syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if fdErr != nil {
+		err = fmt.Errorf("open serial port %s failed, error:%s", name, fdErr.Error())
+		return
+	}
+
+	ioErr := ioctl(fd, syscall.TCSETS, unsafe.Pointer(termios))
+	if ioErr != nil {
+		syscall.Close(fd)
+		err = fmt.Errorf("set serial port %s attributes failed, error:%s", name, ioErr.Error())
+		return
+	}
+
+	ret = os.NewFile(uintptr(fd), name)
+	return
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func openPort(name string, _ Config) (*os.File, error) {
+	return nil, fmt.Errorf("serial port %s unsupported on %s", name, runtime.GOOS)
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package serial
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openPty 打开一对伪终端,返回主设备和从设备路径
+func openPty(t *testing.T) (*os.File, string) {
+	master, masterErr := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if masterErr != nil {
+		t.Skipf("open /dev/ptmx failed, error:%s", masterErr.Error())
+	}
+
+	var unlock int32
+	ioErr := ioctl(int(master.Fd()), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock))
+	if ioErr != nil {
+		master.Close()
+		t.Skipf("unlock pty failed, error:%s", ioErr.Error())
+	}
+
+	var ptyNo uint32
+	ioErr = ioctl(int(master.Fd()), syscall.TIOCGPTN, unsafe.Pointer(&ptyNo))
+	if ioErr != nil {
+		master.Close()
+		t.Skipf("get pty number failed, error:%s", ioErr.Error())
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNo)
+}
+
+func TestConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Verify() != nil {
+		t.Errorf("verify default config failed")
+		return
+	}
+
+	// 9600,8,E,1 每个字符11位
+	if cfg.CharTime() != 11*time.Second/9600 {
+		t.Errorf("illegal char time:%v", cfg.CharTime())
+		return
+	}
+	if cfg.Silence() != 11*time.Second/9600*7/2 {
+		t.Errorf("illegal silence:%v", cfg.Silence())
+		return
+	}
+
+	cfg.BaudRate = 115200
+	if cfg.Silence() != fixedFrameSilence {
+		t.Errorf("illegal silence:%v", cfg.Silence())
+		return
+	}
+
+	cfg.FrameSilence = 5000
+	if cfg.Silence() != 5*time.Millisecond {
+		t.Errorf("illegal silence:%v", cfg.Silence())
+		return
+	}
+
+	cfg.Parity = "X"
+	if cfg.Verify() == nil {
+		t.Errorf("verify illegal parity should fail")
+		return
+	}
+
+	_, portErr := Open("/dev/null", Config{BaudRate: 12345, DataBits: 8, Parity: NoneParity, StopBits: 1})
+	if portErr == nil {
+		t.Errorf("open with unsupported baud rate should fail")
+		return
+	}
+}
+
+func TestPortReadWrite(t *testing.T) {
+	master, slaveName := openPty(t)
+	defer master.Close()
+
+	port, portErr := Open(slaveName, DefaultConfig())
+	if portErr != nil {
+		t.Errorf("Open failed, error:%s", portErr.Error())
+		return
+	}
+	defer port.Close()
+
+	reqVal := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A, 0xC5, 0xCD}
+	_, wErr := master.Write(reqVal)
+	if wErr != nil {
+		t.Errorf("write pty master failed, error:%s", wErr.Error())
+		return
+	}
+
+	recvVal := make([]byte, 0)
+	buffVal := make([]byte, 64)
+	for len(recvVal) < len(reqVal) {
+		port.SetReadDeadline(time.Now().Add(time.Second))
+		rSize, rErr := port.Read(buffVal)
+		if rErr != nil {
+			t.Errorf("read port failed, error:%s", rErr.Error())
+			return
+		}
+		recvVal = append(recvVal, buffVal[:rSize]...)
+	}
+	if !bytes.Equal(recvVal, reqVal) {
+		t.Errorf("read port mismatch, % x", recvVal)
+		return
+	}
+
+	_, wErr = port.Write([]byte{0x01, 0x83, 0x02, 0xC0, 0xF1})
+	if wErr != nil {
+		t.Errorf("write port failed, error:%s", wErr.Error())
+		return
+	}
+	rSize, rErr := master.Read(buffVal)
+	if rErr != nil || !bytes.Equal(buffVal[:rSize], []byte{0x01, 0x83, 0x02, 0xC0, 0xF1}) {
+		t.Errorf("read pty master failed, error:%v", rErr)
+		return
+	}
+
+	// 无数据时读操作按照deadline超时返回
+	port.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, rErr = port.Read(buffVal)
+	if !os.IsTimeout(rErr) {
+		t.Errorf("read port should timeout, error:%v", rErr)
+		return
+	}
+}